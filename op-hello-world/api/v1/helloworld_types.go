@@ -17,7 +17,9 @@ limitations under the License.
 package v1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -29,6 +31,8 @@ const (
 	PhaseRunning = "Running"
 	PhaseFailed  = "Failed"
 	PhaseUnknown = "Unknown"
+	// PhaseAwaitingApproval holds a message until its generation is approved
+	PhaseAwaitingApproval = "AwaitingApproval"
 )
 
 // Condition types for HelloWorld status
@@ -39,18 +43,474 @@ const (
 	TypeProgressing = "Progressing"
 	// TypeDegraded indicates whether the HelloWorld resource is degraded
 	TypeDegraded = "Degraded"
+	// TypeMessageResolved indicates whether the message could be read from spec.messageFrom
+	TypeMessageResolved = "MessageResolved"
+	// TypeServing indicates whether the greeter of a HelloWorld in serve mode has ready endpoints
+	TypeServing = "Serving"
 )
 
+// LocaleAnnotation on a namespace selects the locale of the HelloWorlds in it
+// with spec.messages that do not set spec.locale
+const LocaleAnnotation = "apps.example.com/locale"
+
+// AdoptAnnotation opts an existing pod in to being adopted by the HelloWorld whose
+// generated pod it would be, when set to "true" on a pod with matching labels
+const AdoptAnnotation = "apps.example.com/adopt"
+
+// ApproveGenerationAnnotation approves the message of a HelloWorld with
+// approvalPolicy Required when set to its metadata.generation. A message that
+// changes after its generation was approved needs "<generation>/<messageHash>".
+const ApproveGenerationAnnotation = "apps.example.com/approve-generation"
+
+// ApprovedByAnnotation is set by the HelloWorld webhook to the user who last set
+// ApproveGenerationAnnotation, from the admission request. The webhook restores
+// any other change to it.
+const ApprovedByAnnotation = "apps.example.com/approved-by"
+
+// DeliverySecretLabel must be set to "true" on the Secrets spec.deliveries reads
+// headers or signing keys from, so a HelloWorld cannot send any Secret of its
+// namespace to a webhook
+const DeliverySecretLabel = "apps.example.com/delivery"
+
+// PromoteAnnotation skips the remaining steps of a canary rollout, or resumes
+// an aborted one, when set to the name of the revision being rolled out, as in
+// status.rollout.revision
+const PromoteAnnotation = "apps.example.com/promote"
+
+// PodNameStrategy decides how the pod generated for a HelloWorld is named
+// +kubebuilder:validation:Enum=Fixed;Generated
+type PodNameStrategy string
+
+const (
+	// PodNameFixed names the pod <name>-pod
+	PodNameFixed PodNameStrategy = "Fixed"
+	// PodNameGenerated has the API server generate a unique name prefixed with <name>-
+	PodNameGenerated PodNameStrategy = "Generated"
+)
+
+// ApprovalPolicy decides whether new messages wait for approval
+// +kubebuilder:validation:Enum=None;Required
+type ApprovalPolicy string
+
+const (
+	// ApprovalNone rolls out new messages straight away
+	ApprovalNone ApprovalPolicy = "None"
+	// ApprovalRequired holds a new message until its generation is approved
+	ApprovalRequired ApprovalPolicy = "Required"
+)
+
+// RolloutPhase is the state of the rollout of a revision to the replicas of a
+// HelloWorld
+// +kubebuilder:validation:Enum=Progressing;Paused;Completed;Aborted
+type RolloutPhase string
+
+const (
+	// RolloutProgressing is a rollout moving through its steps
+	RolloutProgressing RolloutPhase = "Progressing"
+	// RolloutPaused is a rollout waiting in a pause step
+	RolloutPaused RolloutPhase = "Paused"
+	// RolloutCompleted is a rollout that replaced every replica
+	RolloutCompleted RolloutPhase = "Completed"
+	// RolloutAborted is a rollout that returned every replica to the stable
+	// revision because the HelloWorld became Degraded
+	RolloutAborted RolloutPhase = "Aborted"
+)
+
+// MessageSource selects where a HelloWorld's message is read from, like the
+// valueFrom of a container environment variable. Exactly one field must be set.
+// +kubebuilder:validation:XValidation:rule="[has(self.configMapKeyRef), has(self.secretKeyRef), has(self.fieldRef)].filter(x, x).size() == 1",message="exactly one of configMapKeyRef, secretKeyRef or fieldRef must be set"
+type MessageSource struct {
+	// configMapKeyRef selects a key of a ConfigMap in the HelloWorld's namespace.
+	// The pod is replaced when the value changes.
+	// +optional
+	ConfigMapKeyRef *corev1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+
+	// secretKeyRef selects a key of a Secret in the HelloWorld's namespace. The
+	// value is passed to the pod by reference and never copied into its spec.
+	// The pod is replaced when the value changes.
+	// +optional
+	SecretKeyRef *corev1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+
+	// fieldRef selects a field of the generated pod through the downward API:
+	// metadata.name, metadata.namespace, metadata.uid, metadata.labels['<KEY>'],
+	// metadata.annotations['<KEY>'], spec.nodeName, spec.serviceAccountName,
+	// status.hostIP or status.podIP.
+	// +optional
+	FieldRef *corev1.ObjectFieldSelector `json:"fieldRef,omitempty"`
+}
+
+// ServeSpec configures serving the message over HTTP
+type ServeSpec struct {
+	// port is the port of the Service in front of the greeter. Defaults to 80.
+	// +kubebuilder:default=80
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	Port int32 `json:"port,omitempty"`
+}
+
+// RouteSpec exposes the Service of a HelloWorld in serve mode outside the
+// cluster, with a Gateway API HTTPRoute or else an Ingress
+type RouteSpec struct {
+	// hostnames are the hosts the message is served on, e.g. "hello.example.com".
+	// A leading "*." label matches any subdomain.
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=16
+	// +kubebuilder:validation:items:MaxLength=253
+	// +kubebuilder:validation:items:Pattern=`^(\*\.)?[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`
+	// +listType=set
+	Hostnames []string `json:"hostnames"`
+
+	// pathPrefixes are the path prefixes the message is served under. Defaults
+	// to "/".
+	// +kubebuilder:default={"/"}
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=16
+	// +kubebuilder:validation:items:MaxLength=1024
+	// +kubebuilder:validation:items:Pattern=`^/[A-Za-z0-9/._~%!$&'()*+,;=:@-]*$`
+	// +listType=set
+	// +optional
+	PathPrefixes []string `json:"pathPrefixes,omitempty"`
+
+	// gatewayRef is the Gateway the HTTPRoute attaches to. Without it, or when
+	// the cluster does not serve the Gateway API, an Ingress is created instead.
+	// +optional
+	GatewayRef *GatewayReference `json:"gatewayRef,omitempty"`
+
+	// ingressClassName is the class of the Ingress created when no HTTPRoute can
+	// be. The cluster's default class is used when it is not set.
+	// +optional
+	IngressClassName *string `json:"ingressClassName,omitempty"`
+}
+
+// GatewayReference names a Gateway API Gateway
+type GatewayReference struct {
+	// name is the name of the Gateway.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=253
+	// +required
+	Name string `json:"name"`
+
+	// namespace is the namespace of the Gateway. Defaults to the HelloWorld's
+	// namespace. The Gateway must allow routes from the HelloWorld's namespace.
+	// +kubebuilder:validation:MaxLength=63
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// Delivery posts the message of each spec generation to a webhook
+type Delivery struct {
+	// name identifies the delivery in the status and the metrics.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +required
+	Name string `json:"name"`
+
+	// url is the http or https URL the message is posted to.
+	// +kubebuilder:validation:MaxLength=2048
+	// +kubebuilder:validation:XValidation:rule="isURL(self) && url(self).getScheme() in ['http', 'https']",message="url must be an http or https URL"
+	// +required
+	URL string `json:"url"`
+
+	// headersFrom names a Secret in the HelloWorld's namespace whose keys and
+	// values are sent as request headers, e.g. Authorization. The Secret must be
+	// labeled apps.example.com/delivery=true.
+	// +optional
+	HeadersFrom *corev1.LocalObjectReference `json:"headersFrom,omitempty"`
+
+	// signingKeyRef selects a key of a Secret in the HelloWorld's namespace used
+	// to sign the request with HMAC-SHA256. The signature of "<timestamp>.<body>"
+	// is sent in the X-HelloWorld-Signature header as "sha256=<hex>", and the
+	// Unix timestamp in X-HelloWorld-Timestamp. The Secret must be labeled
+	// apps.example.com/delivery=true.
+	// +optional
+	SigningKeyRef *corev1.SecretKeySelector `json:"signingKeyRef,omitempty"`
+}
+
+// DeliveryState is the state of the delivery of a spec generation
+type DeliveryState string
+
+const (
+	// DeliveryPending is a delivery that has not succeeded yet and is retried
+	DeliveryPending DeliveryState = "Pending"
+	// DeliveryDelivered is a delivery the webhook accepted
+	DeliveryDelivered DeliveryState = "Delivered"
+	// DeliveryFailed is a delivery that was rejected or ran out of attempts
+	DeliveryFailed DeliveryState = "Failed"
+)
+
+// DeliveryStatus reports the delivery of the latest spec generation to a
+// webhook of spec.deliveries
+type DeliveryStatus struct {
+	// name is the name of the delivery in spec.deliveries.
+	// +required
+	Name string `json:"name"`
+
+	// observedGeneration is the metadata.generation being delivered.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// state is Pending while the delivery is attempted, Delivered once the
+	// webhook accepted it and Failed when it was rejected or ran out of attempts.
+	// +optional
+	// +kubebuilder:validation:Enum=Pending;Delivered;Failed
+	State DeliveryState `json:"state,omitempty"`
+
+	// attempts is the number of requests made for this generation.
+	// +optional
+	Attempts int32 `json:"attempts,omitempty"`
+
+	// lastAttemptTime is when the last request was made.
+	// +optional
+	LastAttemptTime *metav1.Time `json:"lastAttemptTime,omitempty"`
+
+	// nextAttemptTime is when the delivery is retried while it is pending.
+	// +optional
+	NextAttemptTime *metav1.Time `json:"nextAttemptTime,omitempty"`
+
+	// lastStatusCode is the HTTP status code of the last response.
+	// +optional
+	LastStatusCode int32 `json:"lastStatusCode,omitempty"`
+
+	// message explains why the last attempt failed.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// RolloutStrategy rolls out a new revision to the replicas of a HelloWorld in
+// steps
+type RolloutStrategy struct {
+	// steps are applied in order. The replicas that are not yet updated keep
+	// running the stable revision, and the remaining replicas are updated once
+	// the last step completes.
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=20
+	// +listType=atomic
+	Steps []RolloutStep `json:"steps"`
+}
+
+// RolloutStep is a step of a canary rollout. Exactly one field must be set.
+// +kubebuilder:validation:XValidation:rule="has(self.setWeight) != has(self.pause)",message="exactly one of setWeight or pause must be set"
+type RolloutStep struct {
+	// setWeight is the percentage of replicas running the new revision, rounded
+	// up to whole replicas. The step completes once they all run.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	SetWeight *int32 `json:"setWeight,omitempty"`
+
+	// pause holds the rollout at the current weight.
+	// +optional
+	Pause *RolloutPause `json:"pause,omitempty"`
+}
+
+// RolloutPause holds a rollout for a duration, or until it is promoted
+type RolloutPause struct {
+	// duration is how long the rollout is held. Without it the rollout is held
+	// until the apps.example.com/promote annotation is set to the revision.
+	// +optional
+	Duration *metav1.Duration `json:"duration,omitempty"`
+}
+
+// RolloutStatus reports the rollout of a revision to the replicas of a
+// HelloWorld
+type RolloutStatus struct {
+	// revision is the name of the ControllerRevision being rolled out.
+	Revision string `json:"revision"`
+
+	// stableRevision is the name of the ControllerRevision the replicas that are
+	// not updated run, and that an aborted rollout returns to.
+	// +optional
+	StableRevision string `json:"stableRevision,omitempty"`
+
+	// currentStepIndex is the index of the step of spec.rollout in progress. It
+	// equals the number of steps once they all completed.
+	// +optional
+	CurrentStepIndex int32 `json:"currentStepIndex"`
+
+	// weight is the percentage of replicas the new revision is rolled out to.
+	// +optional
+	Weight int32 `json:"weight"`
+
+	// phase is Progressing while the rollout moves through its steps, Paused in
+	// a pause step, Completed once every replica runs the revision, and Aborted
+	// when the HelloWorld became Degraded during a step.
+	// +optional
+	Phase RolloutPhase `json:"phase,omitempty"`
+
+	// startTime is when the rollout started.
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// pauseStartTime is when the current pause step started.
+	// +optional
+	PauseStartTime *metav1.Time `json:"pauseStartTime,omitempty"`
+
+	// message explains the phase.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// RollbackConfig selects the revision a HelloWorld is rolled back to
+type RollbackConfig struct {
+	// revision is the number of the revision to roll back to, as recorded in
+	// the ControllerRevision. 0 rolls back to the revision before the latest.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Revision int64 `json:"revision,omitempty"`
+}
+
+// ApprovalStatus records the approval of a message
+type ApprovalStatus struct {
+	// generation is the metadata.generation that was approved.
+	Generation int64 `json:"generation"`
+
+	// messageHash is the hash of the approved message, as in the
+	// apps.example.com/message-hash annotation of the pod.
+	MessageHash string `json:"messageHash"`
+
+	// approver is the user who approved the generation, as recorded by the
+	// HelloWorld webhook. It is empty when the webhook does not serve, since
+	// the annotation cannot be trusted then.
+	// +optional
+	Approver string `json:"approver,omitempty"`
+
+	// approvedAt is when the controller observed the approval.
+	ApprovedAt metav1.Time `json:"approvedAt"`
+}
+
 // HelloWorldSpec defines the desired state of HelloWorld
+// +kubebuilder:validation:XValidation:rule="[has(self.message), has(self.messageFrom), has(self.messageTemplate), has(self.messages)].filter(x, x).size() == 1",message="exactly one of message, messageFrom, messageTemplate or messages must be set"
+// +kubebuilder:validation:XValidation:rule="has(self.messages) == has(self.defaultLocale)",message="defaultLocale must be set together with messages"
+// +kubebuilder:validation:XValidation:rule="!has(self.messages) || !has(self.defaultLocale) || self.defaultLocale in self.messages",message="defaultLocale must be a key of messages"
+// +kubebuilder:validation:XValidation:rule="!has(self.locale) || has(self.messages)",message="locale requires messages"
+// +kubebuilder:validation:XValidation:rule="!has(self.route) || has(self.serve)",message="route requires serve"
+// +kubebuilder:validation:XValidation:rule="!has(self.rollout) || (has(self.replicas) && self.replicas > 1)",message="rollout requires more than one replica"
 type HelloWorldSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 	// The following markers will use OpenAPI v3 schema to validate the value
 	// More info: https://book.kubebuilder.io/reference/markers/crd-validation.html
 
-	// Message is the message to be displayed
-	// +kubebuilder:validation:Required
-	Message string `json:"message"`
+	// message is the greeting the generated pod prints to its log when it starts,
+	// at most 256 characters. It is passed to the pod's shell in single quotes,
+	// so it cannot contain single quotes or control characters such as newlines.
+	// Changing it replaces the pod. Exactly one of message, messageFrom,
+	// messageTemplate or messages must be set.
+	// +optional
+	// +kubebuilder:validation:MaxLength=256
+	// +kubebuilder:validation:XValidation:rule=`!self.matches('^\\s*$')`,message="message must not be blank"
+	// +kubebuilder:validation:XValidation:rule=`!self.matches('[\\x00-\\x1f\\x7f\']')`,message="message must not contain single quotes or control characters"
+	Message string `json:"message,omitempty"`
+
+	// messageFrom reads the message from a ConfigMap, a Secret or a field of the
+	// generated pod instead. The referenced value is passed to the pod as is.
+	// +optional
+	MessageFrom *MessageSource `json:"messageFrom,omitempty"`
+
+	// messageTemplate is a Go text/template rendered into the message, at most
+	// 1024 characters before and after rendering. It can use the HelloWorld's
+	// .Name, .Namespace, .UID, .Generation, .Labels and .Annotations, the pod's
+	// .PodName, .PodIP, .HostIP, .NodeName and .ServiceAccountName, and .Now, the
+	// time the pod was created. The functions lower, upper, trim, trimPrefix,
+	// trimSuffix, replace, default and truncate are available besides the
+	// builtins and, or, not, len, index, print and the comparisons. Pod values are
+	// filled in when the container starts, so they should be inserted as they are.
+	// +optional
+	// +kubebuilder:validation:MaxLength=1024
+	// +kubebuilder:validation:XValidation:rule=`!self.matches('[\x00-\x08\x0b-\x1f\x7f]')`,message="messageTemplate must not contain control characters other than tabs and newlines"
+	MessageTemplate string `json:"messageTemplate,omitempty"`
+
+	// messages maps BCP 47 locale tags such as "en", "de-CH" or "zh-Hant" to the
+	// message for that locale, each at most 256 characters. The pod prints the
+	// message of the selected locale, or every message in a container per
+	// locale when none is selected.
+	// +optional
+	// +kubebuilder:validation:MinProperties=1
+	// +kubebuilder:validation:MaxProperties=16
+	// +kubebuilder:validation:XValidation:rule="self.all(k, k.size() <= 35 && self[k].size() <= 256)",message="locales must be at most 35 and messages at most 256 characters"
+	Messages map[string]string `json:"messages,omitempty"`
+
+	// defaultLocale is the key of messages used when no message matches the
+	// selected locale. Required with messages.
+	// +optional
+	// +kubebuilder:validation:MaxLength=35
+	DefaultLocale string `json:"defaultLocale,omitempty"`
+
+	// locale selects the locale whose message the pod prints, overriding the
+	// namespace's apps.example.com/locale annotation. A locale without a message
+	// falls back to its parents, e.g. de-CH to de, and then to defaultLocale.
+	// +optional
+	// +kubebuilder:validation:MaxLength=35
+	Locale string `json:"locale,omitempty"`
+
+	// podNameStrategy decides how the generated pod is named. "Fixed" names it
+	// <name>-pod, which conflicts with any other pod of that name unless the pod
+	// opts in to adoption with the apps.example.com/adopt annotation. "Generated"
+	// has the API server generate a unique name prefixed with <name>-. Defaults
+	// to "Fixed" and cannot be changed after creation.
+	// +kubebuilder:default=Fixed
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="podNameStrategy is immutable"
+	// +optional
+	PodNameStrategy PodNameStrategy `json:"podNameStrategy,omitempty"`
+
+	// serve runs a greeter that serves the message over HTTP instead of printing
+	// it, behind a Service named after the HelloWorld. Localized messages are
+	// negotiated with the request's Accept-Language header. Turning serve mode
+	// on or off replaces the pod.
+	// +optional
+	Serve *ServeSpec `json:"serve,omitempty"`
+
+	// route exposes the served message outside the cluster on hostnames and
+	// path prefixes. Requires serve.
+	// +optional
+	Route *RouteSpec `json:"route,omitempty"`
+
+	// deliveries are webhooks each spec generation's message is posted to as
+	// JSON, retried with exponential backoff until they accept it.
+	// +optional
+	// +kubebuilder:validation:MaxItems=8
+	// +listType=map
+	// +listMapKey=name
+	Deliveries []Delivery `json:"deliveries,omitempty"`
+
+	// revisionHistoryLimit is the number of old ControllerRevisions kept to
+	// allow rollback, besides the current and update revisions. Defaults to 10.
+	// +kubebuilder:default=10
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+
+	// rollbackTo restores the spec recorded in a revision. The controller
+	// replaces the spec with it and clears rollbackTo.
+	// +optional
+	RollbackTo *RollbackConfig `json:"rollbackTo,omitempty"`
+
+	// approvalPolicy "Required" holds a new message in the AwaitingApproval
+	// phase, neither rolled out nor delivered, until the
+	// apps.example.com/approve-generation annotation is set to the generation
+	// that introduced it. Defaults to "None".
+	// +kubebuilder:default=None
+	// +optional
+	ApprovalPolicy ApprovalPolicy `json:"approvalPolicy,omitempty"`
+
+	// replicas is the number of pods that print or serve the message. With more
+	// than one, the pods get generated names and each runs the revision of the
+	// spec it was created from, and a new revision is rolled out as spec.rollout
+	// specifies. Defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=20
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// rollout rolls out a new revision to the replicas in steps, shifting them
+	// by weight and pausing between steps. The rollout returns every replica to
+	// the previous revision when the HelloWorld becomes Degraded during a step.
+	// Without it every replica is replaced at once. Requires more than one
+	// replica.
+	// +optional
+	Rollout *RolloutStrategy `json:"rollout,omitempty"`
 }
 
 // HelloWorldStatus defines the observed state of HelloWorld.
@@ -58,38 +518,125 @@ type HelloWorldStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 
-	// Conditions represent the latest available observations of the HelloWorld's state
+	// conditions are the latest observations of the HelloWorld's state. "Ready" is
+	// true while the pod runs, "Progressing" while the pod is being created or
+	// replaced, and "Degraded" when the pod failed or cannot be managed, with the
+	// cause in its reason, e.g. "NameConflict". "MessageResolved" is false while
+	// spec.messageFrom cannot be read, spec.messageTemplate cannot be rendered or
+	// spec.messages has no message for the locale, e.g. with reason
+	// "ConfigMapNotFound", "TemplateInvalid" or "InvalidLocale". "Serving" is
+	// true in serve mode while the Service has ready endpoints.
 	// +optional
 	// +listType=map
 	// +listMapKey=type
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
 
-	// Phase represents the current phase of the HelloWorld resource
+	// phase summarises the state of the generated pod: Pending until it starts,
+	// Running, Failed when it failed or could not be created, or Unknown.
+	// AwaitingApproval while a new message waits for approval.
 	// +optional
-	// +kubebuilder:validation:Enum=Pending;Running;Failed;Unknown
+	// +kubebuilder:validation:Enum=Pending;Running;Failed;Unknown;AwaitingApproval
 	Phase string `json:"phase,omitempty"`
 
-	// PodName is the name of the pod created for this HelloWorld resource
+	// podName is the name of the pod the HelloWorld currently manages, if any.
+	// It is empty with more than one replica.
 	// +optional
 	PodName string `json:"podName,omitempty"`
 
-	// Message contains any additional information about the current status
+	// podUID is the UID of the pod the HelloWorld created or adopted, used to
+	// recognise the pod if its owner reference is removed.
+	// +optional
+	PodUID types.UID `json:"podUID,omitempty"`
+
+	// renderedMessage is spec.messageTemplate as rendered for the current pod.
+	// +optional
+	RenderedMessage string `json:"renderedMessage,omitempty"`
+
+	// activeLocales are the locales of spec.messages the current pod prints.
+	// +optional
+	// +listType=atomic
+	ActiveLocales []string `json:"activeLocales,omitempty"`
+
+	// endpoint is the URL of the Service serving the message in serve mode.
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// routeConditions are the conditions the Gateway controller reported for the
+	// HTTPRoute of spec.route, such as "Accepted" and "ResolvedRefs". They are
+	// empty while an Ingress exposes the message instead.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	RouteConditions []metav1.Condition `json:"routeConditions,omitempty"`
+
+	// deliveries report the delivery of the latest generation to each webhook of
+	// spec.deliveries.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Deliveries []DeliveryStatus `json:"deliveries,omitempty"`
+
+	// currentRevision is the name of the ControllerRevision the running pod was
+	// created from. With replicas, it is the revision of the last completed
+	// rollout.
+	// +optional
+	CurrentRevision string `json:"currentRevision,omitempty"`
+
+	// updateRevision is the name of the ControllerRevision of the current spec.
+	// It differs from currentRevision while the pod is rolled out.
+	// +optional
+	UpdateRevision string `json:"updateRevision,omitempty"`
+
+	// replicas is the number of pods running for spec.replicas, of any revision.
+	// It is empty with a single replica.
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// updatedReplicas is the number of those pods running the update revision.
+	// +optional
+	UpdatedReplicas int32 `json:"updatedReplicas,omitempty"`
+
+	// readyReplicas is the number of those pods that are running.
+	// +optional
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+
+	// rollout reports the rollout of the update revision to the replicas.
+	// +optional
+	Rollout *RolloutStatus `json:"rollout,omitempty"`
+
+	// approval records the latest approved message when approvalPolicy is
+	// Required.
+	// +optional
+	Approval *ApprovalStatus `json:"approval,omitempty"`
+
+	// message is a human-readable explanation of the current phase.
 	// +optional
 	Message string `json:"message,omitempty"`
 
-	// LastUpdateTime is the last time the status was updated
+	// lastUpdateTime is when the controller last wrote the status.
 	// +optional
 	LastUpdateTime *metav1.Time `json:"lastUpdateTime,omitempty"`
 
-	// ObservedGeneration reflects the generation of the most recently observed HelloWorld spec
+	// observedGeneration is the metadata.generation of the spec the status
+	// describes. The status is stale while it is lower than metadata.generation.
 	// +optional
 	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=hw,categories=greetings
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`,description="Phase of the generated pod"
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`,description="Whether the generated pod is running"
+// +kubebuilder:printcolumn:name="Pod",type=string,JSONPath=`.status.podName`,description="Name of the generated pod"
+// +kubebuilder:printcolumn:name="Message",type=string,JSONPath=`.spec.message`,description="Greeting printed by the pod"
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+// +kubebuilder:validation:XValidation:rule="self.metadata.name.size() <= 63",message="name must be at most 63 characters, since it labels the generated pod"
+// +kubebuilder:validation:XValidation:rule="!has(self.spec.serve) || self.metadata.name.matches('^[a-z]')",message="name must start with a letter in serve mode, since it names the Service"
 
-// HelloWorld is the Schema for the helloworlds API
+// HelloWorld runs a pod that prints a greeting. The controller creates the pod,
+// keeps the fields it manages as specified, and reports the pod's state in the
+// status.
 type HelloWorld struct {
 	metav1.TypeMeta `json:",inline"`
 
@@ -97,11 +644,11 @@ type HelloWorld struct {
 	// +optional
 	metav1.ObjectMeta `json:"metadata,omitempty,omitzero"`
 
-	// spec defines the desired state of HelloWorld
+	// spec defines the greeting and how its pod is created.
 	// +required
 	Spec HelloWorldSpec `json:"spec"`
 
-	// status defines the observed state of HelloWorld
+	// status reports the state of the generated pod, as observed by the controller.
 	// +optional
 	Status HelloWorldStatus `json:"status,omitempty,omitzero"`
 }
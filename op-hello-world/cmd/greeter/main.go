@@ -0,0 +1,78 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command greeter serves the message of a HelloWorld in serve mode over HTTP.
+// The controller passes the message in environment variables; see package
+// greeter.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/example/op-hello-world/internal/greeter"
+)
+
+func main() {
+	var listen string
+	var shutdownTimeout time.Duration
+	flag.StringVar(&listen, "listen", fmt.Sprintf(":%d", greeter.Port), "The address the greeter listens on.")
+	flag.DurationVar(&shutdownTimeout, "shutdown-timeout", 5*time.Second,
+		"How long in-flight requests are given to finish on SIGTERM.")
+	flag.Parse()
+
+	log := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	g, err := greeter.New(os.Getenv)
+	if err != nil {
+		log.Error("Invalid message", "error", err)
+		os.Exit(1)
+	}
+
+	server := &http.Server{
+		Addr:              listen,
+		Handler:           g.Handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	// ListenAndServe returns as soon as Shutdown starts, so main waits for done
+	// to give in-flight requests their time
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Error("Failed to shut down", "error", err)
+		}
+	}()
+
+	log.Info("Serving greeting", "address", listen)
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		log.Error("Failed to serve", "error", err)
+		os.Exit(1)
+	}
+	<-done
+}
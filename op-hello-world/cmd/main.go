@@ -20,17 +20,26 @@ import (
 	"context"
 	"crypto/tls"
 	"flag"
-	"net/http"
-	_ "net/http/pprof"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	_ "github.com/example/op-hello-world/internal/metrics" // Register custom metrics
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	"github.com/go-logr/zapr"
+	uberzap "go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -40,10 +49,19 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
 
 	appsv1 "github.com/example/op-hello-world/api/v1"
+	"github.com/example/op-hello-world/internal/cloudevents"
+	"github.com/example/op-hello-world/internal/config"
 	"github.com/example/op-hello-world/internal/controller"
+	"github.com/example/op-hello-world/internal/delivery"
+	"github.com/example/op-hello-world/internal/health"
+	"github.com/example/op-hello-world/internal/profiling"
+	"github.com/example/op-hello-world/internal/sharding"
+	"github.com/example/op-hello-world/internal/shutdown"
 	"github.com/example/op-hello-world/internal/tracing"
+	webhookappsv1 "github.com/example/op-hello-world/internal/webhook/v1"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -55,47 +73,82 @@ var (
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
+	utilruntime.Must(gatewayv1.Install(scheme))
+
 	utilruntime.Must(appsv1.AddToScheme(scheme))
 	// +kubebuilder:scaffold:scheme
 }
 
-// nolint:gocyclo
 func main() {
-	var metricsAddr string
-	var metricsCertPath, metricsCertName, metricsCertKey string
-	var webhookCertPath, webhookCertName, webhookCertKey string
-	var enableLeaderElection bool
-	var probeAddr string
-	var secureMetrics bool
-	var enableHTTP2 bool
-	var pprofAddr string
+	os.Exit(run())
+}
+
+// run sets up and runs the manager, returning the process exit code. Keeping
+// this out of main lets deferred telemetry shutdown run on every exit path.
+// nolint:gocyclo
+func run() int {
+	var configFile string
 	var tlsOpts []func(*tls.Config)
-	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
-		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
-	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
-	flag.StringVar(&pprofAddr, "pprof-bind-address", "", "The address the pprof endpoint binds to. "+
-		"Use :6060 for HTTP, or leave empty to disable pprof.")
-	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
-		"Enable leader election for controller manager. "+
-			"Enabling this will ensure there is only one active controller manager.")
-	flag.BoolVar(&secureMetrics, "metrics-secure", true,
-		"If set, the metrics endpoint is served securely via HTTPS. Use --metrics-secure=false to use HTTP instead.")
-	flag.StringVar(&webhookCertPath, "webhook-cert-path", "", "The directory that contains the webhook certificate.")
-	flag.StringVar(&webhookCertName, "webhook-cert-name", "tls.crt", "The name of the webhook certificate file.")
-	flag.StringVar(&webhookCertKey, "webhook-cert-key", "tls.key", "The name of the webhook key file.")
-	flag.StringVar(&metricsCertPath, "metrics-cert-path", "",
-		"The directory that contains the metrics server certificate.")
-	flag.StringVar(&metricsCertName, "metrics-cert-name", "tls.crt", "The name of the metrics server certificate file.")
-	flag.StringVar(&metricsCertKey, "metrics-cert-key", "tls.key", "The name of the metrics server key file.")
-	flag.BoolVar(&enableHTTP2, "enable-http2", false,
-		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flagConfig := config.Default()
+	flag.StringVar(&configFile, "config", "",
+		"The manager config file (kind ManagerConfig). Flags set on the command line override its settings.")
+	config.BindFlags(flag.CommandLine, &flagConfig)
 	opts := zap.Options{
 		Development: false, // Use JSON format for production-style logs
 	}
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
 
-	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+	// Log through an atomic level so logging.level can be changed without a restart
+	logLevel, ok := opts.Level.(uberzap.AtomicLevel)
+	if !ok {
+		logLevel = uberzap.NewAtomicLevelAt(zapcore.InfoLevel)
+		opts.Level = logLevel
+	}
+	zapLevelFlagSet := false
+	flag.Visit(func(f *flag.Flag) { zapLevelFlagSet = zapLevelFlagSet || f.Name == "zap-log-level" })
+
+	// The zap logger is kept so the shutdown coordinator can flush it
+	logger := zap.NewRaw(zap.UseFlagOptions(&opts))
+	ctrl.SetLogger(zapr.NewLogger(logger))
+
+	// The config file is loaded over the defaults, then the flags set on the command
+	// line are applied again so that they take precedence over the file
+	loadConfig := func() (config.ManagerConfig, error) {
+		cfg := config.Default()
+		if err := config.Load(configFile, &cfg); err != nil {
+			return cfg, err
+		}
+		if err := config.ApplyFlags(flag.CommandLine, &cfg); err != nil {
+			return cfg, err
+		}
+		return cfg, cfg.Validate()
+	}
+
+	cfg := flagConfig
+	if configFile != "" {
+		var err error
+		if cfg, err = loadConfig(); err != nil {
+			setupLog.Error(err, "unable to load manager config", "path", configFile)
+			return 1
+		}
+		setupLog.Info("Loaded manager config", "path", configFile)
+	} else if err := cfg.Validate(); err != nil {
+		setupLog.Error(err, "invalid manager config")
+		return 1
+	}
+	configStore := config.NewStore(cfg)
+
+	// applyRuntimeConfig pushes the settings that can be reloaded to their components
+	applyRuntimeConfig := func(c *config.ManagerConfig) {
+		tracing.SetSamplingRatio(c.Telemetry.SamplingRatio)
+		if c.Logging.Level != "" && !zapLevelFlagSet {
+			if level, err := config.ParseLogLevel(c.Logging.Level); err == nil {
+				logLevel.SetLevel(level)
+			}
+		}
+	}
+	applyRuntimeConfig(&cfg)
 
 	///////////////////////////////
 	// Custom code start
@@ -103,29 +156,28 @@ func main() {
 
 	ctx := context.Background()
 
+	otlpEndpoint := cfg.Telemetry.OTLPEndpoint
+	if otlpEndpoint == "" {
+		otlpEndpoint = tracing.Endpoint()
+	}
+
+	// Telemetry providers register with the coordinator, which flushes them within a
+	// bounded timeout on every exit path, after the manager has stopped. Logs are
+	// registered first so they are flushed last, after the other components have
+	// reported. Metrics are scraped from the Prometheus registry and need no flush.
+	coordinator := shutdown.NewCoordinator(cfg.Telemetry.FlushTimeout.Duration, ctrl.Log.WithName("shutdown"))
+	defer coordinator.Shutdown()
+	coordinator.Register("logs", shutdown.SyncLogger(logger))
+
 	// Initialize tracing
-	shutdownTracing, err := tracing.InitTracer(ctx, "op-hello-world")
+	shutdownTracing, err := tracing.InitTracer(ctx, "op-hello-world", otlpEndpoint)
 	if err != nil {
 		setupLog.Error(err, "Failed to initialize tracing")
 	} else {
-		defer func() {
-			if err := shutdownTracing(ctx); err != nil {
-				setupLog.Error(err, "Failed to shutdown tracing")
-			}
-		}()
+		coordinator.Register("traces", shutdownTracing)
 		setupLog.Info("OpenTelemetry tracing initialized")
 	}
 
-	// Initialize pprof server if enabled
-	if pprofAddr != "" {
-		go func() {
-			setupLog.Info("Starting pprof server", "address", pprofAddr)
-			if err := http.ListenAndServe(pprofAddr, nil); err != nil {
-				setupLog.Error(err, "Failed to start pprof server")
-			}
-		}()
-	}
-
 	// Custom code end
 	///////////////////////////////"
 
@@ -140,7 +192,7 @@ func main() {
 		c.NextProtos = []string{"http/1.1"}
 	}
 
-	if !enableHTTP2 {
+	if !cfg.EnableHTTP2 {
 		tlsOpts = append(tlsOpts, disableHTTP2)
 	}
 
@@ -150,18 +202,19 @@ func main() {
 	// Initial webhook TLS options
 	webhookTLSOpts := tlsOpts
 
-	if len(webhookCertPath) > 0 {
+	if len(cfg.Webhook.CertPath) > 0 {
 		setupLog.Info("Initializing webhook certificate watcher using provided certificates",
-			"webhook-cert-path", webhookCertPath, "webhook-cert-name", webhookCertName, "webhook-cert-key", webhookCertKey)
+			"webhook-cert-path", cfg.Webhook.CertPath, "webhook-cert-name", cfg.Webhook.CertName,
+			"webhook-cert-key", cfg.Webhook.CertKey)
 
 		var err error
 		webhookCertWatcher, err = certwatcher.New(
-			filepath.Join(webhookCertPath, webhookCertName),
-			filepath.Join(webhookCertPath, webhookCertKey),
+			filepath.Join(cfg.Webhook.CertPath, cfg.Webhook.CertName),
+			filepath.Join(cfg.Webhook.CertPath, cfg.Webhook.CertKey),
 		)
 		if err != nil {
 			setupLog.Error(err, "Failed to initialize webhook certificate watcher")
-			os.Exit(1)
+			return 1
 		}
 
 		webhookTLSOpts = append(webhookTLSOpts, func(config *tls.Config) {
@@ -178,12 +231,12 @@ func main() {
 	// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.21.0/pkg/metrics/server
 	// - https://book.kubebuilder.io/reference/metrics.html
 	metricsServerOptions := metricsserver.Options{
-		BindAddress:   metricsAddr,
-		SecureServing: secureMetrics,
+		BindAddress:   cfg.Metrics.BindAddress,
+		SecureServing: cfg.Metrics.Secure,
 		TLSOpts:       tlsOpts,
 	}
 
-	if secureMetrics {
+	if cfg.Metrics.Secure {
 		// FilterProvider is used to protect the metrics endpoint with authn/authz.
 		// These configurations ensure that only authorized users and service accounts
 		// can access the metrics endpoint. The RBAC are configured in 'config/rbac/kustomization.yaml'. More info:
@@ -191,6 +244,18 @@ func main() {
 		metricsServerOptions.FilterProvider = filters.WithAuthenticationAndAuthorization
 	}
 
+	// pprof handlers share the metrics server so they get the same TLS listener and
+	// TokenReview/SubjectAccessReview checks; the config is rejected unless metrics are
+	// secure. Callers need the 'pprof-reader' ClusterRole configured in 'config/base/rbac/base'.
+	if cfg.Pprof.Enabled && cfg.Metrics.Secure {
+		if cfg.Metrics.BindAddress == "0" {
+			setupLog.Info("pprof requested but the metrics endpoint is disabled; pprof will not be served")
+		} else {
+			setupLog.Info("Serving pprof on the metrics endpoint", "path", "/debug/pprof/")
+			metricsServerOptions.ExtraHandlers = profiling.PprofHandlers()
+		}
+	}
+
 	// If the certificate is not specified, controller-runtime will automatically
 	// generate self-signed certificates for the metrics server. While convenient for development and testing,
 	// this setup is not recommended for production.
@@ -199,18 +264,19 @@ func main() {
 	// - [METRICS-WITH-CERTS] at config/default/kustomization.yaml to generate and use certificates
 	// managed by cert-manager for the metrics server.
 	// - [PROMETHEUS-WITH-CERTS] at config/prometheus/kustomization.yaml for TLS certification.
-	if len(metricsCertPath) > 0 {
+	if len(cfg.Metrics.CertPath) > 0 {
 		setupLog.Info("Initializing metrics certificate watcher using provided certificates",
-			"metrics-cert-path", metricsCertPath, "metrics-cert-name", metricsCertName, "metrics-cert-key", metricsCertKey)
+			"metrics-cert-path", cfg.Metrics.CertPath, "metrics-cert-name", cfg.Metrics.CertName,
+			"metrics-cert-key", cfg.Metrics.CertKey)
 
 		var err error
 		metricsCertWatcher, err = certwatcher.New(
-			filepath.Join(metricsCertPath, metricsCertName),
-			filepath.Join(metricsCertPath, metricsCertKey),
+			filepath.Join(cfg.Metrics.CertPath, cfg.Metrics.CertName),
+			filepath.Join(cfg.Metrics.CertPath, cfg.Metrics.CertKey),
 		)
 		if err != nil {
 			setupLog.Error(err, "to initialize metrics certificate watcher", "error", err)
-			os.Exit(1)
+			return 1
 		}
 
 		metricsServerOptions.TLSOpts = append(metricsServerOptions.TLSOpts, func(config *tls.Config) {
@@ -218,36 +284,115 @@ func main() {
 		})
 	}
 
+	// Restricting the watch lets several managers split tenants by namespace or label,
+	// and lets a manager scoped to namespaces run with namespaced Roles only
+	cacheOptions, err := controller.CacheOptions(cfg.Watch)
+	if err != nil {
+		setupLog.Error(err, "invalid watch configuration")
+		return 1
+	}
+	if len(cfg.Watch.Namespaces) > 0 || cfg.Watch.LabelSelector != "" {
+		setupLog.Info("Restricting watch", "namespaces", cfg.Watch.Namespaces, "labelSelector", cfg.Watch.LabelSelector)
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
+		Cache:                  cacheOptions,
+		NewClient:              tracing.NewClient, // Trace every Kubernetes API call
 		Metrics:                metricsServerOptions,
 		WebhookServer:          webhookServer,
-		HealthProbeBindAddress: probeAddr,
-		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "0ae07d5a.example.com",
+		HealthProbeBindAddress: cfg.Health.ProbeBindAddress,
+		LeaderElection:         cfg.LeaderElection.Enabled,
+		LeaderElectionID:       cfg.LeaderElection.ID,
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
 		// when the Manager ends. This requires the binary to immediately end when the
 		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
 		// speeds up voluntary leader transitions as the new leader don't have to wait
 		// LeaseDuration time first.
 		//
-		// In the default scaffold provided, the program ends immediately after
-		// the manager stops, so would be fine to enable this option. However,
-		// if you are doing or is intended to do any operation such as perform cleanups
-		// after the manager stops then its usage might be unsafe.
-		// LeaderElectionReleaseOnCancel: true,
+		// After the manager stops, the only remaining work is the shutdown coordinator
+		// flushing telemetry, which does not touch the cluster and is bounded by
+		// telemetry.flushTimeout, so releasing the lease early is safe.
+		LeaderElectionReleaseOnCancel: true,
+		GracefulShutdownTimeout:       &cfg.Controller.GracefulShutdownTimeout.Duration,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
-		os.Exit(1)
+		return 1
 	}
 
-	if err := (&controller.HelloWorldReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-	}).SetupWithManager(mgr); err != nil {
+	reconciler := &controller.HelloWorldReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Config:   configStore,
+		Recorder: mgr.GetEventRecorderFor("helloworld-controller"),
+	}
+
+	// HTTPRoutes are only watched when the Gateway API CRDs are installed, and
+	// routes fall back to Ingresses otherwise
+	_, err = mgr.GetRESTMapper().RESTMapping(schema.GroupKind{Group: gatewayv1.GroupName, Kind: "HTTPRoute"}, gatewayv1.GroupVersion.Version)
+	switch {
+	case err == nil:
+		reconciler.GatewayAPI = true
+	case meta.IsNoMatchError(err):
+		setupLog.Info("Gateway API is not installed, exposing routes with Ingresses")
+	default:
+		setupLog.Error(err, "unable to discover the Gateway API")
+		return 1
+	}
+
+	var sharder *sharding.Sharder
+	if cfg.Sharding.Enabled {
+		sharder, err = newSharder(mgr, cfg)
+		if err != nil {
+			setupLog.Error(err, "unable to create sharder")
+			return 1
+		}
+		setupLog.Info("Adding sharder to manager", "group", cfg.LeaderElection.ID, "key", cfg.Sharding.Key)
+		if err := mgr.Add(sharder); err != nil {
+			setupLog.Error(err, "unable to add sharder to manager")
+			return 1
+		}
+		reconciler.Shard = sharder
+	}
+
+	if cfg.CloudEvents.Sink != "" {
+		emitter := cloudevents.NewEmitter(cloudevents.Options{
+			Sink:    cfg.CloudEvents.Sink,
+			Timeout: cfg.CloudEvents.Timeout.Duration,
+		}, ctrl.Log.WithName("cloudevents"))
+		setupLog.Info("Adding CloudEvents emitter to manager", "sink", cfg.CloudEvents.Sink)
+		if err := mgr.Add(emitter); err != nil {
+			setupLog.Error(err, "unable to add CloudEvents emitter to manager")
+			return 1
+		}
+		reconciler.CloudEvents = emitter
+	}
+
+	dispatcher, err := newDispatcher(ctx, mgr, cfg, reconciler)
+	if err != nil {
+		setupLog.Error(err, "unable to create delivery dispatcher")
+		return 1
+	}
+	setupLog.Info("Adding delivery dispatcher to manager")
+	if err := mgr.Add(dispatcher); err != nil {
+		setupLog.Error(err, "unable to add delivery dispatcher to manager")
+		return 1
+	}
+	reconciler.Deliveries = dispatcher
+
+	if err := reconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "HelloWorld")
-		os.Exit(1)
+		return 1
+	}
+	// The webhook needs a serving certificate, so it only runs when one is
+	// configured; HelloWorld approvers are not recorded without it
+	if webhookCertWatcher != nil {
+		if err := webhookappsv1.SetupHelloWorldWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "HelloWorld")
+			return 1
+		}
+		reconciler.ApprovalWebhook = true
 	}
 	// +kubebuilder:scaffold:builder
 
@@ -255,7 +400,7 @@ func main() {
 		setupLog.Info("Adding metrics certificate watcher to manager")
 		if err := mgr.Add(metricsCertWatcher); err != nil {
 			setupLog.Error(err, "unable to add metrics certificate watcher to manager")
-			os.Exit(1)
+			return 1
 		}
 	}
 
@@ -263,22 +408,173 @@ func main() {
 		setupLog.Info("Adding webhook certificate watcher to manager")
 		if err := mgr.Add(webhookCertWatcher); err != nil {
 			setupLog.Error(err, "unable to add webhook certificate watcher to manager")
-			os.Exit(1)
+			return 1
+		}
+	}
+
+	if cfg.Telemetry.Profiling.Enabled {
+		profiler, err := profiling.NewProfiler(profiling.Config{
+			ServerAddress:        cfg.Telemetry.Profiling.ServerAddress,
+			ApplicationName:      "op-hello-world",
+			UploadRate:           cfg.Telemetry.Profiling.UploadRate.Duration,
+			MutexProfileFraction: cfg.Telemetry.Profiling.MutexFraction,
+		}, ctrl.Log.WithName("profiling"))
+		if err != nil {
+			setupLog.Error(err, "unable to create profiler")
+			return 1
+		}
+		setupLog.Info("Adding continuous profiler to manager")
+		if err := mgr.Add(profiler); err != nil {
+			setupLog.Error(err, "unable to add profiler to manager")
+			return 1
+		}
+	}
+
+	if configFile != "" {
+		// Reload events are recorded on the manager's own pod when the downward API
+		// provides it, so they show up in 'kubectl describe pod'
+		var eventObject runtime.Object
+		if podName := os.Getenv("POD_NAME"); podName != "" {
+			eventObject = &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Name:      podName,
+				Namespace: os.Getenv("POD_NAMESPACE"),
+				UID:       types.UID(os.Getenv("POD_UID")),
+			}}
+		}
+		reloader, err := config.NewReloader(configStore, config.ReloaderOptions{
+			Path:        configFile,
+			Load:        loadConfig,
+			Apply:       applyRuntimeConfig,
+			Recorder:    mgr.GetEventRecorderFor("op-hello-world-config"),
+			EventObject: eventObject,
+		}, ctrl.Log.WithName("config"))
+		if err != nil {
+			setupLog.Error(err, "unable to create config reloader")
+			return 1
+		}
+		setupLog.Info("Adding config reloader to manager")
+		if err := mgr.Add(reloader); err != nil {
+			setupLog.Error(err, "unable to add config reloader to manager")
+			return 1
 		}
 	}
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
-		os.Exit(1)
+		return 1
+	}
+	// Each readiness check is served individually under /readyz/<name>; use /readyz?verbose
+	// to list them. Degraded checks are reported in logs and the helloworld_readiness_check
+	// metric but never mark the manager unready.
+	readyChecks := []health.Check{
+		{Name: "informer-sync", Checker: health.CacheSynced(mgr.GetCache(), 500*time.Millisecond)},
+		{Name: "otlp-exporter", Checker: health.Reachable(otlpEndpoint, 500*time.Millisecond), Degraded: true},
+		{Name: "leader-election", Checker: health.LeaderElected(mgr.Elected()), Degraded: true},
+	}
+	if sharder != nil {
+		readyChecks = append(readyChecks, health.Check{Name: "shard-membership", Checker: sharder.Joined})
 	}
-	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
+	if webhookCertWatcher != nil {
+		// Only check the webhook server when webhooks are configured, since asking the
+		// manager for it registers the server to be started.
+		readyChecks = append(readyChecks, health.Check{Name: "webhook", Checker: mgr.GetWebhookServer().StartedChecker()})
+	}
+	if err := health.AddReadyzChecks(mgr, ctrl.Log.WithName("health"), readyChecks...); err != nil {
 		setupLog.Error(err, "unable to set up ready check")
-		os.Exit(1)
+		return 1
 	}
 
+	// On SIGTERM the manager stops its controllers and servers and releases the leader
+	// lease; the deferred coordinator then flushes telemetry before the process exits.
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
 		setupLog.Error(err, "problem running manager")
-		os.Exit(1)
+		return 1
+	}
+	setupLog.Info("manager stopped")
+	return 0
+}
+
+// newSharder returns the Sharder for this replica. Shard Leases live in the
+// manager's namespace, where the leader election Role already grants access,
+// and the replicas are grouped by the leader election ID.
+func newSharder(mgr ctrl.Manager, cfg config.ManagerConfig) (*sharding.Sharder, error) {
+	namespace, err := managerNamespace()
+	if err != nil {
+		return nil, fmt.Errorf("sharding needs POD_NAMESPACE or an in-cluster service account: %w", err)
+	}
+	replicaID, err := replicaID()
+	if err != nil {
+		return nil, fmt.Errorf("sharding needs POD_NAME or a hostname: %w", err)
+	}
+	keyFunc, err := sharding.KeyFunc(cfg.Sharding.Key)
+	if err != nil {
+		return nil, err
+	}
+	return sharding.NewSharder(mgr.GetClient(), mgr.GetAPIReader(), sharding.Options{
+		Namespace:     namespace,
+		Group:         cfg.LeaderElection.ID,
+		ReplicaID:     replicaID,
+		KeyFunc:       keyFunc,
+		LeaseDuration: cfg.Sharding.LeaseDuration.Duration,
+		RenewInterval: cfg.Sharding.RenewInterval.Duration,
+	}, ctrl.Log.WithName("sharding"))
+}
+
+// newDispatcher returns the Dispatcher delivering messages for reconciler. The
+// queue is kept in a ConfigMap in the manager's namespace, or only in memory
+// when sharding or when the manager runs outside a cluster. Sharded replicas
+// have no stable name to key a ConfigMap by, and the HelloWorld that now owns a
+// pending delivery queues it again from its status.
+func newDispatcher(ctx context.Context, mgr ctrl.Manager, cfg config.ManagerConfig, reconciler *controller.HelloWorldReconciler) (*delivery.Dispatcher, error) {
+	opts := delivery.Options{
+		Build:          reconciler.BuildDelivery,
+		Timeout:        cfg.Delivery.Timeout.Duration,
+		InitialBackoff: cfg.Delivery.InitialBackoff.Duration,
+		MaxBackoff:     cfg.Delivery.MaxBackoff.Duration,
+		MaxAttempts:    cfg.Delivery.MaxAttempts,
+		AllowedHosts:   cfg.Delivery.AllowedHosts,
+	}
+	log := ctrl.Log.WithName("delivery")
+	namespace, err := managerNamespace()
+	if err != nil {
+		log.Info("Manager namespace is unknown, keeping the delivery queue in memory", "error", err.Error())
+		return delivery.NewDispatcher(opts, log), nil
+	}
+	name := cfg.LeaderElection.ID + "-deliveries"
+	if cfg.Sharding.Enabled {
+		// Earlier versions kept a queue per replica, named after its pod
+		log.Info("Sharding is enabled, keeping the delivery queue in memory")
+		if err := delivery.DeleteQueues(ctx, mgr.GetClient(), mgr.GetAPIReader(), namespace, name+"-"); err != nil {
+			log.Error(err, "Failed to delete the delivery queues of earlier replicas")
+		}
+		return delivery.NewDispatcher(opts, log), nil
+	}
+	opts.Store = &delivery.ConfigMapStore{
+		Client: mgr.GetClient(),
+		Reader: mgr.GetAPIReader(),
+		Key:    types.NamespacedName{Namespace: namespace, Name: name},
+	}
+	return delivery.NewDispatcher(opts, log), nil
+}
+
+// managerNamespace returns the namespace the manager runs in, from the downward
+// API or the service account
+func managerNamespace() (string, error) {
+	if namespace := os.Getenv("POD_NAMESPACE"); namespace != "" {
+		return namespace, nil
+	}
+	data, err := os.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// replicaID names this replica, by the downward API's pod name or the hostname
+func replicaID() (string, error) {
+	if name := os.Getenv("POD_NAME"); name != "" {
+		return name, nil
 	}
+	return os.Hostname()
 }
@@ -0,0 +1,192 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cloudevents publishes CloudEvents 1.0 to an HTTP sink in structured
+// mode. Events are queued in memory and sent in the background, so publishing
+// never blocks the caller; events that cannot be sent are dropped.
+package cloudevents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/example/op-hello-world/internal/metrics"
+)
+
+// SpecVersion is the CloudEvents version events are published with
+const SpecVersion = "1.0"
+
+// ContentType is the media type of an event in structured mode
+const ContentType = "application/cloudevents+json; charset=utf-8"
+
+// Event is a CloudEvent as encoded in structured mode
+type Event struct {
+	SpecVersion     string    `json:"specversion"`
+	ID              string    `json:"id"`
+	Source          string    `json:"source"`
+	Type            string    `json:"type"`
+	Subject         string    `json:"subject,omitempty"`
+	Time            time.Time `json:"time"`
+	DataContentType string    `json:"datacontenttype,omitempty"`
+	// TraceParent and TraceState are the distributed tracing extension
+	TraceParent string `json:"traceparent,omitempty"`
+	TraceState  string `json:"tracestate,omitempty"`
+	Data        any    `json:"data,omitempty"`
+}
+
+// Options configure an Emitter
+type Options struct {
+	// Sink is the URL events are POSTed to
+	Sink string
+	// Client sends the events. Defaults to a client with Timeout.
+	Client *http.Client
+	// Timeout bounds each attempt when Client is not set
+	Timeout time.Duration
+	// MaxAttempts is how many times an event is sent before it is dropped.
+	// Defaults to 3.
+	MaxAttempts int
+	// Backoff is the wait before the first retry, doubled for every further
+	// one. Defaults to 500ms.
+	Backoff time.Duration
+	// BufferSize is how many events wait to be sent before new ones are
+	// dropped. Defaults to 256.
+	BufferSize int
+}
+
+// Emitter sends events to a sink in the order they were emitted. It is a
+// manager Runnable.
+type Emitter struct {
+	opts   Options
+	log    logr.Logger
+	events chan Event
+}
+
+// NewEmitter returns an Emitter for opts
+func NewEmitter(opts Options, log logr.Logger) *Emitter {
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: opts.Timeout}
+	}
+	if opts.MaxAttempts < 1 {
+		opts.MaxAttempts = 3
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = 500 * time.Millisecond
+	}
+	if opts.BufferSize < 1 {
+		opts.BufferSize = 256
+	}
+	return &Emitter{opts: opts, log: log, events: make(chan Event, opts.BufferSize)}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. Events are
+// only emitted by running controllers, so the Emitter runs on every replica.
+func (e *Emitter) NeedLeaderElection() bool {
+	return false
+}
+
+// Emit queues event to be sent without waiting, dropping it when the queue is
+// full. SpecVersion and Time are filled in when unset.
+func (e *Emitter) Emit(event Event) {
+	if event.SpecVersion == "" {
+		event.SpecVersion = SpecVersion
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+	select {
+	case e.events <- event:
+	default:
+		e.log.Info("Dropping CloudEvent, the queue is full", "type", event.Type, "id", event.ID)
+		metrics.CloudEvents.WithLabelValues(event.Type, "dropped").Inc()
+	}
+}
+
+// Start sends queued events until ctx is done
+func (e *Emitter) Start(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event := <-e.events:
+			e.publish(ctx, event)
+		}
+	}
+}
+
+// publish sends event, retrying failures that may be temporary
+func (e *Emitter) publish(ctx context.Context, event Event) {
+	log := e.log.WithValues("type", event.Type, "id", event.ID, "subject", event.Subject)
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Error(err, "Failed to encode CloudEvent")
+		metrics.CloudEvents.WithLabelValues(event.Type, "failed").Inc()
+		return
+	}
+
+	backoff := e.opts.Backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := e.send(ctx, body)
+		if err == nil {
+			log.V(1).Info("Sent CloudEvent")
+			metrics.CloudEvents.WithLabelValues(event.Type, "sent").Inc()
+			return
+		}
+		if !retry || attempt >= e.opts.MaxAttempts {
+			log.Info("Failed to send CloudEvent", "error", err.Error(), "attempts", attempt)
+			metrics.CloudEvents.WithLabelValues(event.Type, "failed").Inc()
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// send POSTs an encoded event and reports whether a failure may succeed when
+// retried
+func (e *Emitter) send(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.opts.Sink, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", ContentType)
+
+	resp, err := e.opts.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return true, fmt.Errorf("sink responded %s", resp.Status)
+	default:
+		return false, fmt.Errorf("sink rejected the event with %s", resp.Status)
+	}
+}
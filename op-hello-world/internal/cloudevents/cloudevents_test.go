@@ -0,0 +1,162 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudevents
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// sink answers each event with the next of statuses, then 202, and passes the
+// content type and body of every request on
+func sink(t *testing.T, statuses ...int) (*httptest.Server, <-chan *http.Request, <-chan []byte) {
+	t.Helper()
+	requests := make(chan *http.Request, 10)
+	bodies := make(chan []byte, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- r
+		bodies <- body
+		status := http.StatusAccepted
+		if len(statuses) > 0 {
+			status, statuses = statuses[0], statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, requests, bodies
+}
+
+// start runs an Emitter publishing to server until the test ends
+func start(t *testing.T, server *httptest.Server) *Emitter {
+	t.Helper()
+	e := NewEmitter(Options{Sink: server.URL, Client: server.Client(), Backoff: time.Millisecond}, logr.Discard())
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = e.Start(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return e
+}
+
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a request")
+	}
+	panic("unreachable")
+}
+
+func TestPublishesStructuredEvent(t *testing.T) {
+	server, requests, bodies := sink(t)
+	e := start(t, server)
+
+	e.Emit(Event{
+		ID:          "1234-5-phase",
+		Source:      "/apis/apps.example.com/v1/namespaces/default/helloworlds",
+		Subject:     "greeting",
+		Type:        "com.example.apps.helloworld.phase.changed",
+		TraceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		Data:        map[string]string{"phase": "Running"},
+	})
+
+	req := receive(t, requests)
+	if got := req.Header.Get("Content-Type"); got != ContentType {
+		t.Errorf("expected content type %q, got %q", ContentType, got)
+	}
+	var event map[string]any
+	if err := json.Unmarshal(receive(t, bodies), &event); err != nil {
+		t.Fatal(err)
+	}
+	for attribute, want := range map[string]any{
+		"specversion": "1.0",
+		"id":          "1234-5-phase",
+		"subject":     "greeting",
+		"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"data":        map[string]any{"phase": "Running"},
+	} {
+		if got, _ := json.Marshal(event[attribute]); string(got) != mustMarshal(want) {
+			t.Errorf("expected %s %s, got %s", attribute, mustMarshal(want), got)
+		}
+	}
+	if _, ok := event["time"]; !ok {
+		t.Error("expected the time to be set")
+	}
+}
+
+func TestRetriesTemporaryFailures(t *testing.T) {
+	server, requests, _ := sink(t, http.StatusServiceUnavailable, http.StatusAccepted)
+	e := start(t, server)
+
+	e.Emit(Event{ID: "1", Type: "test"})
+	receive(t, requests)
+	receive(t, requests)
+}
+
+func TestDropsRejectedEvents(t *testing.T) {
+	server, requests, bodies := sink(t, http.StatusBadRequest)
+	e := start(t, server)
+
+	e.Emit(Event{ID: "1", Type: "test"})
+	e.Emit(Event{ID: "2", Type: "test"})
+	receive(t, requests)
+	receive(t, requests)
+	<-bodies
+	var event Event
+	if err := json.Unmarshal(receive(t, bodies), &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.ID != "2" {
+		t.Errorf("expected the rejected event not to be retried, got event %s", event.ID)
+	}
+}
+
+func TestEmitDoesNotBlock(t *testing.T) {
+	e := NewEmitter(Options{Sink: "http://127.0.0.1:1", BufferSize: 1}, logr.Discard())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 3 {
+			e.Emit(Event{ID: "1", Type: "test"})
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected Emit to drop events instead of blocking")
+	}
+}
+
+func mustMarshal(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
@@ -0,0 +1,450 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package config defines the versioned manager configuration file. Settings are
+// read from the file first, and command-line flags override them.
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// APIVersion is the only supported configuration file version
+	APIVersion = "config.apps.example.com/v1alpha1"
+	// Kind is the kind of the configuration file
+	Kind = "ManagerConfig"
+)
+
+// ManagerConfig is the configuration file for the manager
+type ManagerConfig struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Metrics configures the metrics endpoint
+	Metrics MetricsConfig `json:"metrics,omitempty"`
+	// Logging configures the manager log level
+	Logging LoggingConfig `json:"logging,omitempty"`
+	// Health configures the liveness and readiness probe endpoint
+	Health HealthConfig `json:"health,omitempty"`
+	// Pprof configures the pprof handlers on the metrics endpoint
+	Pprof PprofConfig `json:"pprof,omitempty"`
+	// LeaderElection configures leader election between replicas
+	LeaderElection LeaderElectionConfig `json:"leaderElection,omitempty"`
+	// Webhook configures the webhook server certificates
+	Webhook WebhookConfig `json:"webhook,omitempty"`
+	// Telemetry configures trace export and continuous profiling
+	Telemetry TelemetryConfig `json:"telemetry,omitempty"`
+	// Watch limits which HelloWorld resources this manager reconciles
+	Watch WatchConfig `json:"watch,omitempty"`
+	// Sharding spreads HelloWorld resources across active replicas
+	Sharding ShardingConfig `json:"sharding,omitempty"`
+	// Controller configures the HelloWorld controller
+	Controller ControllerConfig `json:"controller,omitempty"`
+	// Pod configures the pods generated for HelloWorld resources
+	Pod PodDefaults `json:"pod,omitempty"`
+	// Delivery configures the delivery of messages to webhooks
+	Delivery DeliveryConfig `json:"delivery,omitempty"`
+	// CloudEvents configures the CloudEvents published on status transitions
+	CloudEvents CloudEventsConfig `json:"cloudEvents,omitempty"`
+	// EnableHTTP2 enables HTTP/2 for the metrics and webhook servers
+	EnableHTTP2 bool `json:"enableHTTP2,omitempty"`
+}
+
+// MetricsConfig configures the metrics endpoint
+type MetricsConfig struct {
+	// BindAddress is the address the metrics endpoint binds to, or "0" to disable it
+	BindAddress string `json:"bindAddress,omitempty"`
+	// Secure serves metrics over HTTPS behind authn/authz
+	Secure bool `json:"secure,omitempty"`
+	// CertPath is the directory that contains the metrics server certificate
+	CertPath string `json:"certPath,omitempty"`
+	// CertName is the name of the metrics server certificate file
+	CertName string `json:"certName,omitempty"`
+	// CertKey is the name of the metrics server key file
+	CertKey string `json:"certKey,omitempty"`
+}
+
+// LoggingConfig configures the manager log level
+type LoggingConfig struct {
+	// Level is "debug", "info", "error", or a logr verbosity such as "2". Empty keeps
+	// the --zap-log-level setting. It can be changed without a restart.
+	Level string `json:"level,omitempty"`
+}
+
+// HealthConfig configures the probe endpoint
+type HealthConfig struct {
+	// ProbeBindAddress is the address the probe endpoint binds to
+	ProbeBindAddress string `json:"probeBindAddress,omitempty"`
+}
+
+// PprofConfig configures the pprof handlers
+type PprofConfig struct {
+	// Enabled serves pprof under /debug/pprof/ on the metrics endpoint
+	Enabled bool `json:"enabled,omitempty"`
+}
+
+// LeaderElectionConfig configures leader election
+type LeaderElectionConfig struct {
+	// Enabled ensures there is only one active controller manager
+	Enabled bool `json:"enabled,omitempty"`
+	// ID is the name of the leader election lease. Managers that split tenants
+	// between them must use different IDs.
+	ID string `json:"id,omitempty"`
+}
+
+// WebhookConfig configures the webhook server certificates
+type WebhookConfig struct {
+	// CertPath is the directory that contains the webhook certificate
+	CertPath string `json:"certPath,omitempty"`
+	// CertName is the name of the webhook certificate file
+	CertName string `json:"certName,omitempty"`
+	// CertKey is the name of the webhook key file
+	CertKey string `json:"certKey,omitempty"`
+}
+
+// TelemetryConfig configures trace export and continuous profiling
+type TelemetryConfig struct {
+	// OTLPEndpoint is the OTLP gRPC endpoint; empty uses OTEL_EXPORTER_OTLP_ENDPOINT
+	OTLPEndpoint string `json:"otlpEndpoint,omitempty"`
+	// FlushTimeout bounds how long buffered telemetry is flushed for on exit
+	FlushTimeout metav1.Duration `json:"flushTimeout,omitempty"`
+	// SamplingRatio is the fraction of new traces that are sampled, from 0 to 1.
+	// It can be changed without a restart.
+	SamplingRatio float64 `json:"samplingRatio,omitempty"`
+	// Profiling configures continuous profiling
+	Profiling ProfilingConfig `json:"profiling,omitempty"`
+}
+
+// ProfilingConfig configures continuous profiling with Pyroscope
+type ProfilingConfig struct {
+	// Enabled pushes profiles to Pyroscope
+	Enabled bool `json:"enabled,omitempty"`
+	// ServerAddress is the Pyroscope server URL
+	ServerAddress string `json:"serverAddress,omitempty"`
+	// UploadRate is how often profiles are pushed
+	UploadRate metav1.Duration `json:"uploadRate,omitempty"`
+	// MutexFraction reports on average 1/n mutex contention events
+	MutexFraction int `json:"mutexFraction,omitempty"`
+}
+
+// WatchConfig limits which HelloWorld resources this manager reconciles, so that
+// several managers can split tenants between them
+type WatchConfig struct {
+	// Namespaces restricts the cache, and so the RBAC the manager needs, to these
+	// namespaces. Empty watches all namespaces.
+	Namespaces []string `json:"namespaces,omitempty"`
+	// LabelSelector restricts the manager to HelloWorlds whose labels match it
+	LabelSelector string `json:"labelSelector,omitempty"`
+}
+
+// ShardingConfig configures sharded reconciliation. Replicas sharing a
+// leaderElection.id form a shard group, announce themselves with a Lease each,
+// and split HelloWorld resources between them by hash.
+type ShardingConfig struct {
+	// Enabled makes every replica active, each reconciling its own shard. Leader
+	// election must be disabled.
+	Enabled bool `json:"enabled,omitempty"`
+	// Key is what resources are hashed by: "namespace" keeps a namespace on one
+	// replica, "uid" spreads individual resources
+	Key string `json:"key,omitempty"`
+	// LeaseDuration is how long a replica keeps its shard after it stops renewing
+	LeaseDuration metav1.Duration `json:"leaseDuration,omitempty"`
+	// RenewInterval is how often replicas renew their Lease and check membership
+	RenewInterval metav1.Duration `json:"renewInterval,omitempty"`
+}
+
+// Shard keys
+const (
+	// ShardByNamespace hashes HelloWorld resources by namespace
+	ShardByNamespace = "namespace"
+	// ShardByUID hashes HelloWorld resources by UID
+	ShardByUID = "uid"
+)
+
+// ControllerConfig configures the HelloWorld controller
+type ControllerConfig struct {
+	// MaxConcurrentReconciles is the number of HelloWorlds reconciled in parallel
+	MaxConcurrentReconciles int `json:"maxConcurrentReconciles,omitempty"`
+	// GracefulShutdownTimeout is how long the manager waits for controllers to stop
+	GracefulShutdownTimeout metav1.Duration `json:"gracefulShutdownTimeout,omitempty"`
+	// RequeueInterval is how often a HelloWorld whose pod is not yet running is
+	// checked again. It can be changed without a restart.
+	RequeueInterval metav1.Duration `json:"requeueInterval,omitempty"`
+}
+
+// DeliveryConfig configures how messages are posted to the webhooks of
+// spec.deliveries
+type DeliveryConfig struct {
+	// Timeout bounds each request to a webhook
+	Timeout metav1.Duration `json:"timeout,omitempty"`
+	// InitialBackoff is the wait before the first retry. It doubles with every
+	// retry up to MaxBackoff.
+	InitialBackoff metav1.Duration `json:"initialBackoff,omitempty"`
+	// MaxBackoff caps the wait between retries
+	MaxBackoff metav1.Duration `json:"maxBackoff,omitempty"`
+	// MaxAttempts is the number of requests made before a delivery fails
+	MaxAttempts int32 `json:"maxAttempts,omitempty"`
+	// AllowedHosts restricts the hosts messages are delivered to: host names, IP
+	// addresses, or "*." and a domain for its subdomains. Empty allows any host.
+	// Loopback, link-local and unspecified addresses are always refused.
+	AllowedHosts []string `json:"allowedHosts,omitempty"`
+}
+
+// CloudEventsConfig configures the CloudEvents published when the phase or a
+// condition of a HelloWorld changes
+type CloudEventsConfig struct {
+	// Sink is the URL events are POSTed to. Empty publishes no events.
+	Sink string `json:"sink,omitempty"`
+	// Timeout bounds each request to the sink
+	Timeout metav1.Duration `json:"timeout,omitempty"`
+}
+
+// PodDefaults configures the pods generated for HelloWorld resources. They can be
+// changed without a restart and apply to pods created afterwards.
+type PodDefaults struct {
+	// Image is the container image that prints the message
+	Image string `json:"image,omitempty"`
+	// GreeterImage is the container image that serves the message of HelloWorlds
+	// in serve mode, built from cmd/greeter
+	GreeterImage string `json:"greeterImage,omitempty"`
+	// Resources are the container resource requests and limits
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// Default returns the configuration used when neither a file nor flags set a value
+func Default() ManagerConfig {
+	return ManagerConfig{
+		TypeMeta: metav1.TypeMeta{APIVersion: APIVersion, Kind: Kind},
+		Metrics: MetricsConfig{
+			BindAddress: "0",
+			Secure:      true,
+			CertName:    "tls.crt",
+			CertKey:     "tls.key",
+		},
+		Health:         HealthConfig{ProbeBindAddress: ":8081"},
+		LeaderElection: LeaderElectionConfig{ID: "0ae07d5a.example.com"},
+		Webhook: WebhookConfig{
+			CertName: "tls.crt",
+			CertKey:  "tls.key",
+		},
+		Telemetry: TelemetryConfig{
+			FlushTimeout:  metav1.Duration{Duration: 3 * time.Second},
+			SamplingRatio: 1,
+			Profiling: ProfilingConfig{
+				ServerAddress: "http://localhost:4040",
+				UploadRate:    metav1.Duration{Duration: 15 * time.Second},
+				MutexFraction: 5,
+			},
+		},
+		Sharding: ShardingConfig{
+			Key:           ShardByNamespace,
+			LeaseDuration: metav1.Duration{Duration: 15 * time.Second},
+			RenewInterval: metav1.Duration{Duration: 5 * time.Second},
+		},
+		Controller: ControllerConfig{
+			MaxConcurrentReconciles: 1,
+			GracefulShutdownTimeout: metav1.Duration{Duration: 5 * time.Second},
+			RequeueInterval:         metav1.Duration{Duration: 10 * time.Second},
+		},
+		Pod: DefaultPodDefaults(),
+		Delivery: DeliveryConfig{
+			Timeout:        metav1.Duration{Duration: 10 * time.Second},
+			InitialBackoff: metav1.Duration{Duration: time.Second},
+			MaxBackoff:     metav1.Duration{Duration: 5 * time.Minute},
+			MaxAttempts:    10,
+		},
+		CloudEvents: CloudEventsConfig{
+			Timeout: metav1.Duration{Duration: 5 * time.Second},
+		},
+	}
+}
+
+// DefaultPodDefaults returns the built-in settings for generated pods
+func DefaultPodDefaults() PodDefaults {
+	return PodDefaults{
+		Image:        "busybox:latest",
+		GreeterImage: "ghcr.io/j7m4/op-hello-world-greeter:latest",
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("50m"),
+				corev1.ResourceMemory: resource.MustParse("64Mi"),
+			},
+			Limits: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("100m"),
+				corev1.ResourceMemory: resource.MustParse("128Mi"),
+			},
+		},
+	}
+}
+
+// Load reads the configuration file at path over cfg. Only settings present in
+// the file are changed, and unknown fields are rejected.
+func Load(path string, cfg *ManagerConfig) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	var meta metav1.TypeMeta
+	if err := yaml.Unmarshal(data, &meta); err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	if meta.APIVersion != APIVersion || meta.Kind != Kind {
+		return fmt.Errorf("config file %s: unsupported apiVersion %q and kind %q, expected %s %s",
+			path, meta.APIVersion, meta.Kind, APIVersion, Kind)
+	}
+
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// ApplyFlags copies the flags explicitly set on src onto cfg, so that a config
+// file loaded after the command line was parsed is still overridden by it
+func ApplyFlags(src *flag.FlagSet, cfg *ManagerConfig) error {
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	BindFlags(fs, cfg)
+
+	var err error
+	src.Visit(func(f *flag.Flag) {
+		if fs.Lookup(f.Name) == nil || err != nil {
+			return
+		}
+		err = fs.Set(f.Name, f.Value.String())
+	})
+	return err
+}
+
+// BindFlags registers command-line flags for cfg. Flag defaults are the current
+// values of cfg, so call it with Default().
+func BindFlags(fs *flag.FlagSet, cfg *ManagerConfig) {
+	fs.StringVar(&cfg.Metrics.BindAddress, "metrics-bind-address", cfg.Metrics.BindAddress,
+		"The address the metrics endpoint binds to. "+
+			"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
+	fs.StringVar(&cfg.Health.ProbeBindAddress, "health-probe-bind-address", cfg.Health.ProbeBindAddress,
+		"The address the probe endpoint binds to.")
+	fs.BoolVar(&cfg.Pprof.Enabled, "enable-pprof", cfg.Pprof.Enabled,
+		"If set, pprof handlers are served under /debug/pprof/ on the metrics endpoint, "+
+			"protected by the same authn/authz as /metrics. Requires --metrics-secure.")
+	fs.BoolVar(&cfg.Telemetry.Profiling.Enabled, "enable-profiling", cfg.Telemetry.Profiling.Enabled,
+		"If set, CPU, heap, goroutine and mutex profiles are continuously pushed to Pyroscope.")
+	fs.StringVar(&cfg.Telemetry.Profiling.ServerAddress, "profiling-server-address", cfg.Telemetry.Profiling.ServerAddress,
+		"The Pyroscope server URL that profiles are pushed to.")
+	fs.DurationVar(&cfg.Telemetry.Profiling.UploadRate.Duration, "profiling-upload-rate", cfg.Telemetry.Profiling.UploadRate.Duration,
+		"How often profiles are pushed to Pyroscope.")
+	fs.IntVar(&cfg.Telemetry.Profiling.MutexFraction, "profiling-mutex-fraction", cfg.Telemetry.Profiling.MutexFraction,
+		"On average 1/n mutex contention events are reported in the mutex profile.")
+	fs.StringVar(&cfg.Telemetry.OTLPEndpoint, "otlp-endpoint", cfg.Telemetry.OTLPEndpoint,
+		"The OTLP gRPC endpoint traces are exported to. Defaults to OTEL_EXPORTER_OTLP_ENDPOINT or localhost:4317.")
+	fs.Float64Var(&cfg.Telemetry.SamplingRatio, "trace-sampling-ratio", cfg.Telemetry.SamplingRatio,
+		"The fraction of new traces that are sampled, from 0 to 1.")
+	fs.BoolVar(&cfg.LeaderElection.Enabled, "leader-elect", cfg.LeaderElection.Enabled,
+		"Enable leader election for controller manager. "+
+			"Enabling this will ensure there is only one active controller manager.")
+	fs.StringVar(&cfg.LeaderElection.ID, "leader-election-id", cfg.LeaderElection.ID,
+		"The name of the leader election lease. Managers that split tenants between them must use different IDs.")
+	fs.Var((*stringList)(&cfg.Watch.Namespaces), "watch-namespaces",
+		"Comma-separated namespaces to watch. Leave empty to watch all namespaces.")
+	fs.StringVar(&cfg.Watch.LabelSelector, "watch-label-selector", cfg.Watch.LabelSelector,
+		"Only reconcile HelloWorld resources whose labels match this selector, e.g. tenant=a.")
+	fs.BoolVar(&cfg.Sharding.Enabled, "enable-sharding", cfg.Sharding.Enabled,
+		"If set, every replica is active and reconciles its own shard of HelloWorld resources. "+
+			"Requires --leader-elect=false.")
+	fs.StringVar(&cfg.Sharding.Key, "shard-key", cfg.Sharding.Key,
+		`What HelloWorld resources are sharded by: "namespace" or "uid".`)
+	fs.BoolVar(&cfg.Metrics.Secure, "metrics-secure", cfg.Metrics.Secure,
+		"If set, the metrics endpoint is served securely via HTTPS. Use --metrics-secure=false to use HTTP instead.")
+	fs.StringVar(&cfg.Webhook.CertPath, "webhook-cert-path", cfg.Webhook.CertPath,
+		"The directory that contains the webhook certificate.")
+	fs.StringVar(&cfg.Webhook.CertName, "webhook-cert-name", cfg.Webhook.CertName,
+		"The name of the webhook certificate file.")
+	fs.StringVar(&cfg.Webhook.CertKey, "webhook-cert-key", cfg.Webhook.CertKey,
+		"The name of the webhook key file.")
+	fs.StringVar(&cfg.Metrics.CertPath, "metrics-cert-path", cfg.Metrics.CertPath,
+		"The directory that contains the metrics server certificate.")
+	fs.StringVar(&cfg.Metrics.CertName, "metrics-cert-name", cfg.Metrics.CertName,
+		"The name of the metrics server certificate file.")
+	fs.StringVar(&cfg.Metrics.CertKey, "metrics-cert-key", cfg.Metrics.CertKey,
+		"The name of the metrics server key file.")
+	fs.BoolVar(&cfg.EnableHTTP2, "enable-http2", cfg.EnableHTTP2,
+		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	fs.IntVar(&cfg.Controller.MaxConcurrentReconciles, "max-concurrent-reconciles", cfg.Controller.MaxConcurrentReconciles,
+		"The number of HelloWorld resources reconciled in parallel.")
+	fs.DurationVar(&cfg.Controller.GracefulShutdownTimeout.Duration, "graceful-shutdown-timeout",
+		cfg.Controller.GracefulShutdownTimeout.Duration,
+		"How long the manager waits for controllers and servers to stop on SIGTERM.")
+	fs.DurationVar(&cfg.Controller.RequeueInterval.Duration, "requeue-interval", cfg.Controller.RequeueInterval.Duration,
+		"How often a HelloWorld whose pod is not yet running is checked again.")
+	fs.DurationVar(&cfg.Telemetry.FlushTimeout.Duration, "telemetry-flush-timeout", cfg.Telemetry.FlushTimeout.Duration,
+		"How long to wait for buffered telemetry to be exported once the manager has stopped.")
+	fs.StringVar(&cfg.Pod.Image, "pod-image", cfg.Pod.Image,
+		"The container image used for pods generated from HelloWorld resources.")
+	fs.StringVar(&cfg.Pod.GreeterImage, "greeter-image", cfg.Pod.GreeterImage,
+		"The container image that serves the message of HelloWorld resources in serve mode.")
+	fs.DurationVar(&cfg.Delivery.Timeout.Duration, "delivery-timeout", cfg.Delivery.Timeout.Duration,
+		"How long a request delivering a message to a webhook may take.")
+	fs.Var((*int32Value)(&cfg.Delivery.MaxAttempts), "delivery-max-attempts",
+		"The number of requests made to deliver a message to a webhook before giving up.")
+	fs.Var((*stringList)(&cfg.Delivery.AllowedHosts), "delivery-allowed-hosts",
+		"Comma-separated hosts messages may be delivered to, e.g. hooks.example.com,*.svc.cluster.local. "+
+			"Leave empty to allow any host.")
+	fs.StringVar(&cfg.CloudEvents.Sink, "cloudevents-sink", cfg.CloudEvents.Sink,
+		"The URL CloudEvents are sent to when a HelloWorld's phase or conditions change. Leave empty to send none.")
+}
+
+// int32Value is an int32 flag value
+type int32Value int32
+
+func (v *int32Value) String() string {
+	return strconv.FormatInt(int64(*v), 10)
+}
+
+func (v *int32Value) Set(value string) error {
+	n, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return err
+	}
+	*v = int32Value(n)
+	return nil
+}
+
+// stringList is a comma-separated flag value
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = nil
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
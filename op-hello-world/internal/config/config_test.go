@@ -0,0 +1,193 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDefaultIsValid(t *testing.T) {
+	cfg := Default()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("default config is invalid: %v", err)
+	}
+}
+
+func TestLoadOverlaysDefaults(t *testing.T) {
+	path := writeConfig(t, `
+apiVersion: config.apps.example.com/v1alpha1
+kind: ManagerConfig
+metrics:
+  bindAddress: ":8443"
+controller:
+  maxConcurrentReconciles: 4
+pod:
+  image: busybox:1.36
+`)
+	cfg := Default()
+	if err := Load(path, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Metrics.BindAddress != ":8443" || cfg.Controller.MaxConcurrentReconciles != 4 {
+		t.Errorf("file settings not applied: %+v", cfg)
+	}
+	if cfg.Health.ProbeBindAddress != ":8081" || !cfg.Metrics.Secure {
+		t.Errorf("settings missing from the file should keep their defaults: %+v", cfg)
+	}
+	if cfg.Pod.Image != "busybox:1.36" {
+		t.Errorf("expected pod image busybox:1.36, got %q", cfg.Pod.Image)
+	}
+}
+
+func TestLoadRejectsBadFiles(t *testing.T) {
+	tests := map[string]struct {
+		content string
+		want    string
+	}{
+		"wrong version": {
+			content: "apiVersion: config.apps.example.com/v2\nkind: ManagerConfig\n",
+			want:    "unsupported apiVersion",
+		},
+		"unknown field": {
+			content: "apiVersion: config.apps.example.com/v1alpha1\nkind: ManagerConfig\nmetrics:\n  bindAdress: \":8080\"\n",
+			want:    "bindAdress",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := Default()
+			err := Load(writeConfig(t, tt.content), &cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestFlagsOverrideFile(t *testing.T) {
+	path := writeConfig(t, `
+apiVersion: config.apps.example.com/v1alpha1
+kind: ManagerConfig
+metrics:
+  bindAddress: ":8443"
+telemetry:
+  flushTimeout: 10s
+`)
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flagConfig := Default()
+	BindFlags(fs, &flagConfig)
+	if err := fs.Parse([]string{"--metrics-bind-address=:9090"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := Default()
+	if err := Load(path, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := ApplyFlags(fs, &cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Metrics.BindAddress != ":9090" {
+		t.Errorf("expected flag to win, got %q", cfg.Metrics.BindAddress)
+	}
+	if cfg.Telemetry.FlushTimeout.Duration != 10*time.Second {
+		t.Errorf("expected file flush timeout 10s, got %s", cfg.Telemetry.FlushTimeout.Duration)
+	}
+}
+
+func TestWatchNamespacesFlag(t *testing.T) {
+	cfg := Default()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	BindFlags(fs, &cfg)
+	if err := fs.Parse([]string{"--watch-namespaces=tenant-a, tenant-b"}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(cfg.Watch.Namespaces, "|") != "tenant-a|tenant-b" {
+		t.Errorf("expected namespaces tenant-a and tenant-b, got %q", cfg.Watch.Namespaces)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg := Default()
+	cfg.Metrics.BindAddress = "8080"
+	cfg.Controller.MaxConcurrentReconciles = 0
+	cfg.Telemetry.Profiling.Enabled = true
+	cfg.Telemetry.Profiling.ServerAddress = "pyroscope:4040"
+	cfg.Pod.Image = ""
+	cfg.Pod.GreeterImage = ""
+	cfg.Pod.Resources.Requests["memory"] = resource.MustParse("1Gi")
+	cfg.Watch.Namespaces = []string{"tenant-a", "Tenant_B", "tenant-a"}
+	cfg.Watch.LabelSelector = "tenant in (a"
+	cfg.Sharding.Enabled = true
+	cfg.Sharding.Key = "name"
+	cfg.LeaderElection.Enabled = true
+	cfg.Delivery.MaxBackoff.Duration = 0
+	cfg.Delivery.MaxAttempts = 0
+	cfg.CloudEvents.Sink = "broker:8080"
+	cfg.Pprof.Enabled = true
+	cfg.Metrics.Secure = false
+	cfg.Delivery.AllowedHosts = []string{"hooks.example.com", "*.svc.cluster.local", "10.0.0.7", "https://hooks.example.com"}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected validation to fail")
+	}
+	for _, path := range []string{
+		"metrics.bindAddress",
+		"controller.maxConcurrentReconciles",
+		"telemetry.profiling.serverAddress",
+		"pod.image",
+		"pod.greeterImage",
+		"pod.resources.requests[memory]",
+		"watch.namespaces[1]",
+		"watch.namespaces[2]: Duplicate value",
+		"watch.labelSelector",
+		"sharding.key",
+		"leaderElection.enabled",
+		"delivery.maxBackoff",
+		"delivery.maxAttempts",
+		"cloudEvents.sink",
+		"pprof.enabled",
+		"delivery.allowedHosts[3]",
+	} {
+		if !strings.Contains(err.Error(), path) {
+			t.Errorf("expected an error for %s, got %v", path, err)
+		}
+	}
+	for _, path := range []string{"delivery.allowedHosts[0]", "delivery.allowedHosts[1]", "delivery.allowedHosts[2]"} {
+		if strings.Contains(err.Error(), path) {
+			t.Errorf("expected no error for %s, got %v", path, err)
+		}
+	}
+}
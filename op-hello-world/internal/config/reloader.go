@@ -0,0 +1,226 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/example/op-hello-world/internal/metrics"
+	"github.com/example/op-hello-world/internal/tracing"
+)
+
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+
+// Reload results recorded in config_reload_total and on the reload span
+const (
+	// ReloadApplied means the new settings are in effect
+	ReloadApplied = "applied"
+	// ReloadUnchanged means the file changed but no effective setting did
+	ReloadUnchanged = "unchanged"
+	// ReloadRejected means settings that need a restart were changed and ignored;
+	// any settings that are safe to change were still applied
+	ReloadRejected = "rejected"
+	// ReloadInvalid means the file could not be loaded or failed validation, and
+	// the running configuration was kept
+	ReloadInvalid = "invalid"
+)
+
+// defaultPollInterval is how often the file is re-read in case a watch event was missed
+const defaultPollInterval = 30 * time.Second
+
+// ReloaderOptions configures a Reloader
+type ReloaderOptions struct {
+	// Path is the config file to watch
+	Path string
+	// Load reads the config file with command-line overrides applied, and validates it
+	Load func() (ManagerConfig, error)
+	// Apply is called with the new configuration after it has been stored, to push
+	// settings such as the log level and sampling ratio to their components
+	Apply func(*ManagerConfig)
+	// Recorder and EventObject are used to report rejected and failed reloads.
+	// Events are skipped when either is nil.
+	Recorder    record.EventRecorder
+	EventObject runtime.Object
+	// PollInterval is how often the file is re-read in case a watch event was missed
+	PollInterval time.Duration
+}
+
+// Reloader watches the config file and applies the settings that are safe to
+// change while the manager runs: the log level, trace sampling ratio, requeue
+// interval and pod defaults. Other changes are rejected with a warning event.
+type Reloader struct {
+	store   *Store
+	options ReloaderOptions
+	log     logr.Logger
+
+	lastContent []byte
+}
+
+var _ manager.LeaderElectionRunnable = &Reloader{}
+
+// NewReloader returns a Reloader to be added to the manager
+func NewReloader(store *Store, options ReloaderOptions, log logr.Logger) (*Reloader, error) {
+	if options.Path == "" {
+		return nil, fmt.Errorf("config file path must be set")
+	}
+	if options.Load == nil {
+		return nil, fmt.Errorf("config load function must be set")
+	}
+	if options.PollInterval <= 0 {
+		options.PollInterval = defaultPollInterval
+	}
+	content, err := os.ReadFile(options.Path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+	return &Reloader{store: store, options: options, log: log, lastContent: content}, nil
+}
+
+// Start watches the config file until the context is cancelled
+func (r *Reloader) Start(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("creating config file watcher: %w", err)
+	}
+	defer func() { _ = watcher.Close() }()
+
+	// Watch the directory rather than the file: a mounted ConfigMap is updated by
+	// swapping a symlink, which replaces the file instead of writing to it
+	if err := watcher.Add(filepath.Dir(r.options.Path)); err != nil {
+		return fmt.Errorf("watching config file: %w", err)
+	}
+	r.log.Info("Watching config file for changes", "path", r.options.Path)
+
+	ticker := time.NewTicker(r.options.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-watcher.Events:
+			r.reloadIfChanged(ctx)
+		case err := <-watcher.Errors:
+			r.log.Error(err, "Config file watch error")
+		case <-ticker.C:
+			r.reloadIfChanged(ctx)
+		}
+	}
+}
+
+// NeedLeaderElection returns false so every replica applies the new settings
+func (r *Reloader) NeedLeaderElection() bool {
+	return false
+}
+
+// reloadIfChanged reloads when the file content differs from the last reload
+func (r *Reloader) reloadIfChanged(ctx context.Context) {
+	content, err := os.ReadFile(r.options.Path)
+	if err != nil {
+		// The file is briefly missing while a ConfigMap update swaps the symlink
+		r.log.V(1).Info("Config file not readable, will retry", "reason", err.Error())
+		return
+	}
+	if bytes.Equal(content, r.lastContent) {
+		return
+	}
+	r.lastContent = content
+	r.Reload(ctx)
+}
+
+// Reload loads the config file and applies its safe settings. It returns the
+// result recorded in config_reload_total.
+func (r *Reloader) Reload(ctx context.Context) string {
+	_, span := tracing.GetTracer("config-reloader").Start(ctx, "ConfigReload")
+	defer span.End()
+	span.SetAttributes(attribute.String("config.path", r.options.Path))
+
+	result, rejected, err := r.reload()
+	metrics.ConfigReloads.WithLabelValues(result).Inc()
+	span.SetAttributes(attribute.String("config.reload.result", result))
+
+	switch result {
+	case ReloadInvalid:
+		r.log.Error(err, "Config reload failed, keeping the running configuration", "path", r.options.Path)
+		tracing.RecordError(span, err, "Failed to load config file")
+		span.SetStatus(codes.Error, "Config reload failed")
+		r.event(corev1.EventTypeWarning, "ConfigReloadFailed",
+			fmt.Sprintf("Config reload failed, keeping the running configuration: %v", err))
+	case ReloadRejected:
+		span.SetAttributes(attribute.StringSlice("config.reload.rejected", rejected))
+		span.SetStatus(codes.Error, "Config changes need a restart")
+		r.log.Info("Config changes need a restart and were not applied", "settings", rejected)
+		r.event(corev1.EventTypeWarning, "ConfigReloadRejected",
+			fmt.Sprintf("Settings that need a restart were not applied: %s", strings.Join(rejected, ", ")))
+	case ReloadApplied:
+		span.SetStatus(codes.Ok, "Config reloaded")
+		r.log.Info("Config reloaded", "path", r.options.Path)
+		r.event(corev1.EventTypeNormal, "ConfigReloaded", "Config reloaded")
+	default:
+		r.log.V(1).Info("Config file changed but no settings did", "path", r.options.Path)
+	}
+	return result
+}
+
+func (r *Reloader) reload() (string, []string, error) {
+	next, err := r.options.Load()
+	if err != nil {
+		return ReloadInvalid, nil, err
+	}
+
+	running := *r.store.Get()
+	merged, rejected := Merge(running, next)
+	changed := !equality.Semantic.DeepEqual(running, merged)
+	if changed {
+		r.store.Set(merged)
+		if r.options.Apply != nil {
+			r.options.Apply(r.store.Get())
+		}
+	}
+
+	switch {
+	case len(rejected) > 0:
+		return ReloadRejected, rejected, nil
+	case changed:
+		return ReloadApplied, nil, nil
+	default:
+		return ReloadUnchanged, nil, nil
+	}
+}
+
+func (r *Reloader) event(eventType, reason, message string) {
+	if r.options.Recorder == nil || r.options.EventObject == nil {
+		return
+	}
+	r.options.Recorder.Event(r.options.EventObject, eventType, reason, message)
+}
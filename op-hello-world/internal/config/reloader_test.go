@@ -0,0 +1,316 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/example/op-hello-world/internal/telemetrytest"
+)
+
+const header = "apiVersion: config.apps.example.com/v1alpha1\nkind: ManagerConfig\n"
+
+func TestMerge(t *testing.T) {
+	running := Default()
+	next := Default()
+	next.Logging.Level = "debug"
+	next.Telemetry.SamplingRatio = 0.25
+	next.Pod.Image = "busybox:1.36"
+	next.Metrics.BindAddress = ":9999"
+	next.Controller.MaxConcurrentReconciles = 8
+	next.Delivery.MaxAttempts = 3
+	next.CloudEvents.Sink = "http://broker.default.svc"
+
+	merged, rejected := Merge(running, next)
+	if merged.Logging.Level != "debug" || merged.Telemetry.SamplingRatio != 0.25 || merged.Pod.Image != "busybox:1.36" {
+		t.Errorf("safe settings were not applied: %+v", merged)
+	}
+	if merged.Metrics.BindAddress != running.Metrics.BindAddress ||
+		merged.Controller.MaxConcurrentReconciles != running.Controller.MaxConcurrentReconciles ||
+		merged.Delivery.MaxAttempts != running.Delivery.MaxAttempts ||
+		merged.CloudEvents.Sink != running.CloudEvents.Sink {
+		t.Errorf("restart-only settings were applied: %+v", merged)
+	}
+	want := []string{"metrics", "controller.maxConcurrentReconciles", "delivery", "cloudEvents"}
+	if strings.Join(rejected, ",") != strings.Join(want, ",") {
+		t.Errorf("expected rejected %v, got %v", want, rejected)
+	}
+}
+
+// TestMergeCoversEveryField changes each setting of ManagerConfig on its own and
+// checks that Merge either applies it or reports it as needing a restart, so a
+// new field cannot be silently dropped on reload
+func TestMergeCoversEveryField(t *testing.T) {
+	pkg := reflect.TypeOf(ManagerConfig{}).PkgPath()
+	var walk func(path string, index []int, typ reflect.Type)
+	walk = func(path string, index []int, typ reflect.Type) {
+		for i := range typ.NumField() {
+			f := typ.Field(i)
+			if f.Anonymous {
+				continue
+			}
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if path != "" {
+				name = path + "." + name
+			}
+			fieldIndex := append(slices.Clone(index), i)
+			// Settings grouped in a struct of this package may be split between
+			// applied and restart-only ones, so each is checked on its own
+			if f.Type.Kind() == reflect.Struct && f.Type.PkgPath() == pkg {
+				walk(name, fieldIndex, f.Type)
+				continue
+			}
+			t.Run(name, func(t *testing.T) {
+				running, next := Default(), Default()
+				changed := reflect.ValueOf(&next).Elem().FieldByIndex(fieldIndex)
+				mutate(t, changed)
+
+				merged, unsafe := Merge(running, next)
+				applied := reflect.DeepEqual(reflect.ValueOf(merged).FieldByIndex(fieldIndex).Interface(), changed.Interface())
+				if !applied && len(unsafe) == 0 {
+					t.Errorf("Merge neither applies %s nor reports it as needing a restart", name)
+				}
+			})
+		}
+	}
+	walk("", nil, reflect.TypeOf(ManagerConfig{}))
+}
+
+// mutate changes v to a value different from the one it holds
+func mutate(t *testing.T, v reflect.Value) {
+	t.Helper()
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(v.String() + "-changed")
+	case reflect.Bool:
+		v.SetBool(!v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(v.Int() + 1)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(v.Float() + 0.5)
+	case reflect.Slice:
+		elem := reflect.New(v.Type().Elem()).Elem()
+		mutate(t, elem)
+		v.Set(reflect.Append(v, elem))
+	case reflect.Map:
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		key := reflect.New(v.Type().Key()).Elem()
+		mutate(t, key)
+		v.SetMapIndex(key, reflect.New(v.Type().Elem()).Elem())
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		mutate(t, v.Elem())
+	case reflect.Struct:
+		for i := range v.NumField() {
+			if v.Type().Field(i).IsExported() {
+				mutate(t, v.Field(i))
+			}
+		}
+	default:
+		t.Fatalf("cannot change a %s", v.Type())
+	}
+}
+
+// newTestReloader returns a Reloader for a config file holding only the header
+func newTestReloader(t *testing.T) (*Reloader, *Store, *record.FakeRecorder, string) {
+	t.Helper()
+	path := writeConfig(t, header)
+	load := func() (ManagerConfig, error) {
+		cfg := Default()
+		if err := Load(path, &cfg); err != nil {
+			return cfg, err
+		}
+		return cfg, cfg.Validate()
+	}
+	initial, err := load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewStore(initial)
+	recorder := record.NewFakeRecorder(10)
+	reloader, err := NewReloader(store, ReloaderOptions{
+		Path:         path,
+		Load:         load,
+		Recorder:     recorder,
+		EventObject:  &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "manager", Namespace: "system"}},
+		PollInterval: 20 * time.Millisecond,
+	}, logr.Discard())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reloader, store, recorder, path
+}
+
+func TestReload(t *testing.T) {
+	tests := map[string]struct {
+		content    string
+		wantResult string
+		wantEvent  string
+		wantImage  string
+	}{
+		"safe change": {
+			content:    "pod:\n  image: busybox:1.36\n",
+			wantResult: ReloadApplied,
+			wantEvent:  "Normal ConfigReloaded",
+			wantImage:  "busybox:1.36",
+		},
+		"restart-only change": {
+			content:    "pod:\n  image: busybox:1.36\nleaderElection:\n  enabled: true\n",
+			wantResult: ReloadRejected,
+			wantEvent:  "Warning ConfigReloadRejected Settings that need a restart were not applied: leaderElection",
+			wantImage:  "busybox:1.36",
+		},
+		"delivery change": {
+			content:    "pod:\n  image: busybox:1.36\ndelivery:\n  allowedHosts: [hooks.example.com]\n",
+			wantResult: ReloadRejected,
+			wantEvent:  "Warning ConfigReloadRejected Settings that need a restart were not applied: delivery",
+			wantImage:  "busybox:1.36",
+		},
+		"cloudEvents change": {
+			content:    "pod:\n  image: busybox:1.36\ncloudEvents:\n  sink: http://broker.default.svc\n  timeout: 1s\n",
+			wantResult: ReloadRejected,
+			wantEvent:  "Warning ConfigReloadRejected Settings that need a restart were not applied: cloudEvents",
+			wantImage:  "busybox:1.36",
+		},
+		"invalid file": {
+			content:    "controller:\n  maxConcurrentReconciles: 0\n",
+			wantResult: ReloadInvalid,
+			wantEvent:  "Warning ConfigReloadFailed",
+			wantImage:  "busybox:latest",
+		},
+		"no effective change": {
+			content:    "# comment only\n",
+			wantResult: ReloadUnchanged,
+			wantImage:  "busybox:latest",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			recorder := telemetrytest.Install()
+			defer recorder.Uninstall()
+
+			reloader, store, events, path := newTestReloader(t)
+			if err := os.WriteFile(path, []byte(header+tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			if result := reloader.Reload(context.Background()); result != tt.wantResult {
+				t.Errorf("expected result %q, got %q", tt.wantResult, result)
+			}
+			if image := store.Get().Pod.Image; image != tt.wantImage {
+				t.Errorf("expected pod image %q, got %q", tt.wantImage, image)
+			}
+			if got := recorder.Metric("config_reload_total", map[string]string{"result": tt.wantResult}); got != 1 {
+				t.Errorf("expected config_reload_total{result=%q} 1, got %v", tt.wantResult, got)
+			}
+
+			spans := recorder.SpansNamed("ConfigReload")
+			if len(spans) != 1 {
+				t.Fatalf("expected 1 ConfigReload span, got %d", len(spans))
+			}
+			if result, _ := telemetrytest.Attribute(spans[0], "config.reload.result"); result.AsString() != tt.wantResult {
+				t.Errorf("expected span result %q, got %q", tt.wantResult, result.AsString())
+			}
+
+			select {
+			case event := <-events.Events:
+				if tt.wantEvent == "" || !strings.HasPrefix(event, tt.wantEvent) {
+					t.Errorf("expected event %q, got %q", tt.wantEvent, event)
+				}
+			default:
+				if tt.wantEvent != "" {
+					t.Errorf("expected event %q, got none", tt.wantEvent)
+				}
+			}
+		})
+	}
+}
+
+func TestReloaderWatchesSymlinkSwap(t *testing.T) {
+	recorder := telemetrytest.Install()
+	defer recorder.Uninstall()
+
+	// Lay the file out the way the kubelet mounts a ConfigMap: config.yaml links
+	// to ..data/config.yaml, and ..data is a symlink swapped on every update
+	dir := t.TempDir()
+	writeVersion := func(version, content string) {
+		versionDir := filepath.Join(dir, version)
+		if err := os.Mkdir(versionDir, 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(versionDir, "config.yaml"), []byte(header+content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		tmp := filepath.Join(dir, "..data_tmp")
+		if err := os.Symlink(version, tmp); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(tmp, filepath.Join(dir, "..data")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeVersion("v1", "")
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.Symlink(filepath.Join("..data", "config.yaml"), path); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewStore(Default())
+	reloader, err := NewReloader(store, ReloaderOptions{
+		Path: path,
+		Load: func() (ManagerConfig, error) {
+			cfg := Default()
+			return cfg, Load(path, &cfg)
+		},
+	}, logr.Discard())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = reloader.Start(ctx) }()
+
+	// Give the watcher a moment to start before the update
+	time.Sleep(50 * time.Millisecond)
+	writeVersion("v2", "controller:\n  requeueInterval: 1m\n")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for store.Get().Controller.RequeueInterval.Duration != time.Minute {
+		if time.Now().After(deadline) {
+			t.Fatal("requeue interval was not reloaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
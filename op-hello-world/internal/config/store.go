@@ -0,0 +1,86 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"sync/atomic"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+// Store holds the configuration the manager is running with. Reloads replace it
+// atomically, so readers always see a complete configuration.
+type Store struct {
+	current atomic.Pointer[ManagerConfig]
+}
+
+// NewStore returns a Store holding cfg
+func NewStore(cfg ManagerConfig) *Store {
+	s := &Store{}
+	s.current.Store(&cfg)
+	return s
+}
+
+// Get returns the current configuration. Callers must not modify it.
+func (s *Store) Get() *ManagerConfig {
+	return s.current.Load()
+}
+
+// Set replaces the current configuration
+func (s *Store) Set(cfg ManagerConfig) {
+	s.current.Store(&cfg)
+}
+
+// Merge returns running with the settings that are safe to change at runtime
+// taken from next, and the paths of the settings that differ but need a restart
+func Merge(running, next ManagerConfig) (ManagerConfig, []string) {
+	merged := running
+	merged.Logging = next.Logging
+	merged.Telemetry.SamplingRatio = next.Telemetry.SamplingRatio
+	merged.Controller.RequeueInterval = next.Controller.RequeueInterval
+	merged.Pod = next.Pod
+
+	// Every setting not copied above is compared here
+	var unsafe []string
+	for _, s := range []struct {
+		path      string
+		old, next any
+	}{
+		{"metrics", running.Metrics, next.Metrics},
+		{"health", running.Health, next.Health},
+		{"pprof", running.Pprof, next.Pprof},
+		{"leaderElection", running.LeaderElection, next.LeaderElection},
+		{"webhook", running.Webhook, next.Webhook},
+		{"watch", running.Watch, next.Watch},
+		{"sharding", running.Sharding, next.Sharding},
+		{"telemetry.otlpEndpoint", running.Telemetry.OTLPEndpoint, next.Telemetry.OTLPEndpoint},
+		{"telemetry.flushTimeout", running.Telemetry.FlushTimeout, next.Telemetry.FlushTimeout},
+		{"telemetry.profiling", running.Telemetry.Profiling, next.Telemetry.Profiling},
+		{"controller.maxConcurrentReconciles", running.Controller.MaxConcurrentReconciles,
+			next.Controller.MaxConcurrentReconciles},
+		{"controller.gracefulShutdownTimeout", running.Controller.GracefulShutdownTimeout,
+			next.Controller.GracefulShutdownTimeout},
+		{"delivery", running.Delivery, next.Delivery},
+		{"cloudEvents", running.CloudEvents, next.CloudEvents},
+		{"enableHTTP2", running.EnableHTTP2, next.EnableHTTP2},
+	} {
+		if !equality.Semantic.DeepEqual(s.old, s.next) {
+			unsafe = append(unsafe, s.path)
+		}
+	}
+	return merged, unsafe
+}
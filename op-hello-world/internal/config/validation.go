@@ -0,0 +1,215 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Validate reports every invalid setting, keyed by its path in the config file
+func (c *ManagerConfig) Validate() error {
+	var errs field.ErrorList
+
+	errs = append(errs, validateBindAddress(c.Metrics.BindAddress, field.NewPath("metrics", "bindAddress"))...)
+	errs = append(errs, validateBindAddress(c.Health.ProbeBindAddress, field.NewPath("health", "probeBindAddress"))...)
+	// pprof exposes heap and goroutine dumps and the command line, so it is only
+	// served behind the authn/authz of a secure metrics endpoint
+	if c.Pprof.Enabled && !c.Metrics.Secure {
+		errs = append(errs, field.Invalid(field.NewPath("pprof", "enabled"), c.Pprof.Enabled,
+			"requires metrics.secure, since pprof is served unauthenticated otherwise"))
+	}
+
+	if c.Logging.Level != "" {
+		if _, err := ParseLogLevel(c.Logging.Level); err != nil {
+			errs = append(errs, field.Invalid(field.NewPath("logging", "level"), c.Logging.Level, err.Error()))
+		}
+	}
+
+	telemetry := field.NewPath("telemetry")
+	errs = append(errs, validatePositive(c.Telemetry.FlushTimeout, telemetry.Child("flushTimeout"))...)
+	if c.Telemetry.SamplingRatio < 0 || c.Telemetry.SamplingRatio > 1 {
+		errs = append(errs, field.Invalid(telemetry.Child("samplingRatio"), c.Telemetry.SamplingRatio,
+			"must be between 0 and 1"))
+	}
+	if c.Telemetry.OTLPEndpoint != "" {
+		if _, _, err := net.SplitHostPort(c.Telemetry.OTLPEndpoint); err != nil {
+			errs = append(errs, field.Invalid(telemetry.Child("otlpEndpoint"), c.Telemetry.OTLPEndpoint,
+				"must be host:port"))
+		}
+	}
+
+	profiling := telemetry.Child("profiling")
+	if c.Telemetry.Profiling.Enabled {
+		if u, err := url.Parse(c.Telemetry.Profiling.ServerAddress); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, field.Invalid(profiling.Child("serverAddress"), c.Telemetry.Profiling.ServerAddress,
+				"must be an absolute URL, e.g. http://pyroscope:4040"))
+		}
+		errs = append(errs, validatePositive(c.Telemetry.Profiling.UploadRate, profiling.Child("uploadRate"))...)
+	}
+	if c.Telemetry.Profiling.MutexFraction < 0 {
+		errs = append(errs, field.Invalid(profiling.Child("mutexFraction"), c.Telemetry.Profiling.MutexFraction,
+			"must not be negative"))
+	}
+
+	if c.LeaderElection.ID == "" {
+		errs = append(errs, field.Required(field.NewPath("leaderElection", "id"), "the lease name must be set"))
+	} else if msgs := validation.IsDNS1123Subdomain(c.LeaderElection.ID); len(msgs) > 0 {
+		errs = append(errs, field.Invalid(field.NewPath("leaderElection", "id"), c.LeaderElection.ID,
+			strings.Join(msgs, "; ")))
+	}
+
+	watch := field.NewPath("watch")
+	seen := map[string]bool{}
+	for i, namespace := range c.Watch.Namespaces {
+		if msgs := validation.IsDNS1123Label(namespace); len(msgs) > 0 {
+			errs = append(errs, field.Invalid(watch.Child("namespaces").Index(i), namespace, strings.Join(msgs, "; ")))
+		} else if seen[namespace] {
+			errs = append(errs, field.Duplicate(watch.Child("namespaces").Index(i), namespace))
+		}
+		seen[namespace] = true
+	}
+	if _, err := labels.Parse(c.Watch.LabelSelector); err != nil {
+		errs = append(errs, field.Invalid(watch.Child("labelSelector"), c.Watch.LabelSelector, err.Error()))
+	}
+
+	sharding := field.NewPath("sharding")
+	if c.Sharding.Key != ShardByNamespace && c.Sharding.Key != ShardByUID {
+		errs = append(errs, field.NotSupported(sharding.Child("key"), c.Sharding.Key,
+			[]string{ShardByNamespace, ShardByUID}))
+	}
+	if c.Sharding.Enabled {
+		if c.LeaderElection.Enabled {
+			errs = append(errs, field.Invalid(field.NewPath("leaderElection", "enabled"), true,
+				"must be false when sharding is enabled, since every replica is active"))
+		}
+		errs = append(errs, validatePositive(c.Sharding.RenewInterval, sharding.Child("renewInterval"))...)
+		if c.Sharding.LeaseDuration.Duration <= c.Sharding.RenewInterval.Duration {
+			errs = append(errs, field.Invalid(sharding.Child("leaseDuration"), c.Sharding.LeaseDuration.Duration.String(),
+				"must be longer than the renew interval"))
+		}
+	}
+
+	controller := field.NewPath("controller")
+	if c.Controller.MaxConcurrentReconciles < 1 {
+		errs = append(errs, field.Invalid(controller.Child("maxConcurrentReconciles"),
+			c.Controller.MaxConcurrentReconciles, "must be at least 1"))
+	}
+	errs = append(errs, validatePositive(c.Controller.GracefulShutdownTimeout,
+		controller.Child("gracefulShutdownTimeout"))...)
+	errs = append(errs, validatePositive(c.Controller.RequeueInterval, controller.Child("requeueInterval"))...)
+
+	errs = append(errs, validatePodDefaults(c.Pod, field.NewPath("pod"))...)
+
+	delivery := field.NewPath("delivery")
+	errs = append(errs, validatePositive(c.Delivery.Timeout, delivery.Child("timeout"))...)
+	errs = append(errs, validatePositive(c.Delivery.InitialBackoff, delivery.Child("initialBackoff"))...)
+	if c.Delivery.MaxBackoff.Duration < c.Delivery.InitialBackoff.Duration {
+		errs = append(errs, field.Invalid(delivery.Child("maxBackoff"), c.Delivery.MaxBackoff.Duration.String(),
+			"must not be shorter than the initial backoff"))
+	}
+	if c.Delivery.MaxAttempts < 1 {
+		errs = append(errs, field.Invalid(delivery.Child("maxAttempts"), c.Delivery.MaxAttempts, "must be at least 1"))
+	}
+	for i, host := range c.Delivery.AllowedHosts {
+		if net.ParseIP(host) != nil {
+			continue
+		}
+		if msgs := validation.IsDNS1123Subdomain(strings.TrimPrefix(strings.ToLower(host), "*.")); len(msgs) > 0 {
+			errs = append(errs, field.Invalid(delivery.Child("allowedHosts").Index(i), host,
+				"must be a host name, an IP address, or *. and a domain"))
+		}
+	}
+
+	cloudEvents := field.NewPath("cloudEvents")
+	if c.CloudEvents.Sink != "" {
+		if u, err := url.Parse(c.CloudEvents.Sink); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, field.Invalid(cloudEvents.Child("sink"), c.CloudEvents.Sink,
+				"must be an http or https URL, e.g. http://broker-ingress.knative-eventing.svc/default/default"))
+		}
+		errs = append(errs, validatePositive(c.CloudEvents.Timeout, cloudEvents.Child("timeout"))...)
+	}
+
+	return errs.ToAggregate()
+}
+
+// validateBindAddress accepts host:port, or "0" to disable the server
+func validateBindAddress(address string, path *field.Path) field.ErrorList {
+	if address == "0" {
+		return nil
+	}
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return field.ErrorList{field.Invalid(path, address, "must be host:port, e.g. :8080, or 0 to disable")}
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		return field.ErrorList{field.Invalid(path, address, "port must be between 0 and 65535")}
+	}
+	return nil
+}
+
+func validatePositive(d metav1.Duration, path *field.Path) field.ErrorList {
+	if d.Duration <= 0 {
+		return field.ErrorList{field.Invalid(path, d.Duration.String(), "must be greater than zero")}
+	}
+	return nil
+}
+
+func validatePodDefaults(pod PodDefaults, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if pod.Image == "" {
+		errs = append(errs, field.Required(path.Child("image"), "the container image must be set"))
+	}
+	if pod.GreeterImage == "" {
+		errs = append(errs, field.Required(path.Child("greeterImage"), "the greeter image must be set"))
+	}
+	for name, request := range pod.Resources.Requests {
+		limit, ok := pod.Resources.Limits[name]
+		if ok && request.Cmp(limit) > 0 {
+			errs = append(errs, field.Invalid(path.Child("resources", "requests").Key(string(name)),
+				request.String(), "must be less than or equal to the limit "+limit.String()))
+		}
+	}
+	return errs
+}
+
+// ParseLogLevel parses a level the way --zap-log-level does: "debug", "info",
+// "error", or a positive logr verbosity
+func ParseLogLevel(level string) (zapcore.Level, error) {
+	switch level {
+	case "debug":
+		return zapcore.DebugLevel, nil
+	case "info":
+		return zapcore.InfoLevel, nil
+	case "error":
+		return zapcore.ErrorLevel, nil
+	}
+	v, err := strconv.Atoi(level)
+	if err != nil || v <= 0 {
+		return 0, errors.New(`must be "debug", "info", "error" or a positive verbosity`)
+	}
+	return zapcore.Level(-v), nil
+}
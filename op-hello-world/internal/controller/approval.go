@@ -0,0 +1,105 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	appsv1 "github.com/example/op-hello-world/api/v1"
+)
+
+// awaitingApproval reports whether the message hashed as messageHash waits for
+// approval under approvalPolicy Required. An approval annotation naming the
+// current generation approves it, which is recorded in the status with the
+// approver the webhook stamped, if it serves. Once a generation is approved, a
+// message that changes without a new generation, e.g. because a referenced
+// ConfigMap changed, needs an approval naming its hash as well.
+func (r *HelloWorldReconciler) awaitingApproval(ctx context.Context, helloworld *appsv1.HelloWorld, messageHash string) bool {
+	if helloworld.Spec.ApprovalPolicy != appsv1.ApprovalRequired {
+		return false
+	}
+	approval := helloworld.Status.Approval
+	if approval != nil && approval.MessageHash == messageHash {
+		return false
+	}
+	if helloworld.Annotations[appsv1.ApproveGenerationAnnotation] != approvalValue(helloworld, messageHash) {
+		return true
+	}
+
+	// Anyone who can update the HelloWorld can set the annotation when the
+	// webhook does not guard it
+	var approver string
+	if r.ApprovalWebhook {
+		approver = helloworld.Annotations[appsv1.ApprovedByAnnotation]
+	}
+	helloworld.Status.Approval = &appsv1.ApprovalStatus{
+		Generation:  helloworld.Generation,
+		MessageHash: messageHash,
+		Approver:    approver,
+		ApprovedAt:  metav1.Now(),
+	}
+	logf.FromContext(ctx).Info("Message approved", "generation", helloworld.Generation, "approver", approver)
+	message := fmt.Sprintf("Generation %d approved", helloworld.Generation)
+	switch {
+	case !r.ApprovalWebhook:
+		message += "; the approver is not recorded without the HelloWorld webhook"
+	case approver != "":
+		message += " by " + approver
+	}
+	r.event(helloworld, corev1.EventTypeNormal, "Approved", message)
+	return false
+}
+
+// approvalValue is the approval annotation that approves the message hashed as
+// messageHash: the current generation, followed by the hash when an approval of
+// the generation was already used for another message
+func approvalValue(helloworld *appsv1.HelloWorld, messageHash string) string {
+	value := strconv.FormatInt(helloworld.Generation, 10)
+	if approval := helloworld.Status.Approval; approval != nil && approval.Generation == helloworld.Generation {
+		value += "/" + messageHash
+	}
+	return value
+}
+
+// approvalMessage explains how to approve the message hashed as messageHash
+func approvalMessage(helloworld *appsv1.HelloWorld, messageHash string) string {
+	return fmt.Sprintf("Generation %d waits for approval; annotate the HelloWorld with %s=%s to roll it out",
+		helloworld.Generation, appsv1.ApproveGenerationAnnotation, approvalValue(helloworld, messageHash))
+}
+
+// holdForApproval reports the message of helloworld hashed as messageHash as
+// waiting for approval while podName, if any, or the replicas keep running the
+// approved message. The annotation update that approves it triggers the next
+// reconcile.
+func (r *HelloWorldReconciler) holdForApproval(ctx context.Context, helloworld *appsv1.HelloWorld, messageHash, podName string) error {
+	message := approvalMessage(helloworld, messageHash)
+	if helloworld.Status.Phase != appsv1.PhaseAwaitingApproval || helloworld.Status.ObservedGeneration != helloworld.Generation {
+		logf.FromContext(ctx).Info("Message awaits approval", "generation", helloworld.Generation)
+		r.event(helloworld, corev1.EventTypeNormal, "AwaitingApproval", message)
+	}
+	r.setCondition(helloworld, appsv1.TypeProgressing, metav1.ConditionFalse, "AwaitingApproval", message)
+	if podName == "" && helloworld.Status.ReadyReplicas == 0 {
+		r.setCondition(helloworld, appsv1.TypeReady, metav1.ConditionFalse, "AwaitingApproval", "No pod runs until the message is approved")
+	}
+	return r.updateStatus(ctx, helloworld, appsv1.PhaseAwaitingApproval, podName, message)
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appsv1 "github.com/example/op-hello-world/api/v1"
+	"github.com/example/op-hello-world/internal/config"
+)
+
+// CacheOptions restricts the manager's cache to the watched namespaces, and
+// HelloWorlds to those matching the watch label selector. Every object the
+// controller reads goes through the cache, so the manager only needs RBAC in
+// the watched namespaces.
+func CacheOptions(watch config.WatchConfig) (cache.Options, error) {
+	options := cache.Options{}
+
+	if len(watch.Namespaces) > 0 {
+		options.DefaultNamespaces = make(map[string]cache.Config, len(watch.Namespaces))
+		for _, namespace := range watch.Namespaces {
+			options.DefaultNamespaces[namespace] = cache.Config{}
+		}
+	}
+
+	if watch.LabelSelector != "" {
+		selector, err := labels.Parse(watch.LabelSelector)
+		if err != nil {
+			return options, fmt.Errorf("parsing watch label selector: %w", err)
+		}
+		options.ByObject = map[client.Object]cache.ByObject{
+			&appsv1.HelloWorld{}: {Label: selector},
+		}
+	}
+	return options, nil
+}
+
+// watchesNamespace reports whether objects in namespace can be read through the cache
+func watchesNamespace(watch config.WatchConfig, namespace string) bool {
+	if len(watch.Namespaces) == 0 {
+		return true
+	}
+	for _, n := range watch.Namespaces {
+		if n == namespace {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/labels"
+
+	appsv1 "github.com/example/op-hello-world/api/v1"
+	"github.com/example/op-hello-world/internal/config"
+)
+
+var _ = Describe("CacheOptions", func() {
+	It("watches all namespaces and HelloWorlds by default", func() {
+		options, err := CacheOptions(config.WatchConfig{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(options.DefaultNamespaces).To(BeEmpty())
+		Expect(options.ByObject).To(BeEmpty())
+		Expect(watchesNamespace(config.WatchConfig{}, "default")).To(BeTrue())
+	})
+
+	It("restricts the cache to the watched namespaces and label selector", func() {
+		watch := config.WatchConfig{Namespaces: []string{"tenant-a", "tenant-b"}, LabelSelector: "tenant=a"}
+		options, err := CacheOptions(watch)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(options.DefaultNamespaces).To(HaveLen(2))
+		Expect(options.DefaultNamespaces).To(HaveKey("tenant-a"))
+		Expect(options.DefaultNamespaces).To(HaveKey("tenant-b"))
+
+		Expect(options.ByObject).To(HaveLen(1))
+		for obj, byObject := range options.ByObject {
+			Expect(obj).To(BeAssignableToTypeOf(&appsv1.HelloWorld{}))
+			Expect(byObject.Label.Matches(labels.Set{"tenant": "a"})).To(BeTrue())
+			Expect(byObject.Label.Matches(labels.Set{"tenant": "b"})).To(BeFalse())
+		}
+
+		Expect(watchesNamespace(watch, "tenant-b")).To(BeTrue())
+		Expect(watchesNamespace(watch, "default")).To(BeFalse())
+	})
+
+	It("rejects an invalid label selector", func() {
+		_, err := CacheOptions(config.WatchConfig{LabelSelector: "tenant in (a"})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,111 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	appsv1 "github.com/example/op-hello-world/api/v1"
+	"github.com/example/op-hello-world/internal/cloudevents"
+	"github.com/example/op-hello-world/internal/tracing"
+)
+
+// Types of the CloudEvents published on status transitions
+const (
+	// PhaseChangedEventType is published when status.phase changes
+	PhaseChangedEventType = "com.example.apps.helloworld.phase.changed"
+	// ConditionChangedEventType is published when a condition is added or its
+	// status changes
+	ConditionChangedEventType = "com.example.apps.helloworld.condition.changed"
+)
+
+// phaseChangedData is the data of a PhaseChangedEventType event
+type phaseChangedData struct {
+	Phase              string `json:"phase"`
+	PreviousPhase      string `json:"previousPhase,omitempty"`
+	PodName            string `json:"podName,omitempty"`
+	Message            string `json:"message,omitempty"`
+	ObservedGeneration int64  `json:"observedGeneration"`
+}
+
+// conditionChangedData is the data of a ConditionChangedEventType event
+type conditionChangedData struct {
+	Type               string                 `json:"type"`
+	Status             metav1.ConditionStatus `json:"status"`
+	PreviousStatus     metav1.ConditionStatus `json:"previousStatus,omitempty"`
+	Reason             string                 `json:"reason"`
+	Message            string                 `json:"message,omitempty"`
+	ObservedGeneration int64                  `json:"observedGeneration"`
+}
+
+// emitTransitions publishes a CloudEvent for the phase and every condition of
+// helloworld whose status differs from previous, the status it was read with.
+// Nothing is published when the status was not written, as it then still
+// differs on the next reconcile.
+func (r *HelloWorldReconciler) emitTransitions(ctx context.Context, helloworld *appsv1.HelloWorld, previous *appsv1.HelloWorldStatus, resourceVersion string) {
+	if r.CloudEvents == nil || helloworld.ResourceVersion == resourceVersion {
+		return
+	}
+
+	traceparent, tracestate := tracing.TraceContext(ctx)
+	event := func(id, eventType string, data any) cloudevents.Event {
+		return cloudevents.Event{
+			// The resource version makes IDs unique per transition and stable
+			// across retries of the same one
+			ID:              fmt.Sprintf("%s-%s-%s", helloworld.UID, helloworld.ResourceVersion, id),
+			Source:          fmt.Sprintf("/apis/%s/namespaces/%s/helloworlds", appsv1.GroupVersion, helloworld.Namespace),
+			Subject:         helloworld.Name,
+			Type:            eventType,
+			DataContentType: "application/json",
+			TraceParent:     traceparent,
+			TraceState:      tracestate,
+			Data:            data,
+		}
+	}
+
+	status := helloworld.Status
+	if status.Phase != previous.Phase {
+		r.CloudEvents.Emit(event("phase", PhaseChangedEventType, phaseChangedData{
+			Phase:              status.Phase,
+			PreviousPhase:      previous.Phase,
+			PodName:            status.PodName,
+			Message:            status.Message,
+			ObservedGeneration: status.ObservedGeneration,
+		}))
+	}
+	for _, condition := range status.Conditions {
+		var previousStatus metav1.ConditionStatus
+		if old := meta.FindStatusCondition(previous.Conditions, condition.Type); old != nil {
+			if old.Status == condition.Status {
+				continue
+			}
+			previousStatus = old.Status
+		}
+		r.CloudEvents.Emit(event(condition.Type, ConditionChangedEventType, conditionChangedData{
+			Type:               condition.Type,
+			Status:             condition.Status,
+			PreviousStatus:     previousStatus,
+			Reason:             condition.Reason,
+			Message:            condition.Message,
+			ObservedGeneration: condition.ObservedGeneration,
+		}))
+	}
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	appsv1 "github.com/example/op-hello-world/api/v1"
+)
+
+// The CRD's CEL rules are enforced by the API server, so these run against envtest
+var _ = Describe("HelloWorld CRD validation", func() {
+	newHelloWorld := func(name string, spec appsv1.HelloWorldSpec) *appsv1.HelloWorld {
+		return &appsv1.HelloWorld{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       spec,
+		}
+	}
+
+	DescribeTable("creating a HelloWorld",
+		func(name string, spec appsv1.HelloWorldSpec, wantErr string) {
+			helloworld := newHelloWorld(name, spec)
+			err := k8sClient.Create(ctx, helloworld)
+			if wantErr == "" {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(k8sClient.Delete(ctx, helloworld)).To(Succeed())
+				return
+			}
+			Expect(errors.IsInvalid(err)).To(BeTrue(), "expected an invalid error, got %v", err)
+			Expect(err.Error()).To(ContainSubstring(wantErr))
+		},
+		Entry("accepts a plain message", "valid", appsv1.HelloWorldSpec{Message: "Hello, world!"}, ""),
+		Entry("accepts non-ASCII text", "unicode", appsv1.HelloWorldSpec{Message: "Grüß Gott 👋"}, ""),
+		Entry("rejects a blank message", "blank", appsv1.HelloWorldSpec{Message: "  "},
+			"message must not be blank"),
+		Entry("rejects a message over 256 characters", "long", appsv1.HelloWorldSpec{Message: strings.Repeat("a", 257)},
+			"Too long"),
+		Entry("rejects single quotes", "quote", appsv1.HelloWorldSpec{Message: "It's me"},
+			"must not contain single quotes or control characters"),
+		Entry("rejects control characters", "newline", appsv1.HelloWorldSpec{Message: "Hello\nworld"},
+			"must not contain single quotes or control characters"),
+		Entry("accepts a message read from a ConfigMap", "configmap", appsv1.HelloWorldSpec{MessageFrom: &appsv1.MessageSource{
+			ConfigMapKeyRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "greetings"}, Key: "message"},
+		}}, ""),
+		Entry("accepts a message read from a Secret", "secret", appsv1.HelloWorldSpec{MessageFrom: &appsv1.MessageSource{
+			SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "greetings"}, Key: "message"},
+		}}, ""),
+		Entry("accepts a message read from a pod field", "field", appsv1.HelloWorldSpec{MessageFrom: &appsv1.MessageSource{
+			FieldRef: &corev1.ObjectFieldSelector{FieldPath: "spec.nodeName"},
+		}}, ""),
+		Entry("rejects a HelloWorld without a message", "missing", appsv1.HelloWorldSpec{},
+			"exactly one of message, messageFrom, messageTemplate or messages must be set"),
+		Entry("rejects both message and messageFrom", "both", appsv1.HelloWorldSpec{Message: "Hello", MessageFrom: &appsv1.MessageSource{
+			FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"},
+		}}, "exactly one of message, messageFrom, messageTemplate or messages must be set"),
+		Entry("rejects more than one message source", "sources", appsv1.HelloWorldSpec{MessageFrom: &appsv1.MessageSource{
+			ConfigMapKeyRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "greetings"}, Key: "message"},
+			SecretKeyRef:    &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "greetings"}, Key: "message"},
+		}}, "exactly one of configMapKeyRef, secretKeyRef or fieldRef must be set"),
+		Entry("rejects a messageFrom without a source", "no-source", appsv1.HelloWorldSpec{MessageFrom: &appsv1.MessageSource{}},
+			"exactly one of configMapKeyRef, secretKeyRef or fieldRef must be set"),
+		Entry("accepts a multi-line message template", "template", appsv1.HelloWorldSpec{MessageTemplate: "Hello from {{.Namespace}}\n\ton {{.NodeName}}"}, ""),
+		Entry("rejects control characters in a message template", "template-control", appsv1.HelloWorldSpec{MessageTemplate: "Hello\x1b[31m"},
+			"messageTemplate must not contain control characters"),
+		Entry("accepts localized messages", "localized", appsv1.HelloWorldSpec{
+			Messages: map[string]string{"en": "Hello", "de": "Hallo"}, DefaultLocale: "en", Locale: "de-CH",
+		}, ""),
+		Entry("rejects a default locale without a message", "default", appsv1.HelloWorldSpec{
+			Messages: map[string]string{"en": "Hello"}, DefaultLocale: "fr",
+		}, "defaultLocale must be a key of messages"),
+		Entry("rejects messages without a default locale", "no-default", appsv1.HelloWorldSpec{
+			Messages: map[string]string{"en": "Hello"},
+		}, "defaultLocale must be set together with messages"),
+		Entry("rejects a default locale without messages", "default-only", appsv1.HelloWorldSpec{Message: "Hello", DefaultLocale: "en"},
+			"defaultLocale must be set together with messages"),
+		Entry("rejects a locale over 35 characters", "long-locale", appsv1.HelloWorldSpec{
+			Messages: map[string]string{"en": "Hello", strings.Repeat("a", 36): "Hi"}, DefaultLocale: "en",
+		}, "locales must be at most 35 and messages at most 256 characters"),
+		Entry("rejects a localized message over 256 characters", "long-localized", appsv1.HelloWorldSpec{
+			Messages: map[string]string{"en": strings.Repeat("a", 257)}, DefaultLocale: "en",
+		}, "locales must be at most 35 and messages at most 256 characters"),
+		Entry("rejects a locale without messages", "locale", appsv1.HelloWorldSpec{Message: "Hello", Locale: "de"},
+			"locale requires messages"),
+		Entry("rejects names too long to label the pod", strings.Repeat("a", 64), appsv1.HelloWorldSpec{Message: "Hello"},
+			"name must be at most 63 characters"),
+		Entry("rejects serving under a name that cannot name a Service", "1-served", appsv1.HelloWorldSpec{Message: "Hello", Serve: &appsv1.ServeSpec{Port: 80}},
+			"name must start with a letter in serve mode"),
+		Entry("accepts serving a message", "served", appsv1.HelloWorldSpec{Message: "Hello", Serve: &appsv1.ServeSpec{Port: 8080}}, ""),
+		Entry("rejects an out of range serve port", "port", appsv1.HelloWorldSpec{Message: "Hello", Serve: &appsv1.ServeSpec{Port: 70000}},
+			"spec.serve.port"),
+		Entry("rejects a negative serve port", "negative-port", appsv1.HelloWorldSpec{Message: "Hello", Serve: &appsv1.ServeSpec{Port: -1}},
+			"spec.serve.port"),
+		Entry("accepts a route of a served message", "routed", appsv1.HelloWorldSpec{Message: "Hello", Serve: &appsv1.ServeSpec{Port: 80},
+			Route: &appsv1.RouteSpec{Hostnames: []string{"*.example.com"}, PathPrefixes: []string{"/hello"}}}, ""),
+		Entry("rejects a route without serve", "route", appsv1.HelloWorldSpec{Message: "Hello", Route: &appsv1.RouteSpec{Hostnames: []string{"hello.example.com"}}},
+			"route requires serve"),
+		Entry("rejects an invalid route hostname", "hostname", appsv1.HelloWorldSpec{Message: "Hello", Serve: &appsv1.ServeSpec{Port: 80},
+			Route: &appsv1.RouteSpec{Hostnames: []string{"Hello_World"}}}, "spec.route.hostnames[0]"),
+		Entry("rejects a route path prefix not starting with a slash", "prefix", appsv1.HelloWorldSpec{Message: "Hello", Serve: &appsv1.ServeSpec{Port: 80},
+			Route: &appsv1.RouteSpec{Hostnames: []string{"hello.example.com"}, PathPrefixes: []string{"hello"}}}, "spec.route.pathPrefixes[0]"),
+		Entry("accepts a webhook delivery", "delivery", appsv1.HelloWorldSpec{Message: "Hello",
+			Deliveries: []appsv1.Delivery{{Name: "audit", URL: "https://hooks.example.com/hello"}}}, ""),
+		Entry("rejects a delivery to a URL other than http or https", "ftp", appsv1.HelloWorldSpec{Message: "Hello",
+			Deliveries: []appsv1.Delivery{{Name: "audit", URL: "ftp://hooks.example.com/hello"}}}, "url must be an http or https URL"),
+		Entry("rejects a negative revision history limit", "history", appsv1.HelloWorldSpec{Message: "Hello",
+			RevisionHistoryLimit: ptr.To[int32](-1)}, "should be greater than or equal to 0"),
+		Entry("rejects an unknown approval policy", "approval", appsv1.HelloWorldSpec{Message: "Hello",
+			ApprovalPolicy: "Sometimes"}, "Unsupported value"),
+		Entry("accepts a canary rollout", "canary", appsv1.HelloWorldSpec{Message: "Hello", Replicas: ptr.To[int32](4),
+			Rollout: &appsv1.RolloutStrategy{Steps: []appsv1.RolloutStep{{SetWeight: ptr.To[int32](25)}, {Pause: &appsv1.RolloutPause{}}}}}, ""),
+		Entry("rejects a rollout step with both setWeight and pause", "step", appsv1.HelloWorldSpec{Message: "Hello", Replicas: ptr.To[int32](4),
+			Rollout: &appsv1.RolloutStrategy{Steps: []appsv1.RolloutStep{{SetWeight: ptr.To[int32](25), Pause: &appsv1.RolloutPause{}}}}},
+			"exactly one of setWeight or pause must be set"),
+		Entry("rejects a weight over 100", "weight", appsv1.HelloWorldSpec{Message: "Hello", Replicas: ptr.To[int32](4),
+			Rollout: &appsv1.RolloutStrategy{Steps: []appsv1.RolloutStep{{SetWeight: ptr.To[int32](150)}}}}, "spec.rollout.steps[0].setWeight"),
+		Entry("rejects a rollout of a single replica", "single", appsv1.HelloWorldSpec{Message: "Hello",
+			Rollout: &appsv1.RolloutStrategy{Steps: []appsv1.RolloutStep{{SetWeight: ptr.To[int32](50)}}}}, "rollout requires more than one replica"),
+	)
+
+	It("keeps podNameStrategy immutable", func() {
+		helloworld := newHelloWorld("immutable", appsv1.HelloWorldSpec{Message: "Hello"})
+		Expect(k8sClient.Create(ctx, helloworld)).To(Succeed())
+		DeferCleanup(func() { Expect(k8sClient.Delete(ctx, helloworld)).To(Succeed()) })
+		Expect(helloworld.Spec.PodNameStrategy).To(Equal(appsv1.PodNameFixed))
+
+		helloworld.Spec.Message = "Hello again"
+		Expect(k8sClient.Update(ctx, helloworld)).To(Succeed())
+
+		helloworld.Spec.PodNameStrategy = appsv1.PodNameGenerated
+		err := k8sClient.Update(ctx, helloworld)
+		Expect(errors.IsInvalid(err)).To(BeTrue(), "expected an invalid error, got %v", err)
+		Expect(err.Error()).To(ContainSubstring("podNameStrategy is immutable"))
+	})
+})
@@ -0,0 +1,236 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"golang.org/x/net/http/httpguts"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	appsv1 "github.com/example/op-hello-world/api/v1"
+	"github.com/example/op-hello-world/internal/delivery"
+)
+
+// deliveryPayload is the JSON body posted to the webhooks of spec.deliveries
+type deliveryPayload struct {
+	APIVersion string    `json:"apiVersion"`
+	Kind       string    `json:"kind"`
+	Namespace  string    `json:"namespace"`
+	Name       string    `json:"name"`
+	UID        types.UID `json:"uid"`
+	Generation int64     `json:"generation"`
+	// Message is left out for a message read from a Secret or a field of the pod
+	Message  string             `json:"message,omitempty"`
+	Messages []localizedMessage `json:"messages,omitempty"`
+}
+
+// reconcileDeliveries queues the current generation of helloworld for every
+// webhook of spec.deliveries it has not been delivered to yet, and reports the
+// state of each delivery in the status
+func (r *HelloWorldReconciler) reconcileDeliveries(ctx context.Context, helloworld *appsv1.HelloWorld) {
+	if r.Deliveries == nil {
+		return
+	}
+
+	var statuses []appsv1.DeliveryStatus
+	for _, target := range helloworld.Spec.Deliveries {
+		i := slices.IndexFunc(helloworld.Status.Deliveries, func(s appsv1.DeliveryStatus) bool { return s.Name == target.Name })
+		// Finished deliveries are not queued again, e.g. after a restart
+		if i >= 0 {
+			status := helloworld.Status.Deliveries[i]
+			if status.ObservedGeneration == helloworld.Generation && status.State != appsv1.DeliveryPending {
+				statuses = append(statuses, status)
+				continue
+			}
+		}
+		outcome := r.Deliveries.Enqueue(ctx, delivery.Entry{
+			Namespace:  helloworld.Namespace,
+			Name:       helloworld.Name,
+			UID:        helloworld.UID,
+			Generation: helloworld.Generation,
+			Target:     target.Name,
+		})
+		statuses = append(statuses, deliveryStatus(target.Name, outcome))
+	}
+	helloworld.Status.Deliveries = statuses
+}
+
+// deliveryStatus returns the status of the delivery to target
+func deliveryStatus(target string, outcome delivery.Outcome) appsv1.DeliveryStatus {
+	status := appsv1.DeliveryStatus{
+		Name:               target,
+		ObservedGeneration: outcome.Generation,
+		State:              appsv1.DeliveryState(outcome.State),
+		Attempts:           outcome.Attempts,
+		LastStatusCode:     int32(outcome.StatusCode),
+		Message:            outcome.Error,
+	}
+	if !outcome.LastAttempt.IsZero() {
+		status.LastAttemptTime = &metav1.Time{Time: outcome.LastAttempt.Truncate(time.Second)}
+	}
+	if outcome.State == delivery.StatePending && !outcome.NextAttempt.IsZero() {
+		status.NextAttemptTime = &metav1.Time{Time: outcome.NextAttempt.Truncate(time.Second)}
+	}
+	return status
+}
+
+// BuildDelivery builds the request delivering entry from the HelloWorld as it
+// is now. Entries for another generation, a removed webhook or a HelloWorld
+// another shard owns are superseded.
+func (r *HelloWorldReconciler) BuildDelivery(ctx context.Context, entry delivery.Entry) (*delivery.Request, error) {
+	helloworld := &appsv1.HelloWorld{}
+	if err := r.Get(ctx, entry.Key(), helloworld); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, delivery.ErrSuperseded
+		}
+		return nil, err
+	}
+	if helloworld.UID != entry.UID || helloworld.Generation != entry.Generation ||
+		(r.Shard != nil && !r.Shard.OwnsObject(helloworld)) {
+		return nil, delivery.ErrSuperseded
+	}
+	i := slices.IndexFunc(helloworld.Spec.Deliveries, func(d appsv1.Delivery) bool { return d.Name == entry.Target })
+	if i < 0 {
+		return nil, delivery.ErrSuperseded
+	}
+	target := helloworld.Spec.Deliveries[i]
+
+	payload, err := r.deliveryPayload(ctx, helloworld)
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	request := &delivery.Request{URL: target.URL, Headers: http.Header{}, Body: body}
+
+	if target.HeadersFrom != nil {
+		secret, err := r.deliverySecret(ctx, helloworld.Namespace, target.HeadersFrom.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get header Secret %s: %w", target.HeadersFrom.Name, err)
+		}
+		for name, value := range secret.Data {
+			if !httpguts.ValidHeaderFieldName(name) || !httpguts.ValidHeaderFieldValue(string(value)) {
+				return nil, fmt.Errorf("key %s of Secret %s is not a valid header", name, secret.Name)
+			}
+			request.Headers.Set(name, string(value))
+		}
+	}
+	if selector := target.SigningKeyRef; selector != nil {
+		secret, err := r.deliverySecret(ctx, helloworld.Namespace, selector.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get signing key Secret %s: %w", selector.Name, err)
+		}
+		key, ok := secret.Data[selector.Key]
+		if !ok || len(key) == 0 {
+			return nil, fmt.Errorf("key %s not found in Secret %s", selector.Key, selector.Name)
+		}
+		request.SigningKey = key
+	}
+	return request, nil
+}
+
+// deliverySecret returns the Secret name in namespace, which must opt in to
+// being read for deliveries with DeliverySecretLabel
+func (r *HelloWorldReconciler) deliverySecret(ctx context.Context, namespace, name string) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, secret); err != nil {
+		return nil, err
+	}
+	if secret.Labels[appsv1.DeliverySecretLabel] != "true" {
+		return nil, fmt.Errorf("the Secret is not labeled %s=true", appsv1.DeliverySecretLabel)
+	}
+	return secret, nil
+}
+
+// deliveryPayload returns the payload delivering the message of helloworld. A
+// template is rendered with the values of the current pod, if any. A Secret is
+// left out, so it is not sent anywhere a HelloWorld points, and so is a field of
+// the pod, since the pod reads it itself.
+func (r *HelloWorldReconciler) deliveryPayload(ctx context.Context, helloworld *appsv1.HelloWorld) (*deliveryPayload, error) {
+	payload := &deliveryPayload{
+		APIVersion: appsv1.GroupVersion.String(),
+		Kind:       "HelloWorld",
+		Namespace:  helloworld.Namespace,
+		Name:       helloworld.Name,
+		UID:        helloworld.UID,
+		Generation: helloworld.Generation,
+	}
+	switch {
+	case helloworld.Spec.MessageFrom == nil && helloworld.Spec.MessageTemplate != "":
+		tmpl, err := parseMessageTemplate(helloworld.Spec.MessageTemplate)
+		if err != nil {
+			return nil, err
+		}
+		var pod *corev1.Pod
+		if helloworld.Status.PodName != "" {
+			pod = &corev1.Pod{}
+			if err := r.Get(ctx, types.NamespacedName{Name: helloworld.Status.PodName, Namespace: helloworld.Namespace}, pod); err != nil {
+				pod = nil
+			}
+		}
+		if payload.Message, err = executeMessageTemplate(tmpl, newTemplateData(helloworld, pod, time.Now().UTC())); err != nil {
+			return nil, err
+		}
+
+	case helloworld.Spec.MessageFrom == nil && len(helloworld.Spec.Messages) > 0:
+		localized, err := r.selectLocales(ctx, helloworld)
+		if err != nil {
+			return nil, err
+		}
+		payload.Messages = localized
+
+	case helloworld.Spec.MessageFrom != nil && helloworld.Spec.MessageFrom.SecretKeyRef != nil:
+		// A message read from a Secret is not delivered
+
+	default:
+		_, value, _, err := r.readMessage(ctx, helloworld)
+		if err != nil {
+			return nil, err
+		}
+		payload.Message = value
+	}
+	return payload, nil
+}
+
+// enqueueOnDelivery queues the HelloWorld of every delivery attempt, so its
+// status reports the outcome
+func (r *HelloWorldReconciler) enqueueOnDelivery(ctx context.Context, queue workqueue.TypedRateLimitingInterface[reconcile.Request]) error {
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case key := <-r.Deliveries.Attempted():
+				queue.Add(reconcile.Request{NamespacedName: key})
+			}
+		}
+	}()
+	return nil
+}
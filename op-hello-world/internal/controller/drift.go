@@ -0,0 +1,165 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SpecHashAnnotation records a hash of the managed spec fields a pod was created
+// with. A pod whose hash differs from the desired one was built from an older
+// HelloWorld spec or manager config, which is not drift.
+const SpecHashAnnotation = "apps.example.com/spec-hash"
+
+// Managed pod fields, as reported in the field label of helloworld_drift_corrections_total
+const (
+	fieldLabels         = "labels"
+	fieldOwnerReference = "ownerReference"
+	fieldContainers     = "containers"
+	fieldImage          = "image"
+	fieldCommand        = "command"
+	fieldArgs           = "args"
+	fieldResources      = "resources"
+	fieldRestartPolicy  = "restartPolicy"
+)
+
+// specHash hashes the pod spec fields the controller manages
+func specHash(pod *corev1.Pod) string {
+	return managedHash(pod, pod)
+}
+
+// managedHash hashes the fields of pod that desired sets: the containers of
+// desired, matched by name, with only the resource keys desired sets. Containers
+// and resources added by admission, e.g. an injected sidecar or LimitRange
+// defaults, do not change the hash.
+func managedHash(desired, pod *corev1.Pod) string {
+	type container struct {
+		Name      string                      `json:"name"`
+		Image     string                      `json:"image"`
+		Command   []string                    `json:"command,omitempty"`
+		Args      []string                    `json:"args,omitempty"`
+		Resources corev1.ResourceRequirements `json:"resources"`
+	}
+	managed := struct {
+		Containers    []container          `json:"containers"`
+		RestartPolicy corev1.RestartPolicy `json:"restartPolicy"`
+	}{RestartPolicy: pod.Spec.RestartPolicy}
+	for _, want := range desired.Spec.Containers {
+		c := findContainer(pod, want.Name)
+		if c == nil {
+			managed.Containers = append(managed.Containers, container{Name: want.Name})
+			continue
+		}
+		managed.Containers = append(managed.Containers, container{
+			Name: c.Name, Image: c.Image, Command: c.Command, Args: c.Args,
+			Resources: managedResources(want.Resources, c.Resources),
+		})
+	}
+
+	// Marshalling plain strings, slices and quantities cannot fail
+	data, _ := json.Marshal(managed)
+	h := fnv.New64a()
+	_, _ = h.Write(data)
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// findContainer returns the container of pod named name, or nil
+func findContainer(pod *corev1.Pod, name string) *corev1.Container {
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name == name {
+			return &pod.Spec.Containers[i]
+		}
+	}
+	return nil
+}
+
+// managedResources returns the requests and limits of got for the resource keys
+// want sets, so defaulted resources are ignored
+func managedResources(want, got corev1.ResourceRequirements) corev1.ResourceRequirements {
+	var managed corev1.ResourceRequirements
+	pick := func(want, got corev1.ResourceList) corev1.ResourceList {
+		if len(want) == 0 {
+			return nil
+		}
+		picked := corev1.ResourceList{}
+		for name := range want {
+			if quantity, ok := got[name]; ok {
+				picked[name] = quantity
+			}
+		}
+		return picked
+	}
+	managed.Requests = pick(want.Requests, got.Requests)
+	managed.Limits = pick(want.Limits, got.Limits)
+	return managed
+}
+
+// podDrift lists the managed fields of live that differ from desired, and whether
+// correcting them needs the pod to be recreated. Labels and the controller
+// reference are always compared; the containers only when live was built from the
+// same spec and config as desired. Only the containers and resource keys the
+// controller sets are compared, so admission mutations are not drift.
+func podDrift(desired, live *corev1.Pod) (fields []string, recreate bool) {
+	for key, value := range desired.Labels {
+		if live.Labels[key] != value {
+			fields = append(fields, fieldLabels)
+			break
+		}
+	}
+
+	want, got := metav1.GetControllerOf(desired), metav1.GetControllerOf(live)
+	if got == nil || want == nil || got.UID != want.UID {
+		fields = append(fields, fieldOwnerReference)
+	}
+
+	hash := desired.Annotations[SpecHashAnnotation]
+	if live.Annotations[SpecHashAnnotation] != hash || managedHash(desired, live) == hash {
+		return fields, false
+	}
+
+	for _, want := range desired.Spec.Containers {
+		got := findContainer(live, want.Name)
+		if got == nil {
+			return append(fields, fieldContainers), true
+		}
+		// Image is the only container field that can be changed in place
+		if got.Image != want.Image && !slices.Contains(fields, fieldImage) {
+			fields = append(fields, fieldImage)
+		}
+		if !slices.Equal(got.Command, want.Command) && !slices.Contains(fields, fieldCommand) {
+			fields, recreate = append(fields, fieldCommand), true
+		}
+		if !slices.Equal(got.Args, want.Args) && !slices.Contains(fields, fieldArgs) {
+			fields, recreate = append(fields, fieldArgs), true
+		}
+		if !equality.Semantic.DeepEqual(managedResources(want.Resources, got.Resources), want.Resources) &&
+			!slices.Contains(fields, fieldResources) {
+			fields, recreate = append(fields, fieldResources), true
+		}
+	}
+	if live.Spec.RestartPolicy != desired.Spec.RestartPolicy {
+		fields, recreate = append(fields, fieldRestartPolicy), true
+	}
+	return fields, recreate
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	appsv1 "github.com/example/op-hello-world/api/v1"
+)
+
+var _ = Describe("podDrift", func() {
+	var desired, live *corev1.Pod
+
+	BeforeEach(func() {
+		helloworld := &appsv1.HelloWorld{
+			ObjectMeta: metav1.ObjectMeta{Name: "drift", Namespace: "default", UID: "hw-uid"},
+			Spec:       appsv1.HelloWorldSpec{Message: "Hello, drift!"},
+		}
+		desired = (&HelloWorldReconciler{}).podForHelloWorld(context.Background(), helloworld)
+		Expect(controllerutil.SetControllerReference(helloworld, desired, scheme.Scheme)).To(Succeed())
+		live = desired.DeepCopy()
+	})
+
+	It("finds nothing on an unchanged pod", func() {
+		fields, recreate := podDrift(desired, live)
+		Expect(fields).To(BeEmpty())
+		Expect(recreate).To(BeFalse())
+	})
+
+	It("patches labels, the owner reference and the image in place", func() {
+		live.Labels["helloworld"] = "edited"
+		live.OwnerReferences = nil
+		live.Spec.Containers[0].Image = "busybox:edited"
+		fields, recreate := podDrift(desired, live)
+		Expect(fields).To(ConsistOf(fieldLabels, fieldOwnerReference, fieldImage))
+		Expect(recreate).To(BeFalse())
+	})
+
+	It("recreates the pod when an immutable field drifted", func() {
+		live.Spec.Containers[0].Args = []string{"echo edited"}
+		live.Spec.Containers[0].Resources.Limits[corev1.ResourceMemory] = resource.MustParse("1Gi")
+		fields, recreate := podDrift(desired, live)
+		Expect(fields).To(ConsistOf(fieldArgs, fieldResources))
+		Expect(recreate).To(BeTrue())
+	})
+
+	It("ignores a container injected by admission", func() {
+		live.Spec.Containers = append(live.Spec.Containers, corev1.Container{
+			Name: "istio-proxy", Image: "istio/proxyv2:1.22.0", Args: []string{"proxy", "sidecar"},
+		})
+		live.Spec.Containers[0], live.Spec.Containers[1] = live.Spec.Containers[1], live.Spec.Containers[0]
+		fields, recreate := podDrift(desired, live)
+		Expect(fields).To(BeEmpty())
+		Expect(recreate).To(BeFalse())
+
+		live.Spec.Containers[1].Image = "busybox:edited"
+		fields, recreate = podDrift(desired, live)
+		Expect(fields).To(ConsistOf(fieldImage))
+		Expect(recreate).To(BeFalse())
+	})
+
+	It("ignores resources defaulted by admission", func() {
+		resources := &live.Spec.Containers[0].Resources
+		resources.Requests[corev1.ResourceEphemeralStorage] = resource.MustParse("1Gi")
+		resources.Limits[corev1.ResourceEphemeralStorage] = resource.MustParse("2Gi")
+		delete(desired.Spec.Containers[0].Resources.Requests, corev1.ResourceCPU)
+		desired.Annotations[SpecHashAnnotation] = specHash(desired)
+		live.Annotations[SpecHashAnnotation] = desired.Annotations[SpecHashAnnotation]
+		fields, recreate := podDrift(desired, live)
+		Expect(fields).To(BeEmpty())
+		Expect(recreate).To(BeFalse())
+	})
+
+	It("recreates the pod when a managed container was removed", func() {
+		live.Spec.Containers[0].Name = "renamed"
+		fields, recreate := podDrift(desired, live)
+		Expect(fields).To(ConsistOf(fieldContainers))
+		Expect(recreate).To(BeTrue())
+	})
+
+	It("leaves the containers of a pod built from an older spec alone", func() {
+		live.Annotations[SpecHashAnnotation] = "older"
+		live.Spec.Containers[0].Args = []string{"echo older"}
+		live.Labels = nil
+		fields, recreate := podDrift(desired, live)
+		Expect(fields).To(ConsistOf(fieldLabels))
+		Expect(recreate).To(BeFalse())
+	})
+})
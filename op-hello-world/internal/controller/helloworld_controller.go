@@ -18,24 +18,45 @@ package controller
 
 import (
 	"context"
+	goerrors "errors"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
+	k8sappsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
 
 	appsv1 "github.com/example/op-hello-world/api/v1"
+	"github.com/example/op-hello-world/internal/cloudevents"
+	"github.com/example/op-hello-world/internal/config"
+	"github.com/example/op-hello-world/internal/delivery"
 	"github.com/example/op-hello-world/internal/metrics"
+	"github.com/example/op-hello-world/internal/profiling"
 	"github.com/example/op-hello-world/internal/tracing"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 )
@@ -44,6 +65,42 @@ import (
 type HelloWorldReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	// Config holds the manager configuration, which may be reloaded while the
+	// controller runs. The built-in defaults are used when it is nil.
+	Config *config.Store
+
+	// Recorder records events on HelloWorld resources. Events are dropped when it is nil.
+	Recorder record.EventRecorder
+
+	// GatewayAPI reports whether the cluster serves Gateway API HTTPRoutes. A
+	// spec.route is exposed with an Ingress instead when it is false.
+	GatewayAPI bool
+
+	// Deliveries sends messages to the webhooks of spec.deliveries. Nothing is
+	// delivered when it is nil.
+	Deliveries *delivery.Dispatcher
+
+	// CloudEvents publishes a CloudEvent when the phase or a condition of a
+	// HelloWorld changes. None are published when it is nil.
+	CloudEvents *cloudevents.Emitter
+
+	// Shard limits the controller to the HelloWorlds this replica owns when
+	// sharding is enabled. Every HelloWorld is reconciled when it is nil.
+	Shard Shard
+
+	// ApprovalWebhook reports whether the HelloWorld webhook serves, which is
+	// the only way the approved-by annotation can be trusted. Approvers are not
+	// recorded when it is false.
+	ApprovalWebhook bool
+}
+
+// Shard decides which HelloWorlds a replica reconciles
+type Shard interface {
+	// OwnsObject reports whether this replica reconciles obj
+	OwnsObject(obj client.Object) bool
+	// Changed receives a value whenever ownership may have moved between replicas
+	Changed() <-chan struct{}
 }
 
 // +kubebuilder:rbac:groups=apps.example.com,resources=helloworlds,verbs=get;list;watch;create;update;patch;delete
@@ -51,6 +108,14 @@ type HelloWorldReconciler struct {
 // +kubebuilder:rbac:groups=apps.example.com,resources=helloworlds/finalizers,verbs=update
 // +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=discovery.k8s.io,resources=endpointslices,verbs=get;list;watch
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=apps,resources=controllerrevisions,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -78,6 +143,13 @@ func (r *HelloWorldReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	)
 	defer span.End()
 
+	// Label profile samples with this span so Pyroscope can link them to the trace
+	ctx, restoreLabels := profiling.WithSpanLabels(ctx, span, "Reconcile",
+		profiling.LabelController, "helloworld",
+		profiling.LabelNamespace, req.Namespace,
+	)
+	defer restoreLabels()
+
 	// Add trace and span IDs to log context
 	log = log.WithValues(
 		"traceID", span.SpanContext().TraceID().String(),
@@ -98,6 +170,9 @@ func (r *HelloWorldReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			log.V(1).Info("HelloWorld resource not found. Ignoring since object must be deleted")
+			if r.Deliveries != nil {
+				r.Deliveries.Forget(ctx, req.NamespacedName)
+			}
 			metrics.ReconcileTotal.WithLabelValues("helloworld", "resource_deleted").Inc()
 			span.SetAttributes(attribute.String("reconcile.result", "resource_deleted"))
 			return ctrl.Result{}, nil
@@ -110,12 +185,62 @@ func (r *HelloWorldReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, err
 	}
 
+	// Another replica reconciles this resource. The predicate filters most
+	// events, but ownership can move while a request waits in the queue.
+	if r.Shard != nil && !r.Shard.OwnsObject(helloworld) {
+		log.V(1).Info("HelloWorld is owned by another shard, skipping")
+		metrics.ReconcileTotal.WithLabelValues("helloworld", "not_owned").Inc()
+		span.SetAttributes(attribute.String("reconcile.result", "not_owned"))
+		return ctrl.Result{}, nil
+	}
+
+	// Publish the transitions of the status written by this reconcile. Events
+	// are sent in the background, so a slow or failing sink does not hold it up.
+	previousStatus, resourceVersion := helloworld.Status.DeepCopy(), helloworld.ResourceVersion
+	defer func() { r.emitTransitions(ctx, helloworld, previousStatus, resourceVersion) }()
+
 	// Add resource attributes to span
 	span.SetAttributes(
 		attribute.String("helloworld.message", helloworld.Spec.Message),
 		attribute.String("helloworld.uid", string(helloworld.UID)),
 	)
 
+	// Link to the trace of whoever applied the resource (CI pipeline, GitOps tool)
+	// and carry its baggage forward to the generated pod
+	appliedCtx := tracing.ExtractFromAnnotations(ctx, helloworld.Annotations)
+	if remote := trace.SpanContextFromContext(appliedCtx); remote.IsValid() && remote.IsRemote() {
+		span.AddLink(trace.Link{SpanContext: remote})
+		span.SetAttributes(attribute.String("helloworld.applied_trace_id", remote.TraceID().String()))
+	}
+	ctx = baggage.ContextWithBaggage(ctx, baggage.FromContext(appliedCtx))
+
+	// Restore the spec of an earlier revision. The update bumps the generation,
+	// which triggers the reconcile that rolls it out.
+	if helloworld.Spec.RollbackTo != nil {
+		if err := r.rollback(ctx, helloworld); err != nil {
+			log.Error(err, "Failed to roll back")
+			metrics.ReconcileErrors.WithLabelValues("helloworld").Inc()
+			metrics.ReconcileTotal.WithLabelValues("helloworld", "error").Inc()
+			tracing.RecordError(span, err, "Failed to roll back")
+			span.SetStatus(codes.Error, "Failed to roll back")
+			return ctrl.Result{}, err
+		}
+		metrics.ReconcileTotal.WithLabelValues("helloworld", "rolled_back").Inc()
+		span.SetAttributes(attribute.String("reconcile.result", "rolled_back"))
+		return ctrl.Result{}, nil
+	}
+
+	// Record the spec in a ControllerRevision, like a Deployment does for its
+	// pod template
+	if err := r.syncRevisions(ctx, helloworld); err != nil {
+		log.Error(err, "Failed to sync revisions")
+		metrics.ReconcileErrors.WithLabelValues("helloworld").Inc()
+		metrics.ReconcileTotal.WithLabelValues("helloworld", "error").Inc()
+		tracing.RecordError(span, err, "Failed to sync revisions")
+		span.SetStatus(codes.Error, "Failed to sync revisions")
+		return ctrl.Result{}, err
+	}
+
 	// Ensure pull secret exists in the namespace
 	if err := r.ensurePullSecret(ctx, req.Namespace); err != nil {
 		log.Error(err, "Failed to ensure pull secret")
@@ -124,8 +249,94 @@ func (r *HelloWorldReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		// Continue even if pull secret fails - pod might use public images
 	}
 
+	// Read the message, which may come from a ConfigMap or Secret
+	messageHash, localized, err := r.resolveMessage(ctx, helloworld)
+	if unresolved := (*unresolvedMessageError)(nil); goerrors.As(err, &unresolved) {
+		// The referenced object is watched, so creating it triggers a reconcile
+		log.Info("Message cannot be resolved", "reason", unresolved.reason, "error", unresolved.message)
+		metrics.ReconcileTotal.WithLabelValues("helloworld", "message_unresolved").Inc()
+		r.setCondition(helloworld, appsv1.TypeMessageResolved, metav1.ConditionFalse, unresolved.reason, unresolved.message)
+		r.setCondition(helloworld, appsv1.TypeProgressing, metav1.ConditionFalse, "MessageUnresolved", "Waiting for the message to be resolved")
+		phase := helloworld.Status.Phase
+		if phase == "" {
+			phase = appsv1.PhasePending
+		}
+		if err := r.updateStatus(ctx, helloworld, phase, helloworld.Status.PodName, unresolved.message); err != nil {
+			log.Error(err, "Failed to update status")
+		}
+		r.event(helloworld, corev1.EventTypeWarning, "MessageUnresolved", unresolved.message)
+		span.SetAttributes(attribute.String("reconcile.result", "message_unresolved"))
+		return ctrl.Result{}, nil
+	}
+	if err != nil {
+		log.Error(err, "Failed to resolve message")
+		metrics.ReconcileErrors.WithLabelValues("helloworld").Inc()
+		metrics.ReconcileTotal.WithLabelValues("helloworld", "error").Inc()
+		tracing.RecordError(span, err, "Failed to resolve message")
+		span.SetStatus(codes.Error, "Failed to resolve message")
+		return ctrl.Result{}, err
+	}
+	r.setCondition(helloworld, appsv1.TypeMessageResolved, metav1.ConditionTrue, "Resolved", "Message resolved")
+	helloworld.Status.ActiveLocales = nil
+	for _, message := range localized {
+		helloworld.Status.ActiveLocales = append(helloworld.Status.ActiveLocales, message.Locale)
+	}
+
+	// Under approvalPolicy Required, a new message is neither delivered nor
+	// rolled out until its generation is approved
+	held := r.awaitingApproval(ctx, helloworld, messageHash)
+
+	// Queue the message for spec.deliveries; outcomes are recorded in the status
+	// written below
+	if !held {
+		r.reconcileDeliveries(ctx, helloworld)
+	}
+
+	// With more than one replica, new revisions are rolled out in the steps of
+	// spec.rollout
+	if ptr.Deref(helloworld.Spec.Replicas, 1) > 1 {
+		result, requeueAfter, err := r.reconcileReplicas(ctx, helloworld, messageHash, held)
+		if err != nil {
+			log.Error(err, "Failed to reconcile replicas")
+			metrics.ReconcileErrors.WithLabelValues("helloworld").Inc()
+			metrics.ReconcileTotal.WithLabelValues("helloworld", "error").Inc()
+			tracing.RecordError(span, err, "Failed to reconcile replicas")
+			span.SetStatus(codes.Error, "Failed to reconcile replicas")
+			return ctrl.Result{}, err
+		}
+		metrics.ReconcileTotal.WithLabelValues("helloworld", result).Inc()
+		metrics.HelloWorldResources.WithLabelValues(helloworld.Namespace).Set(1)
+		span.SetAttributes(attribute.String("reconcile.result", result))
+		span.SetStatus(codes.Ok, "Reconciliation completed")
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
+	}
+	if helloworld.Status.Replicas > 0 {
+		deleted, err := r.deleteReplicas(ctx, helloworld)
+		if err != nil {
+			log.Error(err, "Failed to delete replicas")
+			metrics.ReconcileErrors.WithLabelValues("helloworld").Inc()
+			metrics.ReconcileTotal.WithLabelValues("helloworld", "error").Inc()
+			tracing.RecordError(span, err, "Failed to delete replicas")
+			span.SetStatus(codes.Error, "Failed to delete replicas")
+			return ctrl.Result{}, err
+		}
+		if deleted {
+			// The single pod is created once the replicas are gone
+			metrics.ReconcileTotal.WithLabelValues("helloworld", "replicas_scaled").Inc()
+			r.setCondition(helloworld, appsv1.TypeProgressing, metav1.ConditionTrue, "ScalingReplicas", "Replacing the replicas with a single pod")
+			r.setCondition(helloworld, appsv1.TypeReady, metav1.ConditionFalse, "PodNotReady", "Replicas are terminating")
+			if err := r.updateStatus(ctx, helloworld, appsv1.PhasePending, "", "Waiting for the replicas to terminate"); err != nil {
+				log.Error(err, "Failed to update status")
+			}
+			span.SetAttributes(attribute.String("reconcile.result", "replicas_scaled"))
+			span.SetStatus(codes.Ok, "Replicas deleted")
+			return ctrl.Result{RequeueAfter: r.config().Controller.RequeueInterval.Duration}, nil
+		}
+	}
+
 	// Define the desired pod for this HelloWorld resource
-	pod := r.podForHelloWorld(helloworld)
+	pod := r.podForHelloWorld(ctx, helloworld)
+	pod.Annotations[MessageHashAnnotation] = messageHash
 
 	// Set HelloWorld instance as the owner and controller
 	if err := controllerutil.SetControllerReference(helloworld, pod, r.Scheme); err != nil {
@@ -141,10 +352,26 @@ func (r *HelloWorldReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	}
 
 	// Check if the pod already exists, if not create a new one
-	found := &corev1.Pod{}
-	err = r.Get(ctx, types.NamespacedName{Name: pod.Name, Namespace: pod.Namespace}, found)
-	if err != nil && errors.IsNotFound(err) {
-		log.Info("Creating a new Pod", "pod", types.NamespacedName{Name: pod.Name, Namespace: pod.Namespace}, "message", helloworld.Spec.Message)
+	found, err := r.findPod(ctx, helloworld, pod)
+	if err != nil {
+		log.Error(err, "Failed to get Pod")
+		metrics.ReconcileErrors.WithLabelValues("helloworld").Inc()
+		metrics.ReconcileTotal.WithLabelValues("helloworld", "error").Inc()
+		tracing.RecordError(span, err, "Failed to get pod")
+		span.SetStatus(codes.Error, "Failed to get pod")
+		return ctrl.Result{}, err
+	}
+	if found == nil && held {
+		metrics.ReconcileTotal.WithLabelValues("helloworld", "awaiting_approval").Inc()
+		if err := r.holdForApproval(ctx, helloworld, messageHash, ""); err != nil {
+			log.Error(err, "Failed to update status")
+		}
+		span.SetAttributes(attribute.String("reconcile.result", "awaiting_approval"))
+		span.SetStatus(codes.Ok, "Message awaits approval")
+		return ctrl.Result{}, nil
+	}
+	if found == nil {
+		log.Info("Creating a new Pod", "pod", types.NamespacedName{Name: pod.Name + pod.GenerateName, Namespace: pod.Namespace}, "message", helloworld.Spec.Message)
 
 		// Update status to Pending before creating pod
 		r.setCondition(helloworld, appsv1.TypeProgressing, metav1.ConditionTrue, "CreatingPod", "Creating pod for HelloWorld resource")
@@ -185,6 +412,8 @@ func (r *HelloWorldReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		log.Info("Pod created successfully", "pod", types.NamespacedName{Name: pod.Name, Namespace: pod.Namespace}, "message", helloworld.Spec.Message)
 
 		// Update status to Running
+		helloworld.Status.PodUID = pod.UID
+		helloworld.Status.RenderedMessage = renderedMessage(helloworld, pod)
 		r.setCondition(helloworld, appsv1.TypeProgressing, metav1.ConditionTrue, "PodCreated", "Pod has been created successfully")
 		r.setCondition(helloworld, appsv1.TypeReady, metav1.ConditionFalse, "PodStarting", "Pod is starting up")
 		if err := r.updateStatus(ctx, helloworld, appsv1.PhaseRunning, pod.Name, "Pod created successfully"); err != nil {
@@ -193,19 +422,147 @@ func (r *HelloWorldReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 
 		span.SetAttributes(attribute.String("reconcile.result", "pod_created"))
 		span.SetStatus(codes.Ok, "Pod created successfully")
-		return ctrl.Result{Requeue: true}, nil
-	} else if err != nil {
-		log.Error(err, "Failed to get Pod")
+		return ctrl.Result{RequeueAfter: r.config().Controller.RequeueInterval.Duration}, nil
+	}
+
+	// A pod this HelloWorld does not own is adopted if it opted in, and otherwise
+	// left alone and reported as a name conflict
+	adopted := false
+	if !ownedBy(helloworld, found) {
+		if !canAdopt(pod, found) {
+			message := fmt.Sprintf("Pod %s already exists and is not owned by this HelloWorld; annotate it with %s=true to adopt it, or use podNameStrategy Generated",
+				found.Name, appsv1.AdoptAnnotation)
+			log.Info("Pod name conflict", "pod", types.NamespacedName{Name: found.Name, Namespace: found.Namespace})
+			metrics.ReconcileTotal.WithLabelValues("helloworld", "name_conflict").Inc()
+			r.setCondition(helloworld, appsv1.TypeReady, metav1.ConditionFalse, "NameConflict", "Pod name is taken")
+			r.setCondition(helloworld, appsv1.TypeProgressing, metav1.ConditionFalse, "NameConflict", "Waiting for the pod name conflict to be resolved")
+			r.setCondition(helloworld, appsv1.TypeDegraded, metav1.ConditionTrue, "NameConflict", message)
+			if err := r.updateStatus(ctx, helloworld, appsv1.PhaseFailed, "", message); err != nil {
+				log.Error(err, "Failed to update status")
+			}
+			r.event(helloworld, corev1.EventTypeWarning, "NameConflict", message)
+			span.SetAttributes(attribute.String("reconcile.result", "name_conflict"))
+
+			// Pods owned by something else are not watched, so check again later
+			return ctrl.Result{RequeueAfter: r.config().Controller.RequeueInterval.Duration}, nil
+		}
+
+		if err := r.adoptPod(ctx, helloworld, found); err != nil {
+			log.Error(err, "Failed to adopt Pod", "pod", types.NamespacedName{Name: found.Name, Namespace: found.Namespace})
+			metrics.ReconcileErrors.WithLabelValues("helloworld").Inc()
+			metrics.ReconcileTotal.WithLabelValues("helloworld", "error").Inc()
+			tracing.RecordError(span, err, "Failed to adopt pod")
+			span.SetStatus(codes.Error, "Failed to adopt pod")
+			return ctrl.Result{}, err
+		}
+		adopted = true
+	}
+	helloworld.Status.PodUID = found.UID
+	helloworld.Status.RenderedMessage = renderedMessage(helloworld, found)
+	if c := meta.FindStatusCondition(helloworld.Status.Conditions, appsv1.TypeDegraded); c != nil && c.Reason == "NameConflict" {
+		r.setCondition(helloworld, appsv1.TypeDegraded, metav1.ConditionFalse, "PodOwned", "Pod name conflict resolved")
+	}
+
+	// A pod that is being deleted is recreated once it is gone
+	if !found.DeletionTimestamp.IsZero() {
+		log.V(1).Info("Waiting for pod to terminate", "pod", types.NamespacedName{Name: found.Name, Namespace: found.Namespace})
+		metrics.ReconcileTotal.WithLabelValues("helloworld", "pod_terminating").Inc()
+		r.setCondition(helloworld, appsv1.TypeProgressing, metav1.ConditionTrue, "PodTerminating", "Waiting for the old pod to terminate")
+		r.setCondition(helloworld, appsv1.TypeReady, metav1.ConditionFalse, "PodNotReady", "Pod is terminating")
+		if err := r.updateStatus(ctx, helloworld, appsv1.PhasePending, "", "Waiting for pod to terminate"); err != nil {
+			log.Error(err, "Failed to update status")
+		}
+		span.SetAttributes(attribute.String("reconcile.result", "pod_terminating"))
+		span.SetStatus(codes.Ok, "Pod is terminating")
+		return ctrl.Result{RequeueAfter: r.config().Controller.RequeueInterval.Duration}, nil
+	}
+
+	// Replace a pod created with an older message. Pods created before the
+	// message was hashed, or adopted, are left alone.
+	if live, ok := found.Annotations[MessageHashAnnotation]; ok && live != messageHash {
+		if held {
+			metrics.ReconcileTotal.WithLabelValues("helloworld", "awaiting_approval").Inc()
+			if err := r.holdForApproval(ctx, helloworld, messageHash, found.Name); err != nil {
+				log.Error(err, "Failed to update status")
+			}
+			span.SetAttributes(attribute.String("reconcile.result", "awaiting_approval"))
+			span.SetStatus(codes.Ok, "Message awaits approval")
+			return ctrl.Result{}, nil
+		}
+		log.Info("Deleting pod to roll out a new message", "pod", types.NamespacedName{Name: found.Name, Namespace: found.Namespace})
+		if err := r.Delete(ctx, found, client.Preconditions{UID: &found.UID}); err != nil && !errors.IsNotFound(err) {
+			log.Error(err, "Failed to delete pod with an old message", "pod", types.NamespacedName{Name: found.Name, Namespace: found.Namespace})
+			metrics.ReconcileErrors.WithLabelValues("helloworld").Inc()
+			metrics.ReconcileTotal.WithLabelValues("helloworld", "error").Inc()
+			tracing.RecordError(span, err, "Failed to delete pod with an old message")
+			span.SetStatus(codes.Error, "Failed to delete pod with an old message")
+			return ctrl.Result{}, err
+		}
+		metrics.ReconcileTotal.WithLabelValues("helloworld", "message_rollout").Inc()
+		r.event(helloworld, corev1.EventTypeNormal, "MessageChanged", fmt.Sprintf("Deleted pod %s to roll out the new message", found.Name))
+		r.setCondition(helloworld, appsv1.TypeProgressing, metav1.ConditionTrue, "RollingOut", "Pod is being replaced with the new message")
+		r.setCondition(helloworld, appsv1.TypeReady, metav1.ConditionFalse, "PodNotReady", "Pod is being replaced")
+		if err := r.updateStatus(ctx, helloworld, appsv1.PhasePending, "", "Rolling out new message"); err != nil {
+			log.Error(err, "Failed to update status")
+		}
+		span.SetAttributes(attribute.String("reconcile.result", "message_rollout"))
+		span.SetStatus(codes.Ok, "Pod with an old message deleted")
+		return ctrl.Result{RequeueAfter: r.config().Controller.RequeueInterval.Duration}, nil
+	}
+
+	// Pod already exists - restore fields changed outside the controller, e.g. with kubectl edit
+	result := "no_change"
+	corrected, recreating, err := r.correctDrift(ctx, helloworld, pod, found)
+	if err != nil {
+		log.Error(err, "Failed to correct pod drift", "pod", types.NamespacedName{Name: found.Name, Namespace: found.Namespace})
 		metrics.ReconcileErrors.WithLabelValues("helloworld").Inc()
 		metrics.ReconcileTotal.WithLabelValues("helloworld", "error").Inc()
-		tracing.RecordError(span, err, "Failed to get pod")
-		span.SetStatus(codes.Error, "Failed to get pod")
+		tracing.RecordError(span, err, "Failed to correct pod drift")
+		span.SetStatus(codes.Error, "Failed to correct pod drift")
+
+		r.setCondition(helloworld, appsv1.TypeDegraded, metav1.ConditionTrue, "DriftCorrectionFailed", fmt.Sprintf("Failed to correct pod drift: %v", err))
+		if err := r.updateStatus(ctx, helloworld, helloworld.Status.Phase, found.Name, "Failed to correct pod drift"); err != nil {
+			log.Error(err, "Failed to update status")
+		}
 		return ctrl.Result{}, err
 	}
+	if recreating {
+		// The pod is recreated once its deletion is observed
+		metrics.ReconcileTotal.WithLabelValues("helloworld", "drift_corrected").Inc()
+		r.setCondition(helloworld, appsv1.TypeProgressing, metav1.ConditionTrue, "RecreatingPod", "Pod is being recreated after drift")
+		r.setCondition(helloworld, appsv1.TypeReady, metav1.ConditionFalse, "PodNotReady", "Pod is being recreated")
+		if err := r.updateStatus(ctx, helloworld, appsv1.PhasePending, "", "Recreating drifted pod"); err != nil {
+			log.Error(err, "Failed to update status")
+		}
+		span.SetAttributes(attribute.String("reconcile.result", "drift_corrected"))
+		span.SetStatus(codes.Ok, "Drifted pod deleted")
+		return ctrl.Result{RequeueAfter: r.config().Controller.RequeueInterval.Duration}, nil
+	}
+	switch {
+	case adopted:
+		result = "pod_adopted"
+	case corrected:
+		result = "drift_corrected"
+	default:
+		log.V(1).Info("Skip reconcile: Pod already exists", "pod", types.NamespacedName{Name: found.Name, Namespace: found.Namespace})
+	}
+	metrics.ReconcileTotal.WithLabelValues("helloworld", result).Inc()
 
-	// Pod already exists - check its status and update accordingly
-	log.V(1).Info("Skip reconcile: Pod already exists", "pod", types.NamespacedName{Name: found.Name, Namespace: found.Namespace})
-	metrics.ReconcileTotal.WithLabelValues("helloworld", "no_change").Inc()
+	// Put a Service in front of the greeter in serve mode, and expose it on spec.route
+	if err := r.reconcileService(ctx, helloworld, pod.Labels); err != nil {
+		log.Error(err, "Failed to reconcile Service")
+		metrics.ReconcileErrors.WithLabelValues("helloworld").Inc()
+		tracing.RecordError(span, err, "Failed to reconcile service")
+		span.SetStatus(codes.Error, "Failed to reconcile service")
+		return ctrl.Result{}, err
+	}
+	if err := r.reconcileRoute(ctx, helloworld, pod.Labels); err != nil {
+		log.Error(err, "Failed to reconcile route")
+		metrics.ReconcileErrors.WithLabelValues("helloworld").Inc()
+		tracing.RecordError(span, err, "Failed to reconcile route")
+		span.SetStatus(codes.Error, "Failed to reconcile route")
+		return ctrl.Result{}, err
+	}
 
 	// Update status based on pod phase
 	podPhase := string(found.Status.Phase)
@@ -213,6 +570,7 @@ func (r *HelloWorldReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	case corev1.PodRunning:
 		r.setCondition(helloworld, appsv1.TypeReady, metav1.ConditionTrue, "PodRunning", "Pod is running successfully")
 		r.setCondition(helloworld, appsv1.TypeProgressing, metav1.ConditionFalse, "Stable", "Resource is stable")
+		helloworld.Status.CurrentRevision = helloworld.Status.UpdateRevision
 		if err := r.updateStatus(ctx, helloworld, appsv1.PhaseRunning, found.Name, fmt.Sprintf("Pod is %s", podPhase)); err != nil {
 			log.Error(err, "Failed to update status")
 		}
@@ -238,9 +596,14 @@ func (r *HelloWorldReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	// Update HelloWorld resource count metric
 	metrics.HelloWorldResources.WithLabelValues(helloworld.Namespace).Set(1)
 
-	span.SetAttributes(attribute.String("reconcile.result", "no_change"))
+	span.SetAttributes(attribute.String("reconcile.result", result))
 	span.SetStatus(codes.Ok, "Reconciliation completed")
 
+	// Check again until the pod is running, in case a pod event is missed
+	if found.Status.Phase != corev1.PodRunning && found.Status.Phase != corev1.PodFailed {
+		return ctrl.Result{RequeueAfter: r.config().Controller.RequeueInterval.Duration}, nil
+	}
+
 	// Custom code end
 	///////////////////////////////
 
@@ -249,9 +612,45 @@ func (r *HelloWorldReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *HelloWorldReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&appsv1.HelloWorld{}).
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &appsv1.HelloWorld{}, messageRefIndex, messageRefs); err != nil {
+		return err
+	}
+
+	b := ctrl.NewControllerManagedBy(mgr)
+	if len(r.config().Watch.Namespaces) == 0 {
+		// A namespace's locale annotation is only read when every namespace is watched
+		b = b.Watches(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(r.helloWorldsForNamespace),
+			builder.WithPredicates(predicate.AnnotationChangedPredicate{}))
+	}
+	if r.GatewayAPI {
+		// Route status is copied from the Gateway controller
+		b = b.Owns(&gatewayv1.HTTPRoute{})
+	}
+	if r.Deliveries != nil {
+		// The status reports the outcome of every delivery attempt
+		b = b.WatchesRawSource(source.Func(r.enqueueOnDelivery))
+	}
+	if r.Shard != nil {
+		b = b.For(&appsv1.HelloWorld{}, builder.WithPredicates(predicate.NewPredicateFuncs(r.Shard.OwnsObject))).
+			WatchesRawSource(source.Func(r.enqueueOnRebalance))
+	} else {
+		b = b.For(&appsv1.HelloWorld{})
+	}
+	return b.
+		// Pod events are mapped by owner reference or label, so a pod whose owner
+		// reference was removed still triggers drift correction
+		Watches(&corev1.Pod{}, handler.EnqueueRequestsFromMapFunc(helloWorldForPod)).
+		// A change to the ConfigMap or Secret a message is read from rolls it out
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.helloWorldsForMessageRef("ConfigMap"))).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.helloWorldsForMessageRef("Secret"))).
+		// The Serving condition follows the readiness of the Service's endpoints
+		Owns(&corev1.Service{}).
+		Owns(&networkingv1.Ingress{}).
+		// A deleted revision is recreated
+		Owns(&k8sappsv1.ControllerRevision{}).
+		Watches(&discoveryv1.EndpointSlice{}, handler.EnqueueRequestsFromMapFunc(helloWorldForEndpointSlice)).
 		Named("helloworld").
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.config().Controller.MaxConcurrentReconciles}).
 		Complete(r)
 }
 
@@ -259,37 +658,203 @@ func (r *HelloWorldReconciler) SetupWithManager(mgr ctrl.Manager) error {
 // Custom code start
 // Helper functions for the HelloWorld controller
 
-// podForHelloWorld returns a busybox pod with the same name/namespace as the HelloWorld CR
-func (r *HelloWorldReconciler) podForHelloWorld(helloworld *appsv1.HelloWorld) *corev1.Pod {
+// enqueueOnRebalance queues every HelloWorld this replica owns after shard
+// membership changes, picking up resources handed over by other replicas
+func (r *HelloWorldReconciler) enqueueOnRebalance(ctx context.Context, queue workqueue.TypedRateLimitingInterface[reconcile.Request]) error {
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-r.Shard.Changed():
+			}
+
+			list := &appsv1.HelloWorldList{}
+			if err := r.List(ctx, list); err != nil {
+				logf.FromContext(ctx).Error(err, "Failed to list HelloWorlds after shard rebalance")
+				continue
+			}
+			for i := range list.Items {
+				if r.Shard.OwnsObject(&list.Items[i]) {
+					queue.Add(reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&list.Items[i])})
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// helloWorldForPod maps a pod to the HelloWorld that controls it, falling back to
+// the pod's labels. Updates are mapped for both the old and new pod, so removing
+// the owner reference or the labels is still seen.
+func helloWorldForPod(_ context.Context, pod client.Object) []reconcile.Request {
+	if owner := metav1.GetControllerOf(pod); owner != nil {
+		if owner.Kind == "HelloWorld" && owner.APIVersion == appsv1.GroupVersion.String() {
+			return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: owner.Name, Namespace: pod.GetNamespace()}}}
+		}
+		return nil
+	}
+	labels := pod.GetLabels()
+	if labels["app"] == "helloworld" && labels["helloworld"] != "" {
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: labels["helloworld"], Namespace: pod.GetNamespace()}}}
+	}
+	return nil
+}
+
+// helloWorldsForMessageRef maps a ConfigMap or Secret to the HelloWorlds whose
+// message is read from it
+func (r *HelloWorldReconciler) helloWorldsForMessageRef(kind string) handler.MapFunc {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		list := &appsv1.HelloWorldList{}
+		if err := r.List(ctx, list, client.InNamespace(obj.GetNamespace()),
+			client.MatchingFields{messageRefIndex: kind + "/" + obj.GetName()}); err != nil {
+			logf.FromContext(ctx).Error(err, "Failed to list HelloWorlds for message reference", "kind", kind, "name", obj.GetName())
+			return nil
+		}
+		requests := make([]reconcile.Request, 0, len(list.Items))
+		for i := range list.Items {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&list.Items[i])})
+		}
+		return requests
+	}
+}
+
+// correctDrift restores the managed fields of live that differ from desired. The
+// labels, owner reference and image are patched in place; for any other drift the
+// pod is deleted so the next reconcile recreates it. It reports whether anything
+// was corrected and whether the pod was deleted.
+func (r *HelloWorldReconciler) correctDrift(ctx context.Context, helloworld *appsv1.HelloWorld, desired, live *corev1.Pod) (corrected, recreating bool, err error) {
+	fields, recreate := podDrift(desired, live)
+	if len(fields) == 0 {
+		return false, false, nil
+	}
+
+	log := logf.FromContext(ctx)
+	ctx, span := tracing.GetTracer("helloworld-controller").Start(ctx, "CorrectDrift",
+		trace.WithAttributes(
+			attribute.String("pod.name", live.Name),
+			attribute.StringSlice("drift.fields", fields),
+			attribute.Bool("drift.recreate", recreate),
+		),
+	)
+	defer span.End()
+
+	if recreate {
+		log.Info("Deleting drifted pod to recreate it", "pod", live.Name, "fields", fields)
+		if err := r.Delete(ctx, live, client.Preconditions{UID: &live.UID}); err != nil && !errors.IsNotFound(err) {
+			tracing.RecordError(span, err, "Failed to delete drifted pod")
+			return false, false, err
+		}
+		r.recordDrift(helloworld, fields, fmt.Sprintf("Deleted pod %s to recreate it: %s drifted", live.Name, strings.Join(fields, ", ")))
+		return true, true, nil
+	}
+
+	patch := client.StrategicMergeFrom(live.DeepCopy())
+	if live.Labels == nil {
+		live.Labels = map[string]string{}
+	}
+	for key, value := range desired.Labels {
+		live.Labels[key] = value
+	}
+	if err := controllerutil.SetControllerReference(helloworld, live, r.Scheme); err != nil {
+		tracing.RecordError(span, err, "Failed to restore controller reference")
+		return false, false, err
+	}
+	if slices.Contains(fields, fieldImage) {
+		for _, want := range desired.Spec.Containers {
+			findContainer(live, want.Name).Image = want.Image
+		}
+	}
+
+	log.Info("Restoring drifted pod fields", "pod", live.Name, "fields", fields)
+	if err := r.Patch(ctx, live, patch); err != nil {
+		tracing.RecordError(span, err, "Failed to patch drifted pod")
+		return false, false, err
+	}
+	r.recordDrift(helloworld, fields, fmt.Sprintf("Restored %s on pod %s", strings.Join(fields, ", "), live.Name))
+	return true, false, nil
+}
+
+// recordDrift counts the corrected fields and records an event on the HelloWorld
+func (r *HelloWorldReconciler) recordDrift(helloworld *appsv1.HelloWorld, fields []string, message string) {
+	for _, field := range fields {
+		metrics.DriftCorrections.WithLabelValues(field).Inc()
+	}
+	r.event(helloworld, corev1.EventTypeWarning, "PodDriftCorrected", message)
+}
+
+// event records an event on the HelloWorld when a recorder is configured
+func (r *HelloWorldReconciler) event(helloworld *appsv1.HelloWorld, eventType, reason, message string) {
+	if r.Recorder != nil {
+		r.Recorder.Event(helloworld, eventType, reason, message)
+	}
+}
+
+// config returns the current manager configuration
+func (r *HelloWorldReconciler) config() *config.ManagerConfig {
+	if r.Config == nil {
+		defaults := config.Default()
+		return &defaults
+	}
+	return r.Config.Get()
+}
+
+// podForHelloWorld returns a pod with the same name/namespace as the HelloWorld CR.
+// The span context in ctx is stamped onto the pod as annotations and env vars so the workload
+// can continue the trace.
+func (r *HelloWorldReconciler) podForHelloWorld(ctx context.Context, helloworld *appsv1.HelloWorld) *corev1.Pod {
+	defaults := r.config().Pod
+
+	// A referenced or rendered message is passed in an environment variable, never in the args
+	args := fmt.Sprintf("echo '%s' && sleep 3600", helloworld.Spec.Message)
+	env := tracing.EnvVars(ctx)
+	switch {
+	case helloworld.Spec.MessageFrom != nil:
+		args = fmt.Sprintf(`echo "$%s" && sleep 3600`, messageEnvVar)
+		env = append(env, messageEnv(helloworld.Spec.MessageFrom))
+	case helloworld.Spec.MessageTemplate != "":
+		// Reconcile resolves the message first, so the template renders
+		message, _ := renderPodMessage(helloworld, time.Now().UTC().Truncate(time.Second))
+		args = fmt.Sprintf(`echo "$%s" && sleep 3600`, messageEnvVar)
+		env = append(append(env, downwardEnv()...), corev1.EnvVar{Name: messageEnvVar, Value: message})
+	}
+
 	pod := &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      helloworld.Name + "-pod",
 			Namespace: helloworld.Namespace,
 			Labels: map[string]string{
 				"app":        "helloworld",
 				"helloworld": helloworld.Name,
 			},
+			Annotations: tracing.InjectIntoAnnotations(ctx, nil),
 		},
 		Spec: corev1.PodSpec{
 			Containers: []corev1.Container{{
-				Name:    "busybox",
-				Image:   "busybox:latest",
-				Command: []string{"sh", "-c"},
-				Args:    []string{fmt.Sprintf("echo '%s' && sleep 3600", helloworld.Spec.Message)},
-				Resources: corev1.ResourceRequirements{
-					Requests: corev1.ResourceList{
-						corev1.ResourceCPU:    resource.MustParse("50m"),
-						corev1.ResourceMemory: resource.MustParse("64Mi"),
-					},
-					Limits: corev1.ResourceList{
-						corev1.ResourceCPU:    resource.MustParse("100m"),
-						corev1.ResourceMemory: resource.MustParse("128Mi"),
-					},
-				},
+				Name:      "busybox",
+				Image:     defaults.Image,
+				Command:   []string{"sh", "-c"},
+				Args:      []string{args},
+				Env:       env,
+				Resources: *defaults.Resources.DeepCopy(),
 			}},
 			RestartPolicy: corev1.RestartPolicyAlways,
 		},
 	}
+	var localized []localizedMessage
+	if len(helloworld.Spec.Messages) > 0 {
+		// Reconcile resolves the message first, so the locales are valid
+		localized, _ = r.selectLocales(ctx, helloworld)
+		pod.Spec.Containers = localizedContainers(pod.Spec.Containers[0], localized)
+	}
+	if helloworld.Spec.Serve != nil {
+		pod.Spec.Containers = []corev1.Container{greeterContainer(helloworld, env, localized, defaults)}
+	}
+	if helloworld.Spec.PodNameStrategy == appsv1.PodNameGenerated {
+		pod.GenerateName = helloworld.Name + "-"
+	} else {
+		pod.Name = helloworld.Name + "-pod"
+	}
+	pod.Annotations[SpecHashAnnotation] = specHash(pod)
 	return pod
 }
 
@@ -298,6 +863,9 @@ func (r *HelloWorldReconciler) updateStatus(ctx context.Context, helloworld *app
 	// Update status fields
 	helloworld.Status.Phase = phase
 	helloworld.Status.PodName = podName
+	if podName == "" {
+		helloworld.Status.PodUID = ""
+	}
 	helloworld.Status.Message = message
 	now := metav1.Now()
 	helloworld.Status.LastUpdateTime = &now
@@ -347,6 +915,14 @@ func (r *HelloWorldReconciler) setCondition(helloworld *appsv1.HelloWorld, condi
 func (r *HelloWorldReconciler) ensurePullSecret(ctx context.Context, namespace string) error {
 	log := logf.FromContext(ctx)
 	secretName := "ghcr-login"
+	sourceNamespace := "default"
+
+	// A manager scoped to other namespaces has neither a cache nor RBAC for the source
+	if !watchesNamespace(r.config().Watch, sourceNamespace) {
+		log.V(1).Info("Pull secret source namespace is not watched, skipping copy",
+			"secret", secretName, "namespace", sourceNamespace)
+		return nil
+	}
 
 	// Check if secret already exists in target namespace
 	targetSecret := &corev1.Secret{}
@@ -362,7 +938,7 @@ func (r *HelloWorldReconciler) ensurePullSecret(ctx context.Context, namespace s
 
 	// Get the secret from default namespace
 	sourceSecret := &corev1.Secret{}
-	err = r.Get(ctx, types.NamespacedName{Name: secretName, Namespace: "default"}, sourceSecret)
+	err = r.Get(ctx, types.NamespacedName{Name: secretName, Namespace: sourceNamespace}, sourceSecret)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			log.V(1).Info("Pull secret not found in default namespace, skipping copy", "secret", secretName)
@@ -18,16 +18,42 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
+	goerrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	k8sappsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	appsv1 "github.com/example/op-hello-world/api/v1"
+	"github.com/example/op-hello-world/internal/cloudevents"
+	"github.com/example/op-hello-world/internal/delivery"
+	"github.com/example/op-hello-world/internal/telemetrytest"
 )
 
 var _ = Describe("HelloWorld Controller", func() {
@@ -41,8 +67,11 @@ var _ = Describe("HelloWorld Controller", func() {
 			Namespace: "default", // TODO(user):Modify as needed
 		}
 		helloworld := &appsv1.HelloWorld{}
+		var recorder *telemetrytest.Recorder
 
 		BeforeEach(func() {
+			recorder = telemetrytest.Install()
+
 			By("creating the custom resource for the Kind HelloWorld")
 			err := k8sClient.Get(ctx, typeNamespacedName, helloworld)
 			if err != nil && errors.IsNotFound(err) {
@@ -51,21 +80,32 @@ var _ = Describe("HelloWorld Controller", func() {
 						Name:      resourceName,
 						Namespace: "default",
 					},
-					// TODO(user): Specify other spec details if needed.
+					Spec: appsv1.HelloWorldSpec{
+						Message: "Hello, test!",
+					},
 				}
 				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
 			}
 		})
 
 		AfterEach(func() {
-			// TODO(user): Cleanup logic after each test, like removing the resource instance.
+			recorder.Uninstall()
+
 			resource := &appsv1.HelloWorld{}
 			err := k8sClient.Get(ctx, typeNamespacedName, resource)
 			Expect(err).NotTo(HaveOccurred())
 
 			By("Cleanup the specific resource instance HelloWorld")
 			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+
+			By("Cleanup the generated pod")
+			pod := &corev1.Pod{}
+			err = k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-pod", Namespace: "default"}, pod)
+			if err == nil {
+				Expect(k8sClient.Delete(ctx, pod)).To(Succeed())
+			}
 		})
+
 		It("should successfully reconcile the resource", func() {
 			By("Reconciling the created resource")
 			controllerReconciler := &HelloWorldReconciler{
@@ -77,8 +117,1011 @@ var _ = Describe("HelloWorld Controller", func() {
 				NamespacedName: typeNamespacedName,
 			})
 			Expect(err).NotTo(HaveOccurred())
-			// TODO(user): Add more specific assertions depending on your controller's reconciliation logic.
-			// Example: If you expect a certain status condition after reconciliation, verify it here.
+
+			By("Checking the reconcile and pod creation spans")
+			spans := recorder.SpansNamed("Reconcile")
+			Expect(spans).To(HaveLen(1))
+			result, ok := telemetrytest.Attribute(spans[0], "reconcile.result")
+			Expect(ok).To(BeTrue())
+			Expect(result.AsString()).To(Equal("pod_created"))
+			Expect(spans[0].Status.Code).To(Equal(codes.Ok))
+			Expect(recorder.SpansNamed("CreatePod")).To(HaveLen(1))
+
+			By("Checking the reconcile metrics")
+			Expect(recorder.Metric("helloworld_reconcile_total",
+				map[string]string{"controller": "helloworld", "result": "pod_created"})).To(Equal(1.0))
+			Expect(recorder.Metric("helloworld_pod_creations_total",
+				map[string]string{"namespace": "default"})).To(Equal(1.0))
+			Expect(recorder.Metric("helloworld_reconcile_duration_seconds",
+				map[string]string{"controller": "helloworld"})).To(Equal(1.0))
+
+			By("Reconciling again once the pod exists")
+			recorder.Reset()
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			spans = recorder.SpansNamed("Reconcile")
+			Expect(spans).To(HaveLen(1))
+			result, _ = telemetrytest.Attribute(spans[0], "reconcile.result")
+			Expect(result.AsString()).To(Equal("no_change"))
+			Expect(recorder.Metric("helloworld_reconcile_total",
+				map[string]string{"controller": "helloworld", "result": "no_change"})).To(Equal(1.0))
+		})
+
+		It("should record the error when the pod cannot be created", func() {
+			By("Reconciling with a client that rejects pod creation")
+			watchClient, err := client.NewWithWatch(cfg, client.Options{Scheme: scheme.Scheme})
+			Expect(err).NotTo(HaveOccurred())
+			failingClient := interceptor.NewClient(watchClient, interceptor.Funcs{
+				Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+					if _, ok := obj.(*corev1.Pod); ok {
+						return goerrors.New("injected pod creation failure")
+					}
+					return c.Create(ctx, obj, opts...)
+				},
+			})
+			controllerReconciler := &HelloWorldReconciler{
+				Client: failingClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).To(MatchError(ContainSubstring("injected pod creation failure")))
+
+			By("Checking the error is recorded on the reconcile span")
+			spans := recorder.SpansNamed("Reconcile")
+			Expect(spans).To(HaveLen(1))
+			Expect(spans[0].Status.Code).To(Equal(codes.Error))
+			Expect(telemetrytest.HasErrorEvent(spans[0])).To(BeTrue())
+			_, ok := telemetrytest.Attribute(spans[0], attribute.Key("reconcile.result"))
+			Expect(ok).To(BeFalse())
+
+			By("Checking the error metrics")
+			Expect(recorder.Metric("helloworld_reconcile_total",
+				map[string]string{"controller": "helloworld", "result": "error"})).To(Equal(1.0))
+			Expect(recorder.Metric("helloworld_reconcile_errors_total",
+				map[string]string{"controller": "helloworld"})).To(Equal(1.0))
+			Expect(recorder.Metric("helloworld_pod_creation_errors_total",
+				map[string]string{"namespace": "default"})).To(Equal(1.0))
+		})
+
+		It("should correct drift on the generated pod", func() {
+			events := record.NewFakeRecorder(10)
+			controllerReconciler := &HelloWorldReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: events,
+			}
+			podName := types.NamespacedName{Name: resourceName + "-pod", Namespace: "default"}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Removing a label and the owner reference, as kubectl edit would")
+			pod := &corev1.Pod{}
+			Expect(k8sClient.Get(ctx, podName, pod)).To(Succeed())
+			delete(pod.Labels, "helloworld")
+			pod.OwnerReferences = nil
+			Expect(k8sClient.Update(ctx, pod)).To(Succeed())
+
+			By("Restoring them in place")
+			recorder.Reset()
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			restored := &corev1.Pod{}
+			Expect(k8sClient.Get(ctx, podName, restored)).To(Succeed())
+			Expect(restored.UID).To(Equal(pod.UID))
+			Expect(restored.Labels).To(HaveKeyWithValue("helloworld", resourceName))
+			Expect(metav1.GetControllerOf(restored)).NotTo(BeNil())
+			Expect(recorder.Metric("helloworld_drift_corrections_total", map[string]string{"field": "labels"})).To(Equal(1.0))
+			Expect(recorder.Metric("helloworld_drift_corrections_total", map[string]string{"field": "ownerReference"})).To(Equal(1.0))
+			Expect(events.Events).To(Receive(ContainSubstring("PodDriftCorrected")))
+
+			By("Deleting the pod when an immutable field drifted")
+			// Pod commands cannot be edited, so stand in for a pod replaced by hand
+			Expect(k8sClient.Delete(ctx, restored)).To(Succeed())
+			owner := &appsv1.HelloWorld{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, owner)).To(Succeed())
+			replaced := controllerReconciler.podForHelloWorld(ctx, owner)
+			replaced.Spec.Containers[0].Args = []string{"echo 'replaced by hand' && sleep 3600"}
+			Expect(controllerutil.SetControllerReference(owner, replaced, k8sClient.Scheme())).To(Succeed())
+			Expect(k8sClient.Create(ctx, replaced)).To(Succeed())
+
+			recorder.Reset()
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recorder.Metric("helloworld_drift_corrections_total", map[string]string{"field": "args"})).To(Equal(1.0))
+			Expect(recorder.Metric("helloworld_reconcile_total",
+				map[string]string{"controller": "helloworld", "result": "drift_corrected"})).To(Equal(1.0))
+			Expect(events.Events).To(Receive(ContainSubstring("Deleted pod")))
+		})
+
+		It("should refuse a conflicting pod until it opts in to adoption", func() {
+			controllerReconciler := &HelloWorldReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			By("Creating an unrelated pod with the generated pod's name")
+			existing := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: resourceName + "-pod", Namespace: "default"},
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{
+					Name: "busybox", Image: "busybox:latest", Command: []string{"sleep", "3600"},
+				}}},
+			}
+			Expect(k8sClient.Create(ctx, existing)).To(Succeed())
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, helloworld)).To(Succeed())
+			degraded := meta.FindStatusCondition(helloworld.Status.Conditions, appsv1.TypeDegraded)
+			Expect(degraded).NotTo(BeNil())
+			Expect(degraded.Status).To(Equal(metav1.ConditionTrue))
+			Expect(degraded.Reason).To(Equal("NameConflict"))
+			Expect(helloworld.Status.Phase).To(Equal(appsv1.PhaseFailed))
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(existing), existing)).To(Succeed())
+			Expect(existing.OwnerReferences).To(BeEmpty())
+			Expect(recorder.Metric("helloworld_reconcile_total",
+				map[string]string{"controller": "helloworld", "result": "name_conflict"})).To(Equal(1.0))
+
+			By("Adopting the pod once it has matching labels and the opt-in annotation")
+			existing.Labels = map[string]string{"app": "helloworld", "helloworld": resourceName}
+			existing.Annotations = map[string]string{appsv1.AdoptAnnotation: "true"}
+			Expect(k8sClient.Update(ctx, existing)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(existing), existing)).To(Succeed())
+			Expect(metav1.GetControllerOf(existing)).NotTo(BeNil())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, helloworld)).To(Succeed())
+			Expect(helloworld.Status.PodUID).To(Equal(existing.UID))
+			Expect(meta.IsStatusConditionFalse(helloworld.Status.Conditions, appsv1.TypeDegraded)).To(BeTrue())
+			Expect(recorder.Metric("helloworld_reconcile_total",
+				map[string]string{"controller": "helloworld", "result": "pod_adopted"})).To(Equal(1.0))
+		})
+
+		It("should generate unique pod names when asked to", func() {
+			controllerReconciler := &HelloWorldReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			generated := &appsv1.HelloWorld{
+				ObjectMeta: metav1.ObjectMeta{Name: "generated", Namespace: "default"},
+				Spec:       appsv1.HelloWorldSpec{Message: "Hello, generated!", PodNameStrategy: appsv1.PodNameGenerated},
+			}
+			Expect(k8sClient.Create(ctx, generated)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, generated)).To(Succeed())
+				Expect(k8sClient.DeleteAllOf(ctx, &corev1.Pod{}, client.InNamespace("default"),
+					client.MatchingLabels{"helloworld": "generated"})).To(Succeed())
+			})
+			request := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(generated)}
+
+			_, err := controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, request.NamespacedName, generated)).To(Succeed())
+			Expect(generated.Status.PodName).To(HavePrefix("generated-"))
+			Expect(generated.Status.PodName).NotTo(Equal("generated-pod"))
+
+			By("Finding the same pod again by label")
+			_, err = controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			pods := &corev1.PodList{}
+			Expect(k8sClient.List(ctx, pods, client.InNamespace("default"), client.MatchingLabels{"helloworld": "generated"})).To(Succeed())
+			Expect(pods.Items).To(HaveLen(1))
+			Expect(pods.Items[0].Name).To(Equal(generated.Status.PodName))
+		})
+
+		It("should wait for a referenced ConfigMap and roll out its changes", func() {
+			events := record.NewFakeRecorder(10)
+			controllerReconciler := &HelloWorldReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: events,
+			}
+			referenced := &appsv1.HelloWorld{
+				ObjectMeta: metav1.ObjectMeta{Name: "referenced", Namespace: "default"},
+				Spec: appsv1.HelloWorldSpec{MessageFrom: &appsv1.MessageSource{
+					ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "greetings"},
+						Key:                  "message",
+					},
+				}},
+			}
+			Expect(k8sClient.Create(ctx, referenced)).To(Succeed())
+			configMap := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "greetings", Namespace: "default"},
+				Data:       map[string]string{"message": "Hello from a ConfigMap!"},
+			}
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, referenced)).To(Succeed())
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, configMap))).To(Succeed())
+				Expect(k8sClient.DeleteAllOf(ctx, &corev1.Pod{}, client.InNamespace("default"),
+					client.MatchingLabels{"helloworld": "referenced"})).To(Succeed())
+			})
+			request := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(referenced)}
+			podName := types.NamespacedName{Name: "referenced-pod", Namespace: "default"}
+
+			By("Reporting the missing ConfigMap without creating a pod")
+			_, err := controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, request.NamespacedName, referenced)).To(Succeed())
+			resolved := meta.FindStatusCondition(referenced.Status.Conditions, appsv1.TypeMessageResolved)
+			Expect(resolved).NotTo(BeNil())
+			Expect(resolved.Status).To(Equal(metav1.ConditionFalse))
+			Expect(resolved.Reason).To(Equal("ConfigMapNotFound"))
+			Expect(referenced.Status.Phase).To(Equal(appsv1.PhasePending))
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, podName, &corev1.Pod{}))).To(BeTrue())
+			Expect(events.Events).To(Receive(ContainSubstring("MessageUnresolved")))
+
+			By("Passing the message by reference once the ConfigMap exists")
+			Expect(k8sClient.Create(ctx, configMap)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			pod := &corev1.Pod{}
+			Expect(k8sClient.Get(ctx, podName, pod)).To(Succeed())
+			Expect(pod.Spec.Containers[0].Args).To(Equal([]string{`echo "$MESSAGE" && sleep 3600`}))
+			Expect(pod.Spec.Containers[0].Env).To(ContainElement(HaveField("ValueFrom.ConfigMapKeyRef.Name", "greetings")))
+			Expect(k8sClient.Get(ctx, request.NamespacedName, referenced)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(referenced.Status.Conditions, appsv1.TypeMessageResolved)).To(BeTrue())
+
+			By("Replacing the pod when the ConfigMap changes")
+			configMap.Data["message"] = "Hello again!"
+			Expect(k8sClient.Update(ctx, configMap)).To(Succeed())
+			recorder.Reset()
+			_, err = controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recorder.Metric("helloworld_reconcile_total",
+				map[string]string{"controller": "helloworld", "result": "message_rollout"})).To(Equal(1.0))
+			Expect(events.Events).To(Receive(ContainSubstring("MessageChanged")))
+			Expect(k8sClient.Get(ctx, request.NamespacedName, referenced)).To(Succeed())
+			progressing := meta.FindStatusCondition(referenced.Status.Conditions, appsv1.TypeProgressing)
+			Expect(progressing).NotTo(BeNil())
+			Expect(progressing.Reason).To(Equal("RollingOut"))
+		})
+
+		It("should report template errors and store the rendered message", func() {
+			controllerReconciler := &HelloWorldReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			templated := &appsv1.HelloWorld{
+				ObjectMeta: metav1.ObjectMeta{Name: "templated", Namespace: "default"},
+				Spec:       appsv1.HelloWorldSpec{MessageTemplate: `Hello from {{printf "%s" .Namespace}}`},
+			}
+			Expect(k8sClient.Create(ctx, templated)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, templated)).To(Succeed())
+				Expect(k8sClient.DeleteAllOf(ctx, &corev1.Pod{}, client.InNamespace("default"),
+					client.MatchingLabels{"helloworld": "templated"})).To(Succeed())
+			})
+			request := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(templated)}
+
+			By("Reporting a template outside the sandbox")
+			_, err := controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, request.NamespacedName, templated)).To(Succeed())
+			resolved := meta.FindStatusCondition(templated.Status.Conditions, appsv1.TypeMessageResolved)
+			Expect(resolved).NotTo(BeNil())
+			Expect(resolved.Status).To(Equal(metav1.ConditionFalse))
+			Expect(resolved.Reason).To(Equal("TemplateInvalid"))
+
+			By("Rendering the fixed template into the pod and the status")
+			templated.Spec.MessageTemplate = "Hello from {{.Namespace}} on {{.NodeName}}"
+			Expect(k8sClient.Update(ctx, templated)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			pod := &corev1.Pod{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "templated-pod", Namespace: "default"}, pod)).To(Succeed())
+			Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{
+				Name: "MESSAGE", Value: "Hello from default on $(HELLOWORLD_NODE_NAME)",
+			}))
+			Expect(k8sClient.Get(ctx, request.NamespacedName, templated)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(templated.Status.Conditions, appsv1.TypeMessageResolved)).To(BeTrue())
+			Expect(templated.Status.RenderedMessage).To(Equal("Hello from default on "))
+		})
+
+		It("should print every locale until the namespace selects one", func() {
+			controllerReconciler := &HelloWorldReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			localized := &appsv1.HelloWorld{
+				ObjectMeta: metav1.ObjectMeta{Name: "localized", Namespace: "default"},
+				Spec: appsv1.HelloWorldSpec{
+					Messages:      map[string]string{"en": "Hello!", "de": "Hallo!", "fr": "Bonjour !"},
+					DefaultLocale: "en",
+				},
+			}
+			Expect(k8sClient.Create(ctx, localized)).To(Succeed())
+			namespace := &corev1.Namespace{}
+			Expect(k8sClient.Get(ctx, client.ObjectKey{Name: "default"}, namespace)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, localized)).To(Succeed())
+				Expect(k8sClient.DeleteAllOf(ctx, &corev1.Pod{}, client.InNamespace("default"),
+					client.MatchingLabels{"helloworld": "localized"})).To(Succeed())
+				Expect(k8sClient.Get(ctx, client.ObjectKey{Name: "default"}, namespace)).To(Succeed())
+				delete(namespace.Annotations, appsv1.LocaleAnnotation)
+				Expect(k8sClient.Update(ctx, namespace)).To(Succeed())
+			})
+			request := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(localized)}
+			podName := types.NamespacedName{Name: "localized-pod", Namespace: "default"}
+
+			By("Running a container per locale")
+			_, err := controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			pod := &corev1.Pod{}
+			Expect(k8sClient.Get(ctx, podName, pod)).To(Succeed())
+			Expect(pod.Spec.Containers).To(HaveLen(3))
+			Expect(pod.Spec.Containers[1].Name).To(Equal("busybox-en"))
+			Expect(pod.Spec.Containers[1].Env).To(ContainElement(corev1.EnvVar{Name: "MESSAGE", Value: "Hello!"}))
+			Expect(k8sClient.Get(ctx, request.NamespacedName, localized)).To(Succeed())
+			Expect(localized.Status.ActiveLocales).To(Equal([]string{"de", "en", "fr"}))
+
+			By("Selecting a locale with the namespace annotation")
+			if namespace.Annotations == nil {
+				namespace.Annotations = map[string]string{}
+			}
+			namespace.Annotations[appsv1.LocaleAnnotation] = "de-AT"
+			Expect(k8sClient.Update(ctx, namespace)).To(Succeed())
+			recorder.Reset()
+			_, err = controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recorder.Metric("helloworld_reconcile_total",
+				map[string]string{"controller": "helloworld", "result": "message_rollout"})).To(Equal(1.0))
+			Expect(k8sClient.Get(ctx, request.NamespacedName, localized)).To(Succeed())
+			Expect(localized.Status.ActiveLocales).To(Equal([]string{"de"}))
+		})
+
+		It("should serve the message behind a Service", func() {
+			controllerReconciler := &HelloWorldReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			served := &appsv1.HelloWorld{
+				ObjectMeta: metav1.ObjectMeta{Name: "served", Namespace: "default"},
+				Spec: appsv1.HelloWorldSpec{
+					Message: "Hello, $USER!",
+					Serve:   &appsv1.ServeSpec{Port: 80},
+				},
+			}
+			Expect(k8sClient.Create(ctx, served)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, served)).To(Succeed())
+				Expect(k8sClient.DeleteAllOf(ctx, &corev1.Pod{}, client.InNamespace("default"),
+					client.MatchingLabels{"helloworld": "served"})).To(Succeed())
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &corev1.Service{
+					ObjectMeta: metav1.ObjectMeta{Name: "served", Namespace: "default"},
+				}))).To(Succeed())
+				Expect(k8sClient.DeleteAllOf(ctx, &discoveryv1.EndpointSlice{}, client.InNamespace("default"),
+					client.MatchingLabels{discoveryv1.LabelServiceName: "served"})).To(Succeed())
+			})
+			request := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(served)}
+
+			By("Running the greeter with probes")
+			_, err := controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			pod := &corev1.Pod{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "served-pod", Namespace: "default"}, pod)).To(Succeed())
+			Expect(pod.Spec.Containers).To(HaveLen(1))
+			greeter := pod.Spec.Containers[0]
+			Expect(greeter.Name).To(Equal("greeter"))
+			Expect(greeter.Env).To(ContainElement(corev1.EnvVar{Name: "MESSAGE", Value: "Hello, $$USER!"}))
+			Expect(greeter.ReadinessProbe.HTTPGet.Path).To(Equal("/readyz"))
+			Expect(greeter.LivenessProbe.HTTPGet.Path).To(Equal("/healthz"))
+
+			By("Creating the Service once the pod exists")
+			_, err = controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			service := &corev1.Service{}
+			Expect(k8sClient.Get(ctx, request.NamespacedName, service)).To(Succeed())
+			Expect(service.Spec.Selector).To(Equal(pod.Labels))
+			Expect(service.Spec.Ports).To(HaveLen(1))
+			Expect(service.Spec.Ports[0].Port).To(Equal(int32(80)))
+			Expect(metav1.IsControlledBy(service, served)).To(BeTrue())
+			Expect(k8sClient.Get(ctx, request.NamespacedName, served)).To(Succeed())
+			Expect(served.Status.Endpoint).To(Equal("http://served.default.svc:80"))
+			Expect(meta.FindStatusCondition(served.Status.Conditions, appsv1.TypeServing).Reason).To(Equal("NoReadyEndpoints"))
+
+			By("Reporting Serving once an endpoint is ready")
+			ready := true
+			Expect(k8sClient.Create(ctx, &discoveryv1.EndpointSlice{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "served-abcde",
+					Namespace: "default",
+					Labels:    map[string]string{discoveryv1.LabelServiceName: "served"},
+				},
+				AddressType: discoveryv1.AddressTypeIPv4,
+				Endpoints: []discoveryv1.Endpoint{{
+					Addresses:  []string{"10.0.0.1"},
+					Conditions: discoveryv1.EndpointConditions{Ready: &ready},
+				}},
+			})).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, request.NamespacedName, served)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(served.Status.Conditions, appsv1.TypeServing)).To(BeTrue())
+		})
+
+		It("should expose a served HelloWorld with an Ingress without the Gateway API", func() {
+			controllerReconciler := &HelloWorldReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			routed := &appsv1.HelloWorld{
+				ObjectMeta: metav1.ObjectMeta{Name: "routed", Namespace: "default"},
+				Spec: appsv1.HelloWorldSpec{
+					Message: "Hello",
+					Serve:   &appsv1.ServeSpec{Port: 80},
+					Route: &appsv1.RouteSpec{
+						Hostnames:  []string{"hello.example.com", "*.hello.example.com"},
+						GatewayRef: &appsv1.GatewayReference{Name: "public"},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, routed)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, routed))).To(Succeed())
+				Expect(k8sClient.DeleteAllOf(ctx, &corev1.Pod{}, client.InNamespace("default"),
+					client.MatchingLabels{"helloworld": "routed"})).To(Succeed())
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &corev1.Service{
+					ObjectMeta: metav1.ObjectMeta{Name: "routed", Namespace: "default"},
+				}))).To(Succeed())
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &networkingv1.Ingress{
+					ObjectMeta: metav1.ObjectMeta{Name: "routed", Namespace: "default"},
+				}))).To(Succeed())
+			})
+			request := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(routed)}
+
+			By("Creating an Ingress for every hostname once the pod exists")
+			for range 2 {
+				_, err := controllerReconciler.Reconcile(ctx, request)
+				Expect(err).NotTo(HaveOccurred())
+			}
+			ingress := &networkingv1.Ingress{}
+			Expect(k8sClient.Get(ctx, request.NamespacedName, ingress)).To(Succeed())
+			Expect(ingress.Spec.Rules).To(HaveLen(2))
+			Expect(ingress.Spec.Rules[1].Host).To(Equal("*.hello.example.com"))
+			paths := ingress.Spec.Rules[0].HTTP.Paths
+			Expect(paths).To(HaveLen(1))
+			Expect(paths[0].Path).To(Equal("/"))
+			Expect(paths[0].Backend.Service.Name).To(Equal("routed"))
+			Expect(paths[0].Backend.Service.Port.Number).To(Equal(int32(80)))
+
+			By("Deleting the Ingress when the route is removed")
+			Expect(k8sClient.Get(ctx, request.NamespacedName, routed)).To(Succeed())
+			Expect(routed.Status.RouteConditions).To(BeEmpty())
+			routed.Spec.Route = nil
+			Expect(k8sClient.Update(ctx, routed)).To(Succeed())
+			_, err := controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, request.NamespacedName, ingress))).To(BeTrue())
+		})
+
+		It("should deliver the message to a webhook", func() {
+			received := make(chan *http.Request, 1)
+			bodies := make(chan []byte, 1)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				received <- r
+				bodies <- body
+			}))
+			DeferCleanup(server.Close)
+
+			controllerReconciler := &HelloWorldReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			controllerReconciler.Deliveries = delivery.NewDispatcher(delivery.Options{
+				Build:          controllerReconciler.BuildDelivery,
+				Client:         server.Client(),
+				InitialBackoff: time.Second,
+				MaxBackoff:     time.Second,
+				MaxAttempts:    3,
+			}, logr.Discard())
+			dispatcherCtx, stopDispatcher := context.WithCancel(ctx)
+			DeferCleanup(stopDispatcher)
+			go func() {
+				defer GinkgoRecover()
+				Expect(controllerReconciler.Deliveries.Start(dispatcherCtx)).To(Succeed())
+			}()
+
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "webhook", Namespace: "default",
+					Labels: map[string]string{appsv1.DeliverySecretLabel: "true"}},
+				Data: map[string][]byte{"Authorization": []byte("Bearer token"), "signing-key": []byte("secret")},
+			}
+			Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+			delivered := &appsv1.HelloWorld{
+				ObjectMeta: metav1.ObjectMeta{Name: "delivered", Namespace: "default"},
+				Spec: appsv1.HelloWorldSpec{
+					Message: "Hello, webhook!",
+					Deliveries: []appsv1.Delivery{{
+						Name:        "audit",
+						URL:         server.URL + "/hooks/hello",
+						HeadersFrom: &corev1.LocalObjectReference{Name: "webhook"},
+						SigningKeyRef: &corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{Name: "webhook"},
+							Key:                  "signing-key",
+						},
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, delivered)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, delivered)).To(Succeed())
+				Expect(k8sClient.Delete(ctx, secret)).To(Succeed())
+				Expect(k8sClient.DeleteAllOf(ctx, &corev1.Pod{}, client.InNamespace("default"),
+					client.MatchingLabels{"helloworld": "delivered"})).To(Succeed())
+			})
+			request := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(delivered)}
+
+			By("Queueing the delivery")
+			_, err := controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, request.NamespacedName, delivered)).To(Succeed())
+			Expect(delivered.Status.Deliveries).To(HaveLen(1))
+			Expect(delivered.Status.Deliveries[0].State).To(Equal(appsv1.DeliveryPending))
+
+			By("Posting the signed message with an idempotency key")
+			var req *http.Request
+			Eventually(received, 5*time.Second).Should(Receive(&req))
+			body := <-bodies
+			Expect(req.URL.Path).To(Equal("/hooks/hello"))
+			Expect(req.Header.Get("Authorization")).To(Equal("Bearer token"))
+			Expect(req.Header.Get(delivery.IdempotencyKeyHeader)).To(Equal(fmt.Sprintf("%s-%d", delivered.UID, delivered.Generation)))
+			timestamp := req.Header.Get(delivery.TimestampHeader)
+			Expect(timestamp).NotTo(BeEmpty())
+			Expect(req.Header.Get(delivery.SignatureHeader)).To(Equal(delivery.Sign([]byte("secret"), timestamp, body)))
+			Expect(body).To(MatchJSON(fmt.Sprintf(
+				`{"apiVersion":"apps.example.com/v1","kind":"HelloWorld","namespace":"default","name":"delivered","uid":%q,"generation":%d,"message":"Hello, webhook!"}`,
+				delivered.UID, delivered.Generation)))
+
+			By("Reporting the delivery once it was attempted")
+			Eventually(controllerReconciler.Deliveries.Attempted(), 5*time.Second).Should(Receive(Equal(request.NamespacedName)))
+			_, err = controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, request.NamespacedName, delivered)).To(Succeed())
+			status := delivered.Status.Deliveries[0]
+			Expect(status.State).To(Equal(appsv1.DeliveryDelivered))
+			Expect(status.Attempts).To(Equal(int32(1)))
+			Expect(status.LastStatusCode).To(Equal(int32(http.StatusOK)))
+			Expect(status.ObservedGeneration).To(Equal(delivered.Generation))
+		})
+
+		It("should only read delivery Secrets that opt in and never deliver a Secret message", func() {
+			controllerReconciler := &HelloWorldReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "unlabeled", Namespace: "default"},
+				Data:       map[string][]byte{"Authorization": []byte("Bearer token"), "message": []byte("Hello, secret!")},
+			}
+			Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+			guarded := &appsv1.HelloWorld{
+				ObjectMeta: metav1.ObjectMeta{Name: "guarded", Namespace: "default"},
+				Spec: appsv1.HelloWorldSpec{
+					MessageFrom: &appsv1.MessageSource{SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "unlabeled"},
+						Key:                  "message",
+					}},
+					Deliveries: []appsv1.Delivery{{
+						Name:        "audit",
+						URL:         "https://hooks.example.com/hello",
+						HeadersFrom: &corev1.LocalObjectReference{Name: "unlabeled"},
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, guarded)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, guarded)).To(Succeed())
+				Expect(k8sClient.Delete(ctx, secret)).To(Succeed())
+			})
+			entry := delivery.Entry{Namespace: "default", Name: "guarded", UID: guarded.UID,
+				Generation: guarded.Generation, Target: "audit"}
+
+			By("Refusing a Secret without the delivery label")
+			_, err := controllerReconciler.BuildDelivery(ctx, entry)
+			Expect(err).To(MatchError(ContainSubstring(appsv1.DeliverySecretLabel + "=true")))
+
+			By("Sending its headers once it is labeled, without the message read from a Secret")
+			secret.Labels = map[string]string{appsv1.DeliverySecretLabel: "true"}
+			Expect(k8sClient.Update(ctx, secret)).To(Succeed())
+			deliveryRequest, err := controllerReconciler.BuildDelivery(ctx, entry)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deliveryRequest.Headers.Get("Authorization")).To(Equal("Bearer token"))
+			Expect(string(deliveryRequest.Body)).NotTo(ContainSubstring("Hello, secret!"))
+			Expect(string(deliveryRequest.Body)).NotTo(ContainSubstring(`"message"`))
+		})
+
+		It("should publish CloudEvents on phase and condition transitions", func() {
+			events := make(chan cloudevents.Event, 20)
+			sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				Expect(r.Header.Get("Content-Type")).To(Equal(cloudevents.ContentType))
+				var event cloudevents.Event
+				Expect(json.NewDecoder(r.Body).Decode(&event)).To(Succeed())
+				events <- event
+				w.WriteHeader(http.StatusAccepted)
+			}))
+			DeferCleanup(sink.Close)
+			emitter := cloudevents.NewEmitter(cloudevents.Options{Sink: sink.URL, Client: sink.Client()}, logr.Discard())
+			emitterCtx, stopEmitter := context.WithCancel(ctx)
+			DeferCleanup(stopEmitter)
+			go func() { _ = emitter.Start(emitterCtx) }()
+
+			controllerReconciler := &HelloWorldReconciler{
+				Client:      k8sClient,
+				Scheme:      k8sClient.Scheme(),
+				CloudEvents: emitter,
+			}
+			request := reconcile.Request{NamespacedName: typeNamespacedName}
+			received := func() map[string]cloudevents.Event {
+				byID := map[string]cloudevents.Event{}
+				for {
+					select {
+					case event := <-events:
+						byID[event.Type+"/"+event.ID[strings.LastIndex(event.ID, "-")+1:]] = event
+					case <-time.After(200 * time.Millisecond):
+						return byID
+					}
+				}
+			}
+
+			By("Publishing the first phase and conditions")
+			_, err := controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			published := received()
+			phase, ok := published[PhaseChangedEventType+"/phase"]
+			Expect(ok).To(BeTrue(), "expected a phase event, got %v", published)
+			Expect(phase.Source).To(Equal("/apis/apps.example.com/v1/namespaces/default/helloworlds"))
+			Expect(phase.Subject).To(Equal(resourceName))
+			Expect(phase.TraceParent).To(MatchRegexp(`^00-[0-9a-f]{32}-[0-9a-f]{16}-0[01]$`))
+			Expect(phase.Data).To(HaveKeyWithValue("phase", appsv1.PhaseRunning))
+			Expect(published).To(HaveKey(ConditionChangedEventType + "/" + appsv1.TypeReady))
+
+			By("Publishing only what changed")
+			pod := &corev1.Pod{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-pod", Namespace: "default"}, pod)).To(Succeed())
+			pod.Status.Phase = corev1.PodRunning
+			Expect(k8sClient.Status().Update(ctx, pod)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			published = received()
+			Expect(published).To(HaveLen(2), "expected Ready and Progressing to change, got %v", published)
+			ready := published[ConditionChangedEventType+"/"+appsv1.TypeReady]
+			Expect(ready.Data).To(HaveKeyWithValue("status", "True"))
+			Expect(ready.Data).To(HaveKeyWithValue("previousStatus", "False"))
+			Expect(published).To(HaveKey(ConditionChangedEventType + "/" + appsv1.TypeProgressing))
+		})
+
+		It("should record revisions and roll back to an earlier one", func() {
+			events := record.NewFakeRecorder(10)
+			controllerReconciler := &HelloWorldReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: events,
+			}
+			request := reconcile.Request{NamespacedName: typeNamespacedName}
+			reconcileAndGet := func() {
+				GinkgoHelper()
+				_, err := controllerReconciler.Reconcile(ctx, request)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(k8sClient.Get(ctx, typeNamespacedName, helloworld)).To(Succeed())
+			}
+			revisions := func() map[string]int64 {
+				GinkgoHelper()
+				list := &k8sappsv1.ControllerRevisionList{}
+				Expect(k8sClient.List(ctx, list, client.InNamespace("default"),
+					client.MatchingLabels{"helloworld": resourceName})).To(Succeed())
+				numbers := map[string]int64{}
+				for _, revision := range list.Items {
+					if metav1.IsControlledBy(&revision, helloworld) {
+						numbers[revision.Name] = revision.Revision
+					}
+				}
+				return numbers
+			}
+
+			By("Recording the first revision")
+			reconcileAndGet()
+			first := helloworld.Status.UpdateRevision
+			Expect(revisions()).To(Equal(map[string]int64{first: 1}))
+			Expect(helloworld.Status.CurrentRevision).To(BeEmpty())
+
+			By("Making it current once the pod runs")
+			pod := &corev1.Pod{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-pod", Namespace: "default"}, pod)).To(Succeed())
+			pod.Status.Phase = corev1.PodRunning
+			Expect(k8sClient.Status().Update(ctx, pod)).To(Succeed())
+			reconcileAndGet()
+			Expect(helloworld.Status.CurrentRevision).To(Equal(first))
+
+			By("Recording a new revision when the message changes")
+			helloworld.Spec.Message = "Hello again!"
+			Expect(k8sClient.Update(ctx, helloworld)).To(Succeed())
+			reconcileAndGet()
+			second := helloworld.Status.UpdateRevision
+			Expect(revisions()).To(Equal(map[string]int64{first: 1, second: 2}))
+			Expect(helloworld.Status.CurrentRevision).To(Equal(first))
+
+			By("Rolling back to the previous revision")
+			helloworld.Spec.RollbackTo = &appsv1.RollbackConfig{}
+			Expect(k8sClient.Update(ctx, helloworld)).To(Succeed())
+			reconcileAndGet()
+			Expect(helloworld.Spec.Message).To(Equal("Hello, test!"))
+			Expect(helloworld.Spec.RollbackTo).To(BeNil())
+			Eventually(events.Events).Should(Receive(ContainSubstring("Rolled back to revision 1")))
+			reconcileAndGet()
+			Expect(helloworld.Status.UpdateRevision).To(Equal(first))
+			Expect(revisions()).To(Equal(map[string]int64{first: 3, second: 2}))
+
+			By("Reporting a revision that does not exist")
+			helloworld.Spec.RollbackTo = &appsv1.RollbackConfig{Revision: 7}
+			Expect(k8sClient.Update(ctx, helloworld)).To(Succeed())
+			reconcileAndGet()
+			Expect(helloworld.Spec.RollbackTo).To(BeNil())
+			Expect(helloworld.Spec.Message).To(Equal("Hello, test!"))
+			Eventually(events.Events).Should(Receive(ContainSubstring("RollbackRevisionNotFound")))
+
+			By("Deleting revisions beyond the history limit")
+			helloworld.Spec.RevisionHistoryLimit = ptr.To[int32](0)
+			Expect(k8sClient.Update(ctx, helloworld)).To(Succeed())
+			reconcileAndGet()
+			Expect(revisions()).To(Equal(map[string]int64{first: 3}))
+		})
+
+		It("should hold new messages until their generation is approved", func() {
+			events := record.NewFakeRecorder(10)
+			controllerReconciler := &HelloWorldReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: events,
+			}
+			request := reconcile.Request{NamespacedName: typeNamespacedName}
+			podName := types.NamespacedName{Name: resourceName + "-pod", Namespace: "default"}
+			reconcileAndGet := func() {
+				GinkgoHelper()
+				_, err := controllerReconciler.Reconcile(ctx, request)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(k8sClient.Get(ctx, typeNamespacedName, helloworld)).To(Succeed())
+			}
+			approve := func(approver string) {
+				GinkgoHelper()
+				helloworld.Annotations = map[string]string{
+					appsv1.ApproveGenerationAnnotation: fmt.Sprint(helloworld.Generation),
+					appsv1.ApprovedByAnnotation:        approver,
+				}
+				Expect(k8sClient.Update(ctx, helloworld)).To(Succeed())
+			}
+
+			By("Holding the first message without creating a pod")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, helloworld)).To(Succeed())
+			helloworld.Spec.ApprovalPolicy = appsv1.ApprovalRequired
+			Expect(k8sClient.Update(ctx, helloworld)).To(Succeed())
+			reconcileAndGet()
+			Expect(helloworld.Status.Phase).To(Equal(appsv1.PhaseAwaitingApproval))
+			Expect(helloworld.Status.Message).To(ContainSubstring(appsv1.ApproveGenerationAnnotation))
+			Expect(meta.IsStatusConditionFalse(helloworld.Status.Conditions, appsv1.TypeReady)).To(BeTrue())
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, podName, &corev1.Pod{}))).To(BeTrue())
+			Eventually(events.Events).Should(Receive(ContainSubstring("AwaitingApproval")))
+
+			By("Creating the pod once the generation is approved, without trusting the approver")
+			approve("alice")
+			reconcileAndGet()
+			Expect(k8sClient.Get(ctx, podName, &corev1.Pod{})).To(Succeed())
+			Expect(helloworld.Status.Approval).NotTo(BeNil())
+			Expect(helloworld.Status.Approval.Generation).To(Equal(helloworld.Generation))
+			Expect(helloworld.Status.Approval.Approver).To(BeEmpty())
+			Eventually(events.Events).Should(Receive(ContainSubstring("approver is not recorded")))
+
+			By("Keeping the pod with the approved message when the message changes")
+			helloworld.Spec.Message = "Hello, approver!"
+			Expect(k8sClient.Update(ctx, helloworld)).To(Succeed())
+			reconcileAndGet()
+			Expect(helloworld.Status.Phase).To(Equal(appsv1.PhaseAwaitingApproval))
+			Expect(helloworld.Status.PodName).To(Equal(podName.Name))
+			Expect(k8sClient.Get(ctx, podName, &corev1.Pod{})).To(Succeed())
+			progressing := meta.FindStatusCondition(helloworld.Status.Conditions, appsv1.TypeProgressing)
+			Expect(progressing).NotTo(BeNil())
+			Expect(progressing.Reason).To(Equal("AwaitingApproval"))
+
+			By("Rolling the message out once its generation is approved, recording the approver the webhook stamped")
+			controllerReconciler.ApprovalWebhook = true
+			approve("bob")
+			recorder.Reset()
+			reconcileAndGet()
+			Expect(recorder.Metric("helloworld_reconcile_total",
+				map[string]string{"controller": "helloworld", "result": "message_rollout"})).To(Equal(1.0))
+			Expect(helloworld.Status.Approval.Approver).To(Equal("bob"))
+			Eventually(events.Events).Should(Receive(ContainSubstring("approved by bob")))
+
+			By("Holding a message that changes without a new generation until its hash is approved")
+			configMap := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "approved-greeting", Namespace: "default"},
+				Data:       map[string]string{"message": "Hello, configmap!"},
+			}
+			Expect(k8sClient.Create(ctx, configMap)).To(Succeed())
+			DeferCleanup(func() { Expect(k8sClient.Delete(ctx, configMap)).To(Succeed()) })
+			helloworld.Spec.Message = ""
+			helloworld.Spec.MessageFrom = &appsv1.MessageSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "approved-greeting"},
+				Key:                  "message",
+			}}
+			Expect(k8sClient.Update(ctx, helloworld)).To(Succeed())
+			approve("carol")
+			reconcileAndGet()
+			Expect(helloworld.Status.Approval.Generation).To(Equal(helloworld.Generation))
+			configMap.Data["message"] = "Hello, unapproved!"
+			Expect(k8sClient.Update(ctx, configMap)).To(Succeed())
+			reconcileAndGet()
+			Expect(helloworld.Status.Phase).To(Equal(appsv1.PhaseAwaitingApproval))
+			messageHash, _, err := controllerReconciler.resolveMessage(ctx, helloworld)
+			Expect(err).NotTo(HaveOccurred())
+			value := fmt.Sprintf("%d/%s", helloworld.Generation, messageHash)
+			Expect(helloworld.Status.Message).To(ContainSubstring(value))
+			helloworld.Annotations[appsv1.ApproveGenerationAnnotation] = value
+			Expect(k8sClient.Update(ctx, helloworld)).To(Succeed())
+			reconcileAndGet()
+			Expect(helloworld.Status.Phase).NotTo(Equal(appsv1.PhaseAwaitingApproval))
+			Expect(helloworld.Status.Approval.MessageHash).To(Equal(messageHash))
+		})
+
+		It("should roll out a new revision to the replicas in canary steps", func() {
+			events := record.NewFakeRecorder(50)
+			controllerReconciler := &HelloWorldReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: events,
+			}
+			canary := &appsv1.HelloWorld{
+				ObjectMeta: metav1.ObjectMeta{Name: "canary", Namespace: "default"},
+				Spec: appsv1.HelloWorldSpec{
+					Message:  "Hello, v1!",
+					Replicas: ptr.To[int32](4),
+					Rollout: &appsv1.RolloutStrategy{Steps: []appsv1.RolloutStep{
+						{SetWeight: ptr.To[int32](25)},
+						{Pause: &appsv1.RolloutPause{}},
+						{SetWeight: ptr.To[int32](50)},
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, canary)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, canary)).To(Succeed())
+				Expect(k8sClient.DeleteAllOf(ctx, &corev1.Pod{}, client.InNamespace("default"),
+					client.MatchingLabels{"helloworld": "canary"})).To(Succeed())
+			})
+			request := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(canary)}
+			reconcileAndGet := func() {
+				GinkgoHelper()
+				_, err := controllerReconciler.Reconcile(ctx, request)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(k8sClient.Get(ctx, request.NamespacedName, canary)).To(Succeed())
+			}
+			replicas := func() map[string]int {
+				GinkgoHelper()
+				pods := &corev1.PodList{}
+				Expect(k8sClient.List(ctx, pods, client.InNamespace("default"), client.MatchingLabels{"helloworld": "canary"})).To(Succeed())
+				counts := map[string]int{}
+				for _, pod := range pods.Items {
+					counts[pod.Labels[k8sappsv1.ControllerRevisionHashLabelKey]]++
+				}
+				return counts
+			}
+			setPhase := func(revision string, phase corev1.PodPhase) {
+				GinkgoHelper()
+				pods := &corev1.PodList{}
+				Expect(k8sClient.List(ctx, pods, client.InNamespace("default"), client.MatchingLabels{
+					"helloworld": "canary", k8sappsv1.ControllerRevisionHashLabelKey: revision})).To(Succeed())
+				for i := range pods.Items {
+					pods.Items[i].Status.Phase = phase
+					Expect(k8sClient.Status().Update(ctx, &pods.Items[i])).To(Succeed())
+				}
+			}
+			updateSpec := func(update func(*appsv1.HelloWorld)) {
+				GinkgoHelper()
+				update(canary)
+				Expect(k8sClient.Update(ctx, canary)).To(Succeed())
+			}
+
+			By("Running every replica of the first revision at once")
+			reconcileAndGet()
+			first := canary.Status.UpdateRevision
+			Expect(replicas()).To(Equal(map[string]int{first: 4}))
+			Expect(canary.Status.CurrentRevision).To(Equal(first))
+			Expect(canary.Status.Rollout.Phase).To(Equal(appsv1.RolloutCompleted))
+			Expect(canary.Status.PodName).To(BeEmpty())
+			setPhase(first, corev1.PodRunning)
+			reconcileAndGet()
+			Expect(canary.Status.Phase).To(Equal(appsv1.PhaseRunning))
+			Expect(canary.Status.ReadyReplicas).To(Equal(int32(4)))
+			Expect(meta.IsStatusConditionTrue(canary.Status.Conditions, appsv1.TypeReady)).To(BeTrue())
+
+			By("Shifting a quarter of the replicas to a new message")
+			updateSpec(func(hw *appsv1.HelloWorld) { hw.Spec.Message = "Hello, v2!" })
+			reconcileAndGet()
+			second := canary.Status.UpdateRevision
+			Expect(replicas()).To(Equal(map[string]int{first: 3, second: 1}))
+			Expect(canary.Status.Rollout.Phase).To(Equal(appsv1.RolloutProgressing))
+			Expect(canary.Status.Rollout.StableRevision).To(Equal(first))
+			Expect(canary.Status.Rollout.Weight).To(Equal(int32(25)))
+			Expect(canary.Status.UpdatedReplicas).To(Equal(int32(1)))
+			Eventually(events.Events).Should(Receive(ContainSubstring("RolloutStarted")))
+
+			By("Pausing until the rollout is promoted")
+			setPhase(second, corev1.PodRunning)
+			reconcileAndGet()
+			reconcileAndGet()
+			Expect(canary.Status.Rollout.Phase).To(Equal(appsv1.RolloutPaused))
+			Expect(canary.Status.Rollout.CurrentStepIndex).To(Equal(int32(1)))
+			Expect(canary.Status.Message).To(ContainSubstring(appsv1.PromoteAnnotation))
+			Expect(replicas()).To(Equal(map[string]int{first: 3, second: 1}))
+
+			By("Skipping the remaining steps once promoted")
+			updateSpec(func(hw *appsv1.HelloWorld) {
+				hw.Annotations = map[string]string{appsv1.PromoteAnnotation: second}
+			})
+			reconcileAndGet()
+			Expect(replicas()).To(Equal(map[string]int{second: 4}))
+			Eventually(events.Events).Should(Receive(ContainSubstring("RolloutPromoted")))
+			setPhase(second, corev1.PodRunning)
+			reconcileAndGet()
+			Expect(canary.Status.Rollout.Phase).To(Equal(appsv1.RolloutCompleted))
+			Expect(canary.Status.CurrentRevision).To(Equal(second))
+			Eventually(events.Events).Should(Receive(ContainSubstring("RolloutCompleted")))
+
+			By("Aborting to the stable revision when the canary fails")
+			updateSpec(func(hw *appsv1.HelloWorld) { hw.Spec.Message = "Hello, v3!" })
+			reconcileAndGet()
+			third := canary.Status.UpdateRevision
+			Expect(replicas()).To(Equal(map[string]int{second: 3, third: 1}))
+			setPhase(third, corev1.PodFailed)
+			recorder.Reset()
+			reconcileAndGet()
+			Expect(recorder.Metric("helloworld_reconcile_total",
+				map[string]string{"controller": "helloworld", "result": "rollout_aborted"})).To(Equal(1.0))
+			Expect(canary.Status.Rollout.Phase).To(Equal(appsv1.RolloutAborted))
+			Expect(canary.Status.Rollout.Weight).To(BeZero())
+			Expect(canary.Status.CurrentRevision).To(Equal(second))
+			Expect(meta.IsStatusConditionTrue(canary.Status.Conditions, appsv1.TypeDegraded)).To(BeTrue())
+			progressing := meta.FindStatusCondition(canary.Status.Conditions, appsv1.TypeProgressing)
+			Expect(progressing).NotTo(BeNil())
+			Expect(progressing.Reason).To(Equal("RolloutAborted"))
+			Expect(replicas()).To(Equal(map[string]int{second: 4}))
+			Eventually(events.Events).Should(Receive(ContainSubstring("RolloutAborted")))
+
+			By("Going back to a single pod")
+			updateSpec(func(hw *appsv1.HelloWorld) { hw.Spec.Replicas, hw.Spec.Rollout = nil, nil })
+			reconcileAndGet()
+			Expect(replicas()).To(BeEmpty())
+			Expect(canary.Status.Replicas).To(BeZero())
+			Expect(canary.Status.Rollout).To(BeNil())
+			reconcileAndGet()
+			Expect(canary.Status.PodName).To(Equal("canary-pod"))
+		})
+
+		It("should record a deleted resource without error", func() {
+			controllerReconciler := &HelloWorldReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: "does-not-exist", Namespace: "default"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			spans := recorder.SpansNamed("Reconcile")
+			Expect(spans).To(HaveLen(1))
+			result, _ := telemetrytest.Attribute(spans[0], "reconcile.result")
+			Expect(result.AsString()).To(Equal("resource_deleted"))
+			Expect(recorder.Metric("helloworld_reconcile_total",
+				map[string]string{"controller": "helloworld", "result": "resource_deleted"})).To(Equal(1.0))
 		})
 	})
 })
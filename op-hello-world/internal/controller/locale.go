@@ -0,0 +1,163 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/language"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	appsv1 "github.com/example/op-hello-world/api/v1"
+)
+
+// Reasons of a false MessageResolved condition for spec.messages
+const (
+	reasonInvalidLocale     = "InvalidLocale"
+	reasonLocaleUnavailable = "LocaleUnavailable"
+)
+
+// localeEnvVar is the variable a localized pod container gets its locale in
+const localeEnvVar = "HELLOWORLD_LOCALE"
+
+// localizedMessage is a message of spec.messages with its canonical locale
+type localizedMessage struct {
+	Locale  string `json:"locale"`
+	Message string `json:"message"`
+}
+
+// selectLocales returns the messages of helloworld's spec.messages the pod
+// prints, sorted by locale: the one for the locale selected by spec.locale or
+// the namespace's LocaleAnnotation, or else all of them. The error is an
+// *unresolvedMessageError when a locale tag is invalid.
+func (r *HelloWorldReconciler) selectLocales(ctx context.Context, helloworld *appsv1.HelloWorld) ([]localizedMessage, error) {
+	messages := make(map[string]string, len(helloworld.Spec.Messages))
+	for key, message := range helloworld.Spec.Messages {
+		tag, err := language.Parse(key)
+		if err != nil {
+			return nil, &unresolvedMessageError{reasonInvalidLocale, fmt.Sprintf("Locale %q in spec.messages is not a valid BCP 47 tag", key)}
+		}
+		if _, duplicate := messages[tag.String()]; duplicate {
+			return nil, &unresolvedMessageError{reasonInvalidLocale, fmt.Sprintf("spec.messages has more than one message for locale %s", tag)}
+		}
+		messages[tag.String()] = message
+	}
+
+	requested, err := r.requestedLocale(ctx, helloworld)
+	if err != nil {
+		return nil, err
+	}
+	if requested == "" {
+		localized := make([]localizedMessage, 0, len(messages))
+		for locale, message := range messages {
+			localized = append(localized, localizedMessage{Locale: locale, Message: message})
+		}
+		sort.Slice(localized, func(i, j int) bool { return localized[i].Locale < localized[j].Locale })
+		return localized, nil
+	}
+
+	tag, err := language.Parse(requested)
+	if err != nil {
+		return nil, &unresolvedMessageError{reasonInvalidLocale, fmt.Sprintf("Requested locale %q is not a valid BCP 47 tag", requested)}
+	}
+	chain := localeFallbacks(tag)
+	if defaultTag, err := language.Parse(helloworld.Spec.DefaultLocale); err == nil {
+		chain = append(chain, defaultTag.String())
+	}
+	for _, locale := range chain {
+		if message, ok := messages[locale]; ok {
+			return []localizedMessage{{Locale: locale, Message: message}}, nil
+		}
+	}
+	return nil, &unresolvedMessageError{reasonLocaleUnavailable,
+		fmt.Sprintf("No message for locale %s, tried %s", tag, strings.Join(chain, ", "))}
+}
+
+// requestedLocale returns the locale set in spec.locale or, failing that, in the
+// namespace's LocaleAnnotation. Namespaces are cluster-scoped, so the annotation
+// is only read when the manager watches every namespace.
+func (r *HelloWorldReconciler) requestedLocale(ctx context.Context, helloworld *appsv1.HelloWorld) (string, error) {
+	if helloworld.Spec.Locale != "" || len(r.config().Watch.Namespaces) > 0 {
+		return helloworld.Spec.Locale, nil
+	}
+	namespace := &corev1.Namespace{}
+	err := r.Get(ctx, client.ObjectKey{Name: helloworld.Namespace}, namespace)
+	if errors.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get namespace %s: %w", helloworld.Namespace, err)
+	}
+	return namespace.Annotations[appsv1.LocaleAnnotation], nil
+}
+
+// localeFallbacks returns tag followed by its parents, e.g. de-CH then de
+func localeFallbacks(tag language.Tag) []string {
+	var chain []string
+	for ; !tag.IsRoot(); tag = tag.Parent() {
+		chain = append(chain, tag.String())
+	}
+	return chain
+}
+
+// localeContainerName returns the name of the container printing the message
+// for locale
+func localeContainerName(locale string) string {
+	return "busybox-" + strings.ToLower(locale)
+}
+
+// localizedContainers returns a copy of base for each localized message, named
+// after its locale and passed the message and locale in environment variables
+func localizedContainers(base corev1.Container, localized []localizedMessage) []corev1.Container {
+	containers := make([]corev1.Container, 0, len(localized))
+	for _, message := range localized {
+		container := *base.DeepCopy()
+		container.Name = localeContainerName(message.Locale)
+		container.Args = []string{fmt.Sprintf(`echo "$%s" && sleep 3600`, messageEnvVar)}
+		container.Env = append(container.Env,
+			corev1.EnvVar{Name: localeEnvVar, Value: message.Locale},
+			// $ is escaped so the kubelet does not expand $(VAR) references
+			corev1.EnvVar{Name: messageEnvVar, Value: strings.ReplaceAll(message.Message, "$", "$$")},
+		)
+		containers = append(containers, container)
+	}
+	return containers
+}
+
+// helloWorldsForNamespace maps a namespace to the HelloWorlds in it with
+// localized messages, whose locale its annotation may select
+func (r *HelloWorldReconciler) helloWorldsForNamespace(ctx context.Context, namespace client.Object) []reconcile.Request {
+	list := &appsv1.HelloWorldList{}
+	if err := r.List(ctx, list, client.InNamespace(namespace.GetName())); err != nil {
+		logf.FromContext(ctx).Error(err, "Failed to list HelloWorlds for namespace", "namespace", namespace.GetName())
+		return nil
+	}
+	var requests []reconcile.Request
+	for i := range list.Items {
+		if len(list.Items[i].Spec.Messages) > 0 && list.Items[i].Spec.Locale == "" {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&list.Items[i])})
+		}
+	}
+	return requests
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	goerrors "errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	appsv1 "github.com/example/op-hello-world/api/v1"
+)
+
+var _ = Describe("selectLocales", func() {
+	// spec.locale is set in every entry, so the namespace is never read
+	DescribeTable("selecting a locale with fallbacks",
+		func(messages map[string]string, locale, wantLocale, wantReason string) {
+			helloworld := &appsv1.HelloWorld{
+				ObjectMeta: metav1.ObjectMeta{Name: "localized", Namespace: "default"},
+				Spec:       appsv1.HelloWorldSpec{Messages: messages, DefaultLocale: "en", Locale: locale},
+			}
+			localized, err := (&HelloWorldReconciler{}).selectLocales(context.Background(), helloworld)
+			if wantReason != "" {
+				var unresolved *unresolvedMessageError
+				Expect(goerrors.As(err, &unresolved)).To(BeTrue(), "expected an unresolved message, got %v", err)
+				Expect(unresolved.reason).To(Equal(wantReason))
+				return
+			}
+			Expect(err).NotTo(HaveOccurred())
+			Expect(localized).To(HaveLen(1))
+			Expect(localized[0].Locale).To(Equal(wantLocale))
+			Expect(localized[0].Message).To(Equal(messages[wantLocale]))
+		},
+		Entry("an exact match", map[string]string{"en": "Hello", "de-CH": "Grüezi"}, "de-CH", "de-CH", ""),
+		Entry("the parent of a regional locale", map[string]string{"en": "Hello", "de": "Hallo"}, "de-AT", "de", ""),
+		Entry("the parent of a script locale", map[string]string{"en": "Hello", "zh-Hant": "你好"}, "zh-Hant-TW", "zh-Hant", ""),
+		Entry("the default locale", map[string]string{"en": "Hello", "de": "Hallo"}, "fr-CA", "en", ""),
+		Entry("tags in any case", map[string]string{"en": "Hello", "pt-BR": "Olá"}, "pt-br", "pt-BR", ""),
+		Entry("an invalid requested tag", map[string]string{"en": "Hello"}, "not a locale", "", reasonInvalidLocale),
+		Entry("an invalid tag in messages", map[string]string{"en": "Hello", "english": "Hello"}, "en", "", reasonInvalidLocale),
+		Entry("two tags for the same locale", map[string]string{"en": "Hello", "en-us": "Hi", "en-US": "Hey"}, "en", "", reasonInvalidLocale),
+	)
+})
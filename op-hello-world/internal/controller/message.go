@@ -0,0 +1,232 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appsv1 "github.com/example/op-hello-world/api/v1"
+)
+
+// MessageHashAnnotation records a hash of the message a pod was created with. A
+// pod whose hash differs from the desired one is replaced, rolling out the new
+// message; pods without it are left alone.
+const MessageHashAnnotation = "apps.example.com/message-hash"
+
+// messageEnvVar is the variable a message read from spec.messageFrom is passed in
+const messageEnvVar = "MESSAGE"
+
+// messageRefIndex indexes HelloWorlds by the ConfigMap or Secret their message
+// is read from, as "ConfigMap/<name>" or "Secret/<name>"
+const messageRefIndex = "spec.messageFrom.ref"
+
+// Reasons of a false MessageResolved condition
+const (
+	reasonConfigMapNotFound    = "ConfigMapNotFound"
+	reasonSecretNotFound       = "SecretNotFound"
+	reasonKeyNotFound          = "KeyNotFound"
+	reasonUnsupportedFieldPath = "UnsupportedFieldPath"
+)
+
+// supportedFieldPaths are the pod fields a message can be read from with
+// fieldRef, besides metadata.labels['<KEY>'] and metadata.annotations['<KEY>']
+var supportedFieldPaths = map[string]bool{
+	"metadata.name":           true,
+	"metadata.namespace":      true,
+	"metadata.uid":            true,
+	"spec.nodeName":           true,
+	"spec.serviceAccountName": true,
+	"status.hostIP":           true,
+	"status.podIP":            true,
+}
+
+// unresolvedMessageError reports why spec.messageFrom could not be read
+type unresolvedMessageError struct {
+	reason  string
+	message string
+}
+
+func (e *unresolvedMessageError) Error() string {
+	return e.message
+}
+
+// resolveMessage reads the message of helloworld and returns a hash of it, so a
+// change to the referenced ConfigMap or Secret rolls out a new pod, and the
+// selected locales of spec.messages, if any. A field of the pod cannot be read
+// before the pod exists, so only its path is hashed, and a template is hashed as
+// rendered with pod values and .Now left out. The error is an
+// *unresolvedMessageError when a reference cannot be resolved, the template
+// cannot be rendered or no localized message can be selected.
+func (r *HelloWorldReconciler) resolveMessage(ctx context.Context, helloworld *appsv1.HelloWorld) (string, []localizedMessage, error) {
+	ref, value, localized, err := r.readMessage(ctx, helloworld)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// The UID salts the hash, so equal secret values in different HelloWorlds
+	// cannot be matched through the annotation. Serving the message runs a
+	// different workload, so turning serve mode on or off rolls out the pod too.
+	parts := []string{string(helloworld.UID), ref, value}
+	if helloworld.Spec.Serve != nil {
+		parts = append(parts, "serve")
+	}
+	h := sha256.New()
+	for _, part := range parts {
+		_, _ = h.Write([]byte(part))
+		_, _ = h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16], localized, nil
+}
+
+// readMessage returns where the message of helloworld comes from and its value
+// as resolveMessage hashes it: empty for a field of the pod, rendered without
+// pod values for a template, and the selected locales as JSON for messages,
+// which are returned as localized too
+func (r *HelloWorldReconciler) readMessage(ctx context.Context, helloworld *appsv1.HelloWorld) (ref, value string, localized []localizedMessage, err error) {
+	source := helloworld.Spec.MessageFrom
+	switch {
+	case source == nil && helloworld.Spec.MessageTemplate != "":
+		if _, err := parseMessageTemplate(helloworld.Spec.MessageTemplate); err != nil {
+			return "", "", nil, &unresolvedMessageError{reasonTemplateInvalid, fmt.Sprintf("Invalid message template: %v", err)}
+		}
+		rendered, err := renderPodMessage(helloworld, time.Time{})
+		if err != nil {
+			return "", "", nil, &unresolvedMessageError{reasonTemplateRenderFailed, fmt.Sprintf("Failed to render message template: %v", err)}
+		}
+		ref, value = "template", rendered
+
+	case source == nil && len(helloworld.Spec.Messages) > 0:
+		localized, err = r.selectLocales(ctx, helloworld)
+		if err != nil {
+			return "", "", nil, err
+		}
+		// Marshalling strings cannot fail
+		data, _ := json.Marshal(localized)
+		ref, value = "messages", string(data)
+
+	case source == nil:
+		ref, value = "inline", helloworld.Spec.Message
+
+	case source.ConfigMapKeyRef != nil:
+		selector := source.ConfigMapKeyRef
+		ref = "configmap:" + selector.Name + "/" + selector.Key
+		configMap := &corev1.ConfigMap{}
+		err := r.Get(ctx, types.NamespacedName{Name: selector.Name, Namespace: helloworld.Namespace}, configMap)
+		switch {
+		case errors.IsNotFound(err):
+			if !isOptional(selector.Optional) {
+				return "", "", nil, &unresolvedMessageError{reasonConfigMapNotFound, fmt.Sprintf("ConfigMap %s not found", selector.Name)}
+			}
+		case err != nil:
+			return "", "", nil, fmt.Errorf("failed to get ConfigMap %s: %w", selector.Name, err)
+		default:
+			data, ok := configMap.Data[selector.Key]
+			if !ok {
+				binary, found := configMap.BinaryData[selector.Key]
+				if !found && !isOptional(selector.Optional) {
+					return "", "", nil, &unresolvedMessageError{reasonKeyNotFound, fmt.Sprintf("Key %s not found in ConfigMap %s", selector.Key, selector.Name)}
+				}
+				data = string(binary)
+			}
+			value = data
+		}
+
+	case source.SecretKeyRef != nil:
+		selector := source.SecretKeyRef
+		ref = "secret:" + selector.Name + "/" + selector.Key
+		secret := &corev1.Secret{}
+		err := r.Get(ctx, types.NamespacedName{Name: selector.Name, Namespace: helloworld.Namespace}, secret)
+		switch {
+		case errors.IsNotFound(err):
+			if !isOptional(selector.Optional) {
+				return "", "", nil, &unresolvedMessageError{reasonSecretNotFound, fmt.Sprintf("Secret %s not found", selector.Name)}
+			}
+		case err != nil:
+			return "", "", nil, fmt.Errorf("failed to get Secret %s: %w", selector.Name, err)
+		default:
+			data, ok := secret.Data[selector.Key]
+			if !ok && !isOptional(selector.Optional) {
+				return "", "", nil, &unresolvedMessageError{reasonKeyNotFound, fmt.Sprintf("Key %s not found in Secret %s", selector.Key, selector.Name)}
+			}
+			value = string(data)
+		}
+
+	case source.FieldRef != nil:
+		path := source.FieldRef.FieldPath
+		if !supportedFieldPaths[path] && !isMetadataKeyPath(path) {
+			return "", "", nil, &unresolvedMessageError{reasonUnsupportedFieldPath, fmt.Sprintf("Field path %s is not supported", path)}
+		}
+		ref = "field:" + path
+	}
+
+	return ref, value, localized, nil
+}
+
+// messageEnv returns the environment variable a message read from
+// spec.messageFrom is passed to the pod in, so secret values never appear in the
+// pod spec and referenced content never reaches the shell unquoted
+func messageEnv(source *appsv1.MessageSource) corev1.EnvVar {
+	env := corev1.EnvVar{Name: messageEnvVar, ValueFrom: &corev1.EnvVarSource{}}
+	switch {
+	case source.ConfigMapKeyRef != nil:
+		env.ValueFrom.ConfigMapKeyRef = source.ConfigMapKeyRef.DeepCopy()
+	case source.SecretKeyRef != nil:
+		env.ValueFrom.SecretKeyRef = source.SecretKeyRef.DeepCopy()
+	case source.FieldRef != nil:
+		env.ValueFrom.FieldRef = source.FieldRef.DeepCopy()
+	}
+	return env
+}
+
+// messageRefs returns the messageRefIndex values of a HelloWorld
+func messageRefs(obj client.Object) []string {
+	source := obj.(*appsv1.HelloWorld).Spec.MessageFrom
+	switch {
+	case source == nil:
+		return nil
+	case source.ConfigMapKeyRef != nil:
+		return []string{"ConfigMap/" + source.ConfigMapKeyRef.Name}
+	case source.SecretKeyRef != nil:
+		return []string{"Secret/" + source.SecretKeyRef.Name}
+	}
+	return nil
+}
+
+func isOptional(optional *bool) bool {
+	return optional != nil && *optional
+}
+
+// isMetadataKeyPath reports whether path selects a single label or annotation
+func isMetadataKeyPath(path string) bool {
+	for _, prefix := range []string{"metadata.labels['", "metadata.annotations['"} {
+		if key, ok := strings.CutPrefix(path, prefix); ok {
+			return strings.HasSuffix(key, "']") && len(key) > len("']")
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	appsv1 "github.com/example/op-hello-world/api/v1"
+)
+
+// ownedBy reports whether pod belongs to helloworld: it is controlled by it, or it
+// is the pod recorded in the status and nothing else controls it, which is the
+// case when its owner reference was removed by hand
+func ownedBy(helloworld *appsv1.HelloWorld, pod *corev1.Pod) bool {
+	if owner := metav1.GetControllerOf(pod); owner != nil {
+		return owner.UID == helloworld.UID
+	}
+	return helloworld.Status.PodUID != "" && pod.UID == helloworld.Status.PodUID
+}
+
+// canAdopt reports whether live can be adopted in place of desired: nothing
+// controls it, it carries the labels of desired and it opted in with the
+// AdoptAnnotation
+func canAdopt(desired, live *corev1.Pod) bool {
+	if metav1.GetControllerOf(live) != nil || live.Annotations[appsv1.AdoptAnnotation] != "true" {
+		return false
+	}
+	for key, value := range desired.Labels {
+		if live.Labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// findPod returns the existing pod for desired, or nil if there is none. A pod
+// with a fixed name is looked up by name whoever owns it, so conflicts are seen.
+// Generated pods are listed by label: the one helloworld owns is returned, or else
+// one that can be adopted, and extra owned pods, e.g. created twice from a stale
+// cache, are deleted.
+func (r *HelloWorldReconciler) findPod(ctx context.Context, helloworld *appsv1.HelloWorld, desired *corev1.Pod) (*corev1.Pod, error) {
+	if desired.Name != "" {
+		pod := &corev1.Pod{}
+		err := r.Get(ctx, client.ObjectKeyFromObject(desired), pod)
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return pod, nil
+	}
+
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(desired.Namespace), client.MatchingLabels(desired.Labels)); err != nil {
+		return nil, err
+	}
+	var owned []*corev1.Pod
+	var adoptable *corev1.Pod
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		switch {
+		case ownedBy(helloworld, pod):
+			owned = append(owned, pod)
+		case adoptable == nil && canAdopt(desired, pod):
+			adoptable = pod
+		}
+	}
+	if len(owned) == 0 {
+		return adoptable, nil
+	}
+
+	// Keep the pod recorded in the status, or else the oldest
+	sort.SliceStable(owned, func(i, j int) bool {
+		if (owned[i].UID == helloworld.Status.PodUID) != (owned[j].UID == helloworld.Status.PodUID) {
+			return owned[i].UID == helloworld.Status.PodUID
+		}
+		return owned[i].CreationTimestamp.Before(&owned[j].CreationTimestamp)
+	})
+	for _, extra := range owned[1:] {
+		logf.FromContext(ctx).Info("Deleting duplicate pod", "pod", extra.Name, "kept", owned[0].Name)
+		if err := r.Delete(ctx, extra, client.Preconditions{UID: &extra.UID}); err != nil && !errors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to delete duplicate pod %s: %w", extra.Name, err)
+		}
+	}
+	return owned[0], nil
+}
+
+// adoptPod makes helloworld the controller of pod
+func (r *HelloWorldReconciler) adoptPod(ctx context.Context, helloworld *appsv1.HelloWorld, pod *corev1.Pod) error {
+	patch := client.StrategicMergeFrom(pod.DeepCopy())
+	if err := controllerutil.SetControllerReference(helloworld, pod, r.Scheme); err != nil {
+		return err
+	}
+	if err := r.Patch(ctx, pod, patch); err != nil {
+		return err
+	}
+
+	logf.FromContext(ctx).Info("Adopted existing pod", "pod", pod.Name)
+	r.event(helloworld, corev1.EventTypeNormal, "PodAdopted", fmt.Sprintf("Adopted existing pod %s", pod.Name))
+	return nil
+}
@@ -0,0 +1,233 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	k8sappsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	appsv1 "github.com/example/op-hello-world/api/v1"
+)
+
+// defaultRevisionHistoryLimit is the number of old revisions kept when
+// spec.revisionHistoryLimit is not set
+const defaultRevisionHistoryLimit = 10
+
+// revisionData is what a ControllerRevision records of a HelloWorld
+type revisionData struct {
+	Spec appsv1.HelloWorldSpec `json:"spec"`
+}
+
+// snapshotSpec returns the spec of helloworld as recorded in a revision,
+// without the fields that control revisions, approvals, replicas and rollouts
+// and the immutable pod name strategy
+func snapshotSpec(helloworld *appsv1.HelloWorld) ([]byte, error) {
+	spec := helloworld.Spec.DeepCopy()
+	spec.RevisionHistoryLimit = nil
+	spec.RollbackTo = nil
+	spec.PodNameStrategy = ""
+	spec.ApprovalPolicy = ""
+	spec.Replicas = nil
+	spec.Rollout = nil
+	return json.Marshal(revisionData{Spec: *spec})
+}
+
+// revisionName names the revision of a HelloWorld recording data. The UID is
+// hashed as well, so a HelloWorld recreated with the same name does not collide
+// with the revisions of its predecessor before they are garbage collected.
+func revisionName(helloworld *appsv1.HelloWorld, data []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(helloworld.UID))
+	hash.Write(data)
+	return helloworld.Name + "-" + hex.EncodeToString(hash.Sum(nil))[:10]
+}
+
+// listRevisions returns the ControllerRevisions helloworld controls, oldest first
+func (r *HelloWorldReconciler) listRevisions(ctx context.Context, helloworld *appsv1.HelloWorld) ([]*k8sappsv1.ControllerRevision, error) {
+	list := &k8sappsv1.ControllerRevisionList{}
+	if err := r.List(ctx, list, client.InNamespace(helloworld.Namespace),
+		client.MatchingLabels{"app": "helloworld", "helloworld": helloworld.Name}); err != nil {
+		return nil, err
+	}
+	var revisions []*k8sappsv1.ControllerRevision
+	for i := range list.Items {
+		if metav1.IsControlledBy(&list.Items[i], helloworld) {
+			revisions = append(revisions, &list.Items[i])
+		}
+	}
+	slices.SortFunc(revisions, func(a, b *k8sappsv1.ControllerRevision) int {
+		return cmp.Compare(a.Revision, b.Revision)
+	})
+	return revisions, nil
+}
+
+// revisionSpec returns the spec recorded in the revision of helloworld named name
+func (r *HelloWorldReconciler) revisionSpec(ctx context.Context, helloworld *appsv1.HelloWorld, name string) (*appsv1.HelloWorldSpec, error) {
+	revision := &k8sappsv1.ControllerRevision{}
+	if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: helloworld.Namespace}, revision); err != nil {
+		return nil, err
+	}
+	if !metav1.IsControlledBy(revision, helloworld) {
+		return nil, fmt.Errorf("revision %s is not controlled by the HelloWorld", name)
+	}
+	var recorded revisionData
+	if err := json.Unmarshal(revision.Data.Raw, &recorded); err != nil {
+		return nil, fmt.Errorf("failed to decode revision %s: %w", name, err)
+	}
+	return &recorded.Spec, nil
+}
+
+// syncRevisions records the spec of helloworld in a ControllerRevision, sets
+// status.updateRevision to it, and deletes the oldest revisions beyond
+// spec.revisionHistoryLimit. A spec that was recorded before, e.g. after a
+// rollback, has its revision renumbered as the latest instead of a new one.
+func (r *HelloWorldReconciler) syncRevisions(ctx context.Context, helloworld *appsv1.HelloWorld) error {
+	revisions, err := r.listRevisions(ctx, helloworld)
+	if err != nil {
+		return err
+	}
+	data, err := snapshotSpec(helloworld)
+	if err != nil {
+		return err
+	}
+	name := revisionName(helloworld, data)
+	var latest int64
+	if len(revisions) > 0 {
+		latest = revisions[len(revisions)-1].Revision
+	}
+
+	i := slices.IndexFunc(revisions, func(revision *k8sappsv1.ControllerRevision) bool { return revision.Name == name })
+	switch {
+	case i < 0:
+		revision := &k8sappsv1.ControllerRevision{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: helloworld.Namespace,
+				Labels:    map[string]string{"app": "helloworld", "helloworld": helloworld.Name},
+			},
+			Data:     runtime.RawExtension{Raw: data},
+			Revision: latest + 1,
+		}
+		if err := controllerutil.SetControllerReference(helloworld, revision, r.Scheme); err != nil {
+			return err
+		}
+		err := r.Create(ctx, revision)
+		if errors.IsAlreadyExists(err) {
+			// The cache has not seen the revision an earlier reconcile created
+			// yet, which is fine as long as it records the same spec
+			existing := &k8sappsv1.ControllerRevision{}
+			if err := r.Get(ctx, client.ObjectKeyFromObject(revision), existing); err != nil {
+				return fmt.Errorf("failed to get revision %s: %w", name, err)
+			}
+			if !metav1.IsControlledBy(existing, helloworld) || !bytes.Equal(existing.Data.Raw, data) {
+				return fmt.Errorf("revision %s records another spec", name)
+			}
+			revision, err = existing, nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create revision %s: %w", name, err)
+		}
+		logf.FromContext(ctx).Info("Created revision", "revision", name, "number", revision.Revision)
+		revisions = append(revisions, revision)
+
+	case !bytes.Equal(revisions[i].Data.Raw, data):
+		return fmt.Errorf("revision %s records another spec", name)
+
+	case revisions[i].Revision != latest:
+		revision := revisions[i]
+		revision.Revision = latest + 1
+		if err := r.Update(ctx, revision); err != nil {
+			return fmt.Errorf("failed to renumber revision %s: %w", name, err)
+		}
+		logf.FromContext(ctx).Info("Renumbered revision", "revision", name, "number", revision.Revision)
+		revisions = append(slices.Delete(revisions, i, i+1), revision)
+	}
+	helloworld.Status.UpdateRevision = name
+
+	// Revisions in use are kept on top of the history
+	limit := int(ptr.Deref(helloworld.Spec.RevisionHistoryLimit, defaultRevisionHistoryLimit))
+	var history []*k8sappsv1.ControllerRevision
+	for _, revision := range revisions {
+		if revision.Name != helloworld.Status.UpdateRevision && revision.Name != helloworld.Status.CurrentRevision {
+			history = append(history, revision)
+		}
+	}
+	for _, revision := range history[:max(len(history)-limit, 0)] {
+		if err := r.Delete(ctx, revision, client.Preconditions{UID: ptr.To(revision.UID)}); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete revision %s: %w", revision.Name, err)
+		}
+		logf.FromContext(ctx).V(1).Info("Deleted old revision", "revision", revision.Name, "number", revision.Revision)
+	}
+	return nil
+}
+
+// rollback replaces the spec of helloworld with the one recorded in the
+// revision spec.rollbackTo selects and clears rollbackTo. A revision that does
+// not exist is reported in an event, and rollbackTo is cleared all the same.
+func (r *HelloWorldReconciler) rollback(ctx context.Context, helloworld *appsv1.HelloWorld) error {
+	revisions, err := r.listRevisions(ctx, helloworld)
+	if err != nil {
+		return err
+	}
+	number := helloworld.Spec.RollbackTo.Revision
+	if number == 0 && len(revisions) > 1 {
+		number = revisions[len(revisions)-2].Revision
+	}
+	i := slices.IndexFunc(revisions, func(revision *k8sappsv1.ControllerRevision) bool { return revision.Revision == number })
+
+	spec := helloworld.Spec.DeepCopy()
+	spec.RollbackTo = nil
+	if i < 0 {
+		message := "Unable to find the revision to roll back to"
+		if number != 0 {
+			message = fmt.Sprintf("Unable to find revision %d to roll back to", number)
+		}
+		r.event(helloworld, corev1.EventTypeWarning, "RollbackRevisionNotFound", message)
+	} else {
+		var recorded revisionData
+		if err := json.Unmarshal(revisions[i].Data.Raw, &recorded); err != nil {
+			return fmt.Errorf("failed to decode revision %s: %w", revisions[i].Name, err)
+		}
+		recorded.Spec.RevisionHistoryLimit = spec.RevisionHistoryLimit
+		recorded.Spec.PodNameStrategy = spec.PodNameStrategy
+		recorded.Spec.ApprovalPolicy = spec.ApprovalPolicy
+		recorded.Spec.Replicas = spec.Replicas
+		recorded.Spec.Rollout = spec.Rollout
+		spec = &recorded.Spec
+		logf.FromContext(ctx).Info("Rolling back", "revision", revisions[i].Name, "number", number)
+		r.event(helloworld, corev1.EventTypeNormal, "RolledBack", fmt.Sprintf("Rolled back to revision %d", number))
+	}
+	helloworld.Spec = *spec
+	return r.Update(ctx, helloworld)
+}
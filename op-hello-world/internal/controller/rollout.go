@@ -0,0 +1,527 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	k8sappsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	appsv1 "github.com/example/op-hello-world/api/v1"
+	"github.com/example/op-hello-world/internal/metrics"
+)
+
+// revisionLabel labels each replica with the name of the ControllerRevision it
+// was created from, as a Deployment's ReplicaSets label their pods
+const revisionLabel = k8sappsv1.ControllerRevisionHashLabelKey
+
+// defaultRolloutSteps replaces every replica at once when spec.rollout is not set
+var defaultRolloutSteps = []appsv1.RolloutStep{{SetWeight: ptr.To[int32](100)}}
+
+// replicaSet holds the replicas of a HelloWorld by the revision they run. Pods
+// of another revision, created without replicas or running an older message are
+// other, and are deleted once the stable and update replicas run.
+type replicaSet struct {
+	replicas       int32
+	stable, update []*corev1.Pod
+	other          []*corev1.Pod
+}
+
+// updateCount is the number of replicas running the update revision at weight,
+// rounded up so any weight above zero runs at least one
+func (s *replicaSet) updateCount(weight int32) int32 {
+	return (s.replicas*weight + 99) / 100
+}
+
+// settled reports whether weight percent of the replicas run the update
+// revision and the rest the stable revision
+func (s *replicaSet) settled(weight int32) bool {
+	want := s.updateCount(weight)
+	return int32(len(s.update)) == want && int32(len(s.stable)) == s.replicas-want &&
+		!slices.ContainsFunc(s.update, notRunning) && !slices.ContainsFunc(s.stable, notRunning)
+}
+
+// countRunning counts the pods that run
+func countRunning(pods []*corev1.Pod) int32 {
+	var running int32
+	for _, pod := range pods {
+		if !notRunning(pod) {
+			running++
+		}
+	}
+	return running
+}
+
+// podFailing reports whether pod failed or one of its containers keeps crashing
+func podFailing(pod *corev1.Pod) bool {
+	if pod.Status.Phase == corev1.PodFailed {
+		return true
+	}
+	return slices.ContainsFunc(pod.Status.ContainerStatuses, func(status corev1.ContainerStatus) bool {
+		return status.State.Waiting != nil && status.State.Waiting.Reason == "CrashLoopBackOff"
+	})
+}
+
+// notRunning reports whether pod is not running or failing
+func notRunning(pod *corev1.Pod) bool {
+	return pod.Status.Phase != corev1.PodRunning || podFailing(pod)
+}
+
+// reconcileReplicas runs spec.replicas pods for helloworld and rolls out a new
+// revision to them in the steps of spec.rollout, aborting to the stable revision
+// when the HelloWorld becomes Degraded during a step. held keeps the replicas on
+// the stable revision while the message awaits approval. It returns the result
+// reported in metrics and how long to wait before checking again.
+func (r *HelloWorldReconciler) reconcileReplicas(ctx context.Context, helloworld *appsv1.HelloWorld, messageHash string, held bool) (string, time.Duration, error) {
+	log := logf.FromContext(ctx)
+	requeueInterval := r.config().Controller.RequeueInterval.Duration
+
+	update, stable := helloworld.Status.UpdateRevision, helloworld.Status.CurrentRevision
+	var stableSpec *appsv1.HelloWorldSpec
+	if stable != "" && stable != update {
+		spec, err := r.revisionSpec(ctx, helloworld, stable)
+		switch {
+		case errors.IsNotFound(err):
+			log.Info("Stable revision not found, replacing every replica at once", "revision", stable)
+			stable = update
+		case err != nil:
+			return "", 0, err
+		default:
+			stableSpec = spec
+		}
+	}
+	if stable == "" {
+		stable = update
+	}
+
+	set, err := r.listReplicas(ctx, helloworld, stable, update, messageHash)
+	if err != nil {
+		return "", 0, err
+	}
+	rollout := helloworld.Status.Rollout
+	if !held {
+		rollout = r.startRollout(ctx, helloworld, stable)
+	}
+
+	// A failing replica of the update revision degrades the HelloWorld, which
+	// aborts the rollout below
+	for _, pod := range set.update {
+		if podFailing(pod) {
+			r.setCondition(helloworld, appsv1.TypeDegraded, metav1.ConditionTrue, "PodFailure",
+				fmt.Sprintf("Pod %s of revision %s is failing", pod.Name, update))
+			break
+		}
+	}
+
+	// Put a Service in front of every replica in serve mode, and expose it on
+	// spec.route
+	labels := map[string]string{"app": "helloworld", "helloworld": helloworld.Name}
+	if err := r.reconcileService(ctx, helloworld, labels); err != nil {
+		return "", 0, fmt.Errorf("failed to reconcile Service: %w", err)
+	}
+	if err := r.reconcileRoute(ctx, helloworld, labels); err != nil {
+		return "", 0, fmt.Errorf("failed to reconcile route: %w", err)
+	}
+
+	var requeueAfter time.Duration
+	if !held {
+		r.promoteRollout(ctx, helloworld, rollout)
+		r.abortRollout(ctx, helloworld, rollout)
+		requeueAfter = r.advanceRollout(ctx, helloworld, rollout, set)
+	}
+
+	// Held messages keep the update revision off the replicas. A first
+	// revision awaiting approval runs no replicas at all.
+	weight := int32(100)
+	if rollout != nil && rollout.Revision == update {
+		weight = rollout.Weight
+	}
+	if held {
+		weight = 0
+	}
+	wantUpdate := set.updateCount(weight)
+	wantStable := set.replicas - wantUpdate
+	if stable == update {
+		wantUpdate, wantStable = set.replicas, 0
+		if held {
+			wantUpdate = 0
+		}
+	}
+
+	changed := false
+	if wantUpdate > 0 || len(set.update) > 0 {
+		desired, err := r.replicaPod(ctx, helloworld, update, messageHash)
+		if err != nil {
+			return "", 0, err
+		}
+		if set.update, err = r.scaleReplicas(ctx, helloworld, desired, set.update, wantUpdate, &changed); err != nil {
+			return "", 0, err
+		}
+	}
+	if stableSpec != nil && (wantStable > 0 || len(set.stable) > 0) {
+		stableHW := helloworld.DeepCopy()
+		stableHW.Spec = *stableSpec
+		// The stable message may no longer resolve, e.g. after its ConfigMap was
+		// deleted, in which case its pods carry no hash
+		stableHash, _, _ := r.resolveMessage(ctx, stableHW)
+		desired, err := r.replicaPod(ctx, stableHW, stable, stableHash)
+		if err != nil {
+			return "", 0, err
+		}
+		if set.stable, err = r.scaleReplicas(ctx, helloworld, desired, set.stable, wantStable, &changed); err != nil {
+			return "", 0, err
+		}
+	}
+
+	// Pods of other revisions keep running until the replicas replacing them
+	// run, and while a new message awaits approval
+	ready := countRunning(set.stable) + countRunning(set.update)
+	if len(set.other) > 0 && !held && ready == wantUpdate+wantStable {
+		for _, pod := range set.other {
+			log.Info("Deleting replica of another revision", "pod", pod.Name, "revision", pod.Labels[revisionLabel])
+			if err := r.Delete(ctx, pod, client.Preconditions{UID: &pod.UID}); err != nil && !errors.IsNotFound(err) {
+				return "", 0, fmt.Errorf("failed to delete pod %s: %w", pod.Name, err)
+			}
+		}
+		set.other, changed = nil, true
+	}
+	if ready < wantUpdate+wantStable || len(set.other) > 0 {
+		requeueAfter = minRequeue(requeueAfter, requeueInterval)
+	}
+
+	helloworld.Status.Replicas = int32(len(set.stable) + len(set.update) + len(set.other))
+	helloworld.Status.UpdatedReplicas = int32(len(set.update))
+	helloworld.Status.ReadyReplicas = ready + countRunning(set.other)
+	helloworld.Status.RenderedMessage = ""
+	if len(set.update) > 0 {
+		helloworld.Status.RenderedMessage = renderedMessage(helloworld, set.update[0])
+	}
+	message := fmt.Sprintf("%d of %d replicas are running", helloworld.Status.ReadyReplicas, set.replicas)
+	if helloworld.Status.ReadyReplicas >= set.replicas {
+		r.setCondition(helloworld, appsv1.TypeReady, metav1.ConditionTrue, "ReplicasReady", message)
+	} else {
+		r.setCondition(helloworld, appsv1.TypeReady, metav1.ConditionFalse, "ReplicasNotReady", message)
+	}
+	if held {
+		return "awaiting_approval", requeueAfter, r.holdForApproval(ctx, helloworld, messageHash, "")
+	}
+
+	phase, result := appsv1.PhasePending, "replicas_scaled"
+	if helloworld.Status.ReadyReplicas >= set.replicas {
+		phase = appsv1.PhaseRunning
+	}
+	switch rollout.Phase {
+	case appsv1.RolloutProgressing:
+		r.setCondition(helloworld, appsv1.TypeProgressing, metav1.ConditionTrue, "RollingOut", rollout.Message)
+		message, result = rollout.Message, "rollout_progressing"
+	case appsv1.RolloutPaused:
+		r.setCondition(helloworld, appsv1.TypeProgressing, metav1.ConditionTrue, "RolloutPaused", rollout.Message)
+		message, result = rollout.Message, "rollout_paused"
+	case appsv1.RolloutAborted:
+		r.setCondition(helloworld, appsv1.TypeProgressing, metav1.ConditionFalse, "RolloutAborted", rollout.Message)
+		message, result = rollout.Message, "rollout_aborted"
+	default:
+		if phase == appsv1.PhaseRunning && len(set.other) == 0 {
+			r.setCondition(helloworld, appsv1.TypeProgressing, metav1.ConditionFalse, "Stable", "Resource is stable")
+		} else {
+			r.setCondition(helloworld, appsv1.TypeProgressing, metav1.ConditionTrue, "ScalingReplicas",
+				fmt.Sprintf("Scaling to %d replicas", set.replicas))
+		}
+		if !changed {
+			result = "no_change"
+		}
+	}
+	if err := r.updateStatus(ctx, helloworld, phase, "", message); err != nil {
+		return "", 0, err
+	}
+	return result, requeueAfter, nil
+}
+
+// listReplicas sorts the pods helloworld controls by revision. Terminating pods
+// are left out, and replicas of the update revision running an older message,
+// e.g. after its ConfigMap changed, are other.
+func (r *HelloWorldReconciler) listReplicas(ctx context.Context, helloworld *appsv1.HelloWorld, stable, update, messageHash string) (*replicaSet, error) {
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(helloworld.Namespace),
+		client.MatchingLabels{"app": "helloworld", "helloworld": helloworld.Name}); err != nil {
+		return nil, err
+	}
+	set := &replicaSet{replicas: ptr.Deref(helloworld.Spec.Replicas, 1)}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if !metav1.IsControlledBy(pod, helloworld) || !pod.DeletionTimestamp.IsZero() {
+			continue
+		}
+		revision, labeled := pod.Labels[revisionLabel]
+		switch {
+		case labeled && revision == update && pod.Annotations[MessageHashAnnotation] == messageHash:
+			set.update = append(set.update, pod)
+		case labeled && revision == stable && stable != update:
+			set.stable = append(set.stable, pod)
+		default:
+			set.other = append(set.other, pod)
+		}
+	}
+	return set, nil
+}
+
+// replicaPod returns the desired replica of helloworld, whose spec is that of
+// the revision named revision
+func (r *HelloWorldReconciler) replicaPod(ctx context.Context, helloworld *appsv1.HelloWorld, revision, messageHash string) (*corev1.Pod, error) {
+	pod := r.podForHelloWorld(ctx, helloworld)
+	pod.Name, pod.GenerateName = "", helloworld.Name+"-"
+	pod.Labels[revisionLabel] = revision
+	if messageHash != "" {
+		pod.Annotations[MessageHashAnnotation] = messageHash
+	}
+	if err := controllerutil.SetControllerReference(helloworld, pod, r.Scheme); err != nil {
+		return nil, err
+	}
+	return pod, nil
+}
+
+// scaleReplicas corrects the drift of the replicas in pods, creates replicas
+// from desired until there are want and deletes the surplus, failing and newest
+// first. It returns the remaining replicas and sets changed when it created or
+// deleted any. A replica that cannot be created degrades the HelloWorld and is
+// created again later.
+func (r *HelloWorldReconciler) scaleReplicas(ctx context.Context, helloworld *appsv1.HelloWorld, desired *corev1.Pod, pods []*corev1.Pod, want int32, changed *bool) ([]*corev1.Pod, error) {
+	log := logf.FromContext(ctx)
+	kept := pods[:0]
+	for _, pod := range pods {
+		_, recreating, err := r.correctDrift(ctx, helloworld, desired, pod)
+		if err != nil {
+			r.setCondition(helloworld, appsv1.TypeDegraded, metav1.ConditionTrue, "DriftCorrectionFailed", fmt.Sprintf("Failed to correct pod drift: %v", err))
+			return nil, fmt.Errorf("failed to correct drift of pod %s: %w", pod.Name, err)
+		}
+		if !recreating {
+			kept = append(kept, pod)
+		}
+	}
+	pods = kept
+
+	slices.SortStableFunc(pods, func(a, b *corev1.Pod) int {
+		if notRunning(a) != notRunning(b) {
+			if notRunning(a) {
+				return 1
+			}
+			return -1
+		}
+		return a.CreationTimestamp.Compare(b.CreationTimestamp.Time)
+	})
+	for int32(len(pods)) > want {
+		pod := pods[len(pods)-1]
+		log.Info("Deleting surplus replica", "pod", pod.Name, "revision", pod.Labels[revisionLabel])
+		if err := r.Delete(ctx, pod, client.Preconditions{UID: &pod.UID}); err != nil && !errors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to delete pod %s: %w", pod.Name, err)
+		}
+		pods, *changed = pods[:len(pods)-1], true
+	}
+	for int32(len(pods)) < want {
+		pod := desired.DeepCopy()
+		if err := r.Create(ctx, pod); err != nil {
+			log.Error(err, "Failed to create replica", "revision", pod.Labels[revisionLabel])
+			metrics.PodCreationErrors.WithLabelValues(pod.Namespace).Inc()
+			r.setCondition(helloworld, appsv1.TypeDegraded, metav1.ConditionTrue, "PodCreationError", fmt.Sprintf("Pod creation failed: %v", err))
+			break
+		}
+		metrics.PodCreations.WithLabelValues(pod.Namespace).Inc()
+		log.Info("Created replica", "pod", pod.Name, "revision", pod.Labels[revisionLabel])
+		pods, *changed = append(pods, pod), true
+	}
+	return pods, nil
+}
+
+// startRollout returns the rollout of the update revision of helloworld,
+// starting it from stable when the update revision changed. A rollout to the
+// stable revision itself completes at once.
+func (r *HelloWorldReconciler) startRollout(ctx context.Context, helloworld *appsv1.HelloWorld, stable string) *appsv1.RolloutStatus {
+	update := helloworld.Status.UpdateRevision
+	if rollout := helloworld.Status.Rollout; rollout != nil && rollout.Revision == update {
+		return rollout
+	}
+	now := metav1.Now()
+	rollout := &appsv1.RolloutStatus{
+		Revision:       update,
+		StableRevision: stable,
+		Phase:          appsv1.RolloutProgressing,
+		StartTime:      &now,
+	}
+	helloworld.Status.Rollout = rollout
+	if stable == update {
+		rollout.Phase, rollout.Weight = appsv1.RolloutCompleted, 100
+		rollout.CurrentStepIndex = int32(len(rolloutSteps(helloworld)))
+		rollout.Message = fmt.Sprintf("Revision %s runs on every replica", update)
+		helloworld.Status.CurrentRevision = update
+		return rollout
+	}
+
+	// Only a Degraded condition raised during this rollout aborts it
+	if meta.IsStatusConditionTrue(helloworld.Status.Conditions, appsv1.TypeDegraded) {
+		r.setCondition(helloworld, appsv1.TypeDegraded, metav1.ConditionFalse, "RolloutStarted",
+			fmt.Sprintf("Rolling out revision %s", update))
+	}
+	rollout.Message = fmt.Sprintf("Rolling out revision %s in %d steps", update, len(rolloutSteps(helloworld)))
+	logf.FromContext(ctx).Info("Starting rollout", "revision", update, "stableRevision", stable)
+	r.event(helloworld, corev1.EventTypeNormal, "RolloutStarted", rollout.Message)
+	return rollout
+}
+
+// promoteRollout skips the remaining steps of rollout, or resumes it after an
+// abort, when the PromoteAnnotation names its revision
+func (r *HelloWorldReconciler) promoteRollout(ctx context.Context, helloworld *appsv1.HelloWorld, rollout *appsv1.RolloutStatus) {
+	steps := int32(len(rolloutSteps(helloworld)))
+	if helloworld.Annotations[appsv1.PromoteAnnotation] != rollout.Revision || rollout.Phase == appsv1.RolloutCompleted ||
+		(rollout.CurrentStepIndex >= steps && rollout.Phase != appsv1.RolloutAborted) {
+		return
+	}
+	if rollout.Phase == appsv1.RolloutAborted {
+		r.setCondition(helloworld, appsv1.TypeDegraded, metav1.ConditionFalse, "RolloutPromoted",
+			fmt.Sprintf("Revision %s was promoted after the rollout was aborted", rollout.Revision))
+	}
+	rollout.CurrentStepIndex, rollout.Phase, rollout.PauseStartTime = steps, appsv1.RolloutProgressing, nil
+	logf.FromContext(ctx).Info("Rollout promoted", "revision", rollout.Revision)
+	r.event(helloworld, corev1.EventTypeNormal, "RolloutPromoted",
+		fmt.Sprintf("Revision %s was promoted to every replica", rollout.Revision))
+}
+
+// abortRollout returns every replica to the stable revision when helloworld
+// became Degraded while rollout moves through its steps
+func (r *HelloWorldReconciler) abortRollout(ctx context.Context, helloworld *appsv1.HelloWorld, rollout *appsv1.RolloutStatus) {
+	if rollout.Phase != appsv1.RolloutProgressing && rollout.Phase != appsv1.RolloutPaused {
+		return
+	}
+	degraded := meta.FindStatusCondition(helloworld.Status.Conditions, appsv1.TypeDegraded)
+	if degraded == nil || degraded.Status != metav1.ConditionTrue {
+		return
+	}
+	rollout.Phase, rollout.Weight, rollout.PauseStartTime = appsv1.RolloutAborted, 0, nil
+	rollout.Message = fmt.Sprintf("Rollout of revision %s aborted at step %d: %s; annotate the HelloWorld with %s=%s to promote it anyway",
+		rollout.Revision, rollout.CurrentStepIndex, degraded.Message, appsv1.PromoteAnnotation, rollout.Revision)
+	logf.FromContext(ctx).Info("Rollout aborted", "revision", rollout.Revision, "reason", degraded.Reason)
+	r.event(helloworld, corev1.EventTypeWarning, "RolloutAborted", rollout.Message)
+}
+
+// advanceRollout moves rollout through the steps of helloworld as far as the
+// replicas in set allow: a weight step completes once the replicas are split
+// by its weight and run, and a pause step once its duration elapsed or the
+// rollout is promoted. The rollout completes when every replica runs the
+// revision after the last step. It returns how long a timed pause has left.
+func (r *HelloWorldReconciler) advanceRollout(ctx context.Context, helloworld *appsv1.HelloWorld, rollout *appsv1.RolloutStatus, set *replicaSet) time.Duration {
+	steps := rolloutSteps(helloworld)
+	for rollout.Phase == appsv1.RolloutProgressing || rollout.Phase == appsv1.RolloutPaused {
+		if int(rollout.CurrentStepIndex) >= len(steps) {
+			rollout.Phase, rollout.Weight = appsv1.RolloutProgressing, 100
+			if !set.settled(100) {
+				rollout.Message = fmt.Sprintf("Rolling out revision %s to every replica", rollout.Revision)
+				return 0
+			}
+			rollout.Phase = appsv1.RolloutCompleted
+			rollout.Message = fmt.Sprintf("Revision %s runs on every replica", rollout.Revision)
+			helloworld.Status.CurrentRevision = rollout.Revision
+			logf.FromContext(ctx).Info("Rollout completed", "revision", rollout.Revision)
+			r.event(helloworld, corev1.EventTypeNormal, "RolloutCompleted", rollout.Message)
+			return 0
+		}
+
+		step := steps[rollout.CurrentStepIndex]
+		if step.SetWeight != nil {
+			rollout.Phase, rollout.Weight = appsv1.RolloutProgressing, *step.SetWeight
+			if !set.settled(rollout.Weight) {
+				rollout.Message = fmt.Sprintf("Rolling out revision %s to %d%% of the replicas", rollout.Revision, rollout.Weight)
+				return 0
+			}
+			rollout.CurrentStepIndex++
+			continue
+		}
+
+		if rollout.PauseStartTime == nil {
+			now := metav1.Now()
+			rollout.PauseStartTime, rollout.Phase = &now, appsv1.RolloutPaused
+			r.event(helloworld, corev1.EventTypeNormal, "RolloutPaused",
+				fmt.Sprintf("Rollout of revision %s paused at %d%% of the replicas", rollout.Revision, rollout.Weight))
+		}
+		if step.Pause.Duration == nil {
+			rollout.Message = fmt.Sprintf("Paused at %d%% of the replicas; annotate the HelloWorld with %s=%s to promote it",
+				rollout.Weight, appsv1.PromoteAnnotation, rollout.Revision)
+			return 0
+		}
+		remaining := time.Until(rollout.PauseStartTime.Add(step.Pause.Duration.Duration))
+		if remaining > 0 {
+			rollout.Message = fmt.Sprintf("Paused at %d%% of the replicas until %s", rollout.Weight,
+				rollout.PauseStartTime.Add(step.Pause.Duration.Duration).UTC().Format(time.RFC3339))
+			return remaining
+		}
+		rollout.Phase, rollout.PauseStartTime = appsv1.RolloutProgressing, nil
+		rollout.CurrentStepIndex++
+	}
+	return 0
+}
+
+// rolloutSteps returns the steps of spec.rollout, or a single step replacing
+// every replica at once
+func rolloutSteps(helloworld *appsv1.HelloWorld) []appsv1.RolloutStep {
+	if helloworld.Spec.Rollout == nil {
+		return defaultRolloutSteps
+	}
+	return helloworld.Spec.Rollout.Steps
+}
+
+// minRequeue returns the shorter of two requeue delays, where zero means none
+func minRequeue(a, b time.Duration) time.Duration {
+	if a == 0 || (b != 0 && b < a) {
+		return b
+	}
+	return a
+}
+
+// deleteReplicas deletes the replicas of helloworld after spec.replicas went
+// back to one, and clears the replica status. It reports whether any were
+// deleted, in which case the single pod is created once they are gone.
+func (r *HelloWorldReconciler) deleteReplicas(ctx context.Context, helloworld *appsv1.HelloWorld) (bool, error) {
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(helloworld.Namespace),
+		client.MatchingLabels{"app": "helloworld", "helloworld": helloworld.Name}, client.HasLabels{revisionLabel}); err != nil {
+		return false, err
+	}
+	deleted := false
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if !metav1.IsControlledBy(pod, helloworld) || !pod.DeletionTimestamp.IsZero() {
+			continue
+		}
+		logf.FromContext(ctx).Info("Deleting replica", "pod", pod.Name)
+		if err := r.Delete(ctx, pod, client.Preconditions{UID: &pod.UID}); err != nil && !errors.IsNotFound(err) {
+			return false, fmt.Errorf("failed to delete pod %s: %w", pod.Name, err)
+		}
+		deleted = true
+	}
+	helloworld.Status.Replicas, helloworld.Status.UpdatedReplicas, helloworld.Status.ReadyReplicas = 0, 0, 0
+	helloworld.Status.Rollout = nil
+	return deleted, nil
+}
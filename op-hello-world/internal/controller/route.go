@@ -0,0 +1,205 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	appsv1 "github.com/example/op-hello-world/api/v1"
+)
+
+// reconcileRoute exposes the Service of a HelloWorld with spec.route through an
+// HTTPRoute when it names a Gateway and the cluster serves the Gateway API, and
+// through an Ingress otherwise. The kind not in use is deleted, as are both
+// when spec.route is removed.
+func (r *HelloWorldReconciler) reconcileRoute(ctx context.Context, helloworld *appsv1.HelloWorld, labels map[string]string) error {
+	route := helloworld.Spec.Route
+	useGateway := route != nil && route.GatewayRef != nil && r.GatewayAPI
+
+	httpRoute := &gatewayv1.HTTPRoute{ObjectMeta: metav1.ObjectMeta{Name: helloworld.Name, Namespace: helloworld.Namespace}}
+	ingress := &networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Name: helloworld.Name, Namespace: helloworld.Namespace}}
+	if r.GatewayAPI && !useGateway {
+		if err := r.deleteOwned(ctx, helloworld, "HTTPRoute", httpRoute); err != nil {
+			return err
+		}
+	}
+	if route == nil || useGateway {
+		if err := r.deleteOwned(ctx, helloworld, "Ingress", ingress); err != nil {
+			return err
+		}
+	}
+	if !useGateway {
+		helloworld.Status.RouteConditions = nil
+	}
+	if route == nil {
+		return nil
+	}
+
+	kind, owned := "Ingress", client.Object(ingress)
+	mutate := func() error {
+		setLabels(ingress, labels)
+		// The API server fills in the default class on creation, which is kept
+		className := ingress.Spec.IngressClassName
+		ingress.Spec = ingressSpec(helloworld)
+		if ingress.Spec.IngressClassName == nil {
+			ingress.Spec.IngressClassName = className
+		}
+		return controllerutil.SetControllerReference(helloworld, ingress, r.Scheme)
+	}
+	if useGateway {
+		kind, owned = "HTTPRoute", httpRoute
+		mutate = func() error {
+			setLabels(httpRoute, labels)
+			httpRoute.Spec = httpRouteSpec(helloworld)
+			return controllerutil.SetControllerReference(helloworld, httpRoute, r.Scheme)
+		}
+	}
+	result, err := controllerutil.CreateOrUpdate(ctx, r.Client, owned, mutate)
+	if alreadyOwned := (*controllerutil.AlreadyOwnedError)(nil); goerrors.As(err, &alreadyOwned) {
+		message := fmt.Sprintf("%s %s already exists and is controlled by %s %s", kind, owned.GetName(), alreadyOwned.Owner.Kind, alreadyOwned.Owner.Name)
+		helloworld.Status.RouteConditions = nil
+		r.setCondition(helloworld, appsv1.TypeDegraded, metav1.ConditionTrue, "RouteConflict", message)
+		r.event(helloworld, corev1.EventTypeWarning, "RouteConflict", message)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if result != controllerutil.OperationResultNone {
+		logf.FromContext(ctx).Info("Reconciled route", "kind", kind, "name", owned.GetName(), "operation", result)
+	}
+	if c := meta.FindStatusCondition(helloworld.Status.Conditions, appsv1.TypeDegraded); c != nil && c.Reason == "RouteConflict" {
+		r.setCondition(helloworld, appsv1.TypeDegraded, metav1.ConditionFalse, "RouteOwned", "Route conflict resolved")
+	}
+	if useGateway {
+		helloworld.Status.RouteConditions = routeConditions(httpRoute, httpRoute.Spec.ParentRefs[0])
+	}
+	return nil
+}
+
+// pathPrefixes returns the path prefixes of spec.route, "/" when none are set
+func pathPrefixes(route *appsv1.RouteSpec) []string {
+	if len(route.PathPrefixes) == 0 {
+		return []string{"/"}
+	}
+	return route.PathPrefixes
+}
+
+// httpRouteSpec returns the HTTPRoute spec routing spec.route to the Service of
+// helloworld. Fields the Gateway API defaults are set, so the stored route
+// matches and updates do not loop.
+func httpRouteSpec(helloworld *appsv1.HelloWorld) gatewayv1.HTTPRouteSpec {
+	route := helloworld.Spec.Route
+	parent := gatewayv1.ParentReference{
+		Group: ptr.To(gatewayv1.Group(gatewayv1.GroupName)),
+		Kind:  ptr.To(gatewayv1.Kind("Gateway")),
+		Name:  gatewayv1.ObjectName(route.GatewayRef.Name),
+	}
+	if route.GatewayRef.Namespace != "" {
+		parent.Namespace = ptr.To(gatewayv1.Namespace(route.GatewayRef.Namespace))
+	}
+
+	hostnames := make([]gatewayv1.Hostname, 0, len(route.Hostnames))
+	for _, hostname := range route.Hostnames {
+		hostnames = append(hostnames, gatewayv1.Hostname(hostname))
+	}
+	var matches []gatewayv1.HTTPRouteMatch
+	for _, prefix := range pathPrefixes(route) {
+		matches = append(matches, gatewayv1.HTTPRouteMatch{Path: &gatewayv1.HTTPPathMatch{
+			Type:  ptr.To(gatewayv1.PathMatchPathPrefix),
+			Value: ptr.To(prefix),
+		}})
+	}
+	return gatewayv1.HTTPRouteSpec{
+		CommonRouteSpec: gatewayv1.CommonRouteSpec{ParentRefs: []gatewayv1.ParentReference{parent}},
+		Hostnames:       hostnames,
+		Rules: []gatewayv1.HTTPRouteRule{{
+			Matches: matches,
+			BackendRefs: []gatewayv1.HTTPBackendRef{{BackendRef: gatewayv1.BackendRef{
+				BackendObjectReference: gatewayv1.BackendObjectReference{
+					Group: ptr.To(gatewayv1.Group("")),
+					Kind:  ptr.To(gatewayv1.Kind("Service")),
+					Name:  gatewayv1.ObjectName(helloworld.Name),
+					Port:  ptr.To(gatewayv1.PortNumber(helloworld.Spec.Serve.Port)),
+				},
+				Weight: ptr.To[int32](1),
+			}}},
+		}},
+	}
+}
+
+// ingressSpec returns the Ingress spec routing spec.route to the Service of
+// helloworld
+func ingressSpec(helloworld *appsv1.HelloWorld) networkingv1.IngressSpec {
+	route := helloworld.Spec.Route
+	var paths []networkingv1.HTTPIngressPath
+	for _, prefix := range pathPrefixes(route) {
+		paths = append(paths, networkingv1.HTTPIngressPath{
+			Path:     prefix,
+			PathType: ptr.To(networkingv1.PathTypePrefix),
+			Backend: networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+				Name: helloworld.Name,
+				Port: networkingv1.ServiceBackendPort{Number: helloworld.Spec.Serve.Port},
+			}},
+		})
+	}
+	spec := networkingv1.IngressSpec{IngressClassName: route.IngressClassName}
+	for _, hostname := range route.Hostnames {
+		spec.Rules = append(spec.Rules, networkingv1.IngressRule{
+			Host:             hostname,
+			IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{Paths: paths}},
+		})
+	}
+	return spec
+}
+
+// routeConditions returns the conditions the Gateway controller reported for
+// parent in the status of httpRoute, or nil before it has reported any
+func routeConditions(httpRoute *gatewayv1.HTTPRoute, parent gatewayv1.ParentReference) []metav1.Condition {
+	namespace := gatewayv1.Namespace(httpRoute.Namespace)
+	if parent.Namespace != nil {
+		namespace = *parent.Namespace
+	}
+	for _, status := range httpRoute.Status.Parents {
+		ref := status.ParentRef
+		refNamespace := gatewayv1.Namespace(httpRoute.Namespace)
+		if ref.Namespace != nil {
+			refNamespace = *ref.Namespace
+		}
+		if ref.Name == parent.Name && refNamespace == namespace &&
+			(ref.Kind == nil || *ref.Kind == "Gateway") && ptr.Deref(ref.SectionName, "") == "" {
+			var conditions []metav1.Condition
+			for _, condition := range status.Conditions {
+				conditions = append(conditions, *condition.DeepCopy())
+			}
+			return conditions
+		}
+	}
+	return nil
+}
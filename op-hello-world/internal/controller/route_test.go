@@ -0,0 +1,79 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	appsv1 "github.com/example/op-hello-world/api/v1"
+)
+
+var _ = Describe("HTTPRoutes", func() {
+	helloworld := &appsv1.HelloWorld{
+		ObjectMeta: metav1.ObjectMeta{Name: "served", Namespace: "default"},
+		Spec: appsv1.HelloWorldSpec{
+			Message: "Hello",
+			Serve:   &appsv1.ServeSpec{Port: 8000},
+			Route: &appsv1.RouteSpec{
+				Hostnames:    []string{"hello.example.com"},
+				PathPrefixes: []string{"/hello", "/hi"},
+				GatewayRef:   &appsv1.GatewayReference{Name: "public", Namespace: "gateways"},
+			},
+		},
+	}
+
+	It("routes every path prefix to the Service", func() {
+		spec := httpRouteSpec(helloworld)
+		Expect(spec.ParentRefs).To(HaveLen(1))
+		Expect(spec.ParentRefs[0].Name).To(Equal(gatewayv1.ObjectName("public")))
+		Expect(*spec.ParentRefs[0].Namespace).To(Equal(gatewayv1.Namespace("gateways")))
+		Expect(spec.Hostnames).To(Equal([]gatewayv1.Hostname{"hello.example.com"}))
+		Expect(spec.Rules).To(HaveLen(1))
+		Expect(spec.Rules[0].Matches).To(HaveLen(2))
+		Expect(*spec.Rules[0].Matches[1].Path.Value).To(Equal("/hi"))
+		Expect(spec.Rules[0].BackendRefs).To(HaveLen(1))
+		backend := spec.Rules[0].BackendRefs[0].BackendObjectReference
+		Expect(backend.Name).To(Equal(gatewayv1.ObjectName("served")))
+		Expect(*backend.Port).To(Equal(gatewayv1.PortNumber(8000)))
+	})
+
+	It("copies the conditions reported for the Gateway", func() {
+		accepted := metav1.Condition{Type: "Accepted", Status: metav1.ConditionTrue, Reason: "Accepted"}
+		httpRoute := &gatewayv1.HTTPRoute{
+			ObjectMeta: metav1.ObjectMeta{Name: "served", Namespace: "default"},
+			Spec:       httpRouteSpec(helloworld),
+		}
+		parent := httpRoute.Spec.ParentRefs[0]
+		Expect(routeConditions(httpRoute, parent)).To(BeNil())
+
+		httpRoute.Status.Parents = []gatewayv1.RouteParentStatus{
+			{
+				ParentRef:  gatewayv1.ParentReference{Name: "public"},
+				Conditions: []metav1.Condition{{Type: "Accepted", Status: metav1.ConditionFalse, Reason: "NotAllowedByListeners"}},
+			},
+			{
+				ParentRef:  gatewayv1.ParentReference{Name: "public", Namespace: ptr.To(gatewayv1.Namespace("gateways"))},
+				Conditions: []metav1.Condition{accepted},
+			},
+		}
+		Expect(routeConditions(httpRoute, parent)).To(Equal([]metav1.Condition{accepted}))
+	})
+})
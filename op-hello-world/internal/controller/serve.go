@@ -0,0 +1,205 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	goerrors "errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	appsv1 "github.com/example/op-hello-world/api/v1"
+	"github.com/example/op-hello-world/internal/config"
+	"github.com/example/op-hello-world/internal/greeter"
+)
+
+// greeterPortName names the greeter's container port, which the Service and
+// probes refer to
+const greeterPortName = "http"
+
+// greeterContainer returns the container serving the message of helloworld.
+// env holds the message for spec.messageFrom and spec.messageTemplate; others
+// are added here, with $ escaped so the kubelet does not expand it.
+func greeterContainer(helloworld *appsv1.HelloWorld, env []corev1.EnvVar, localized []localizedMessage, defaults config.PodDefaults) corev1.Container {
+	env = slices.Clone(env)
+	escape := func(s string) string { return strings.ReplaceAll(s, "$", "$$") }
+	switch {
+	case len(localized) == 1:
+		env = append(env,
+			corev1.EnvVar{Name: greeter.EnvLocale, Value: localized[0].Locale},
+			corev1.EnvVar{Name: greeter.EnvMessage, Value: escape(localized[0].Message)},
+		)
+	case len(localized) > 1:
+		messages := make(map[string]string, len(localized))
+		for _, message := range localized {
+			messages[message.Locale] = message.Message
+		}
+		// Marshalling strings cannot fail
+		data, _ := json.Marshal(messages)
+		env = append(env,
+			corev1.EnvVar{Name: greeter.EnvDefaultLocale, Value: helloworld.Spec.DefaultLocale},
+			corev1.EnvVar{Name: greeter.EnvMessages, Value: escape(string(data))},
+		)
+	case helloworld.Spec.MessageFrom == nil && helloworld.Spec.MessageTemplate == "":
+		env = append(env, corev1.EnvVar{Name: greeter.EnvMessage, Value: escape(helloworld.Spec.Message)})
+	}
+
+	probe := func(path string) *corev1.Probe {
+		return &corev1.Probe{
+			ProbeHandler: corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{
+				Path: path,
+				Port: intstr.FromString(greeterPortName),
+			}},
+			PeriodSeconds: 10,
+		}
+	}
+	return corev1.Container{
+		Name:           "greeter",
+		Image:          defaults.GreeterImage,
+		Args:           []string{fmt.Sprintf("--listen=:%d", greeter.Port)},
+		Env:            env,
+		Ports:          []corev1.ContainerPort{{Name: greeterPortName, ContainerPort: greeter.Port, Protocol: corev1.ProtocolTCP}},
+		ReadinessProbe: probe(greeter.ReadinessPath),
+		LivenessProbe:  probe(greeter.LivenessPath),
+		Resources:      *defaults.Resources.DeepCopy(),
+	}
+}
+
+// reconcileService creates or updates the Service in front of the greeter of a
+// HelloWorld in serve mode, or deletes it when serve mode is off, and reports
+// its endpoint and the Serving condition in the status
+func (r *HelloWorldReconciler) reconcileService(ctx context.Context, helloworld *appsv1.HelloWorld, labels map[string]string) error {
+	service := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: helloworld.Name, Namespace: helloworld.Namespace}}
+
+	if helloworld.Spec.Serve == nil {
+		helloworld.Status.Endpoint = ""
+		meta.RemoveStatusCondition(&helloworld.Status.Conditions, appsv1.TypeServing)
+		return r.deleteOwned(ctx, helloworld, "Service", service)
+	}
+
+	port := helloworld.Spec.Serve.Port
+	result, err := controllerutil.CreateOrUpdate(ctx, r.Client, service, func() error {
+		setLabels(service, labels)
+		service.Spec.Selector = labels
+		service.Spec.Ports = []corev1.ServicePort{{
+			Name:       greeterPortName,
+			Protocol:   corev1.ProtocolTCP,
+			Port:       port,
+			TargetPort: intstr.FromString(greeterPortName),
+		}}
+		return controllerutil.SetControllerReference(helloworld, service, r.Scheme)
+	})
+	if alreadyOwned := (*controllerutil.AlreadyOwnedError)(nil); goerrors.As(err, &alreadyOwned) {
+		message := fmt.Sprintf("Service %s already exists and is controlled by %s %s", service.Name, alreadyOwned.Owner.Kind, alreadyOwned.Owner.Name)
+		helloworld.Status.Endpoint = ""
+		r.setCondition(helloworld, appsv1.TypeServing, metav1.ConditionFalse, "ServiceConflict", message)
+		r.event(helloworld, corev1.EventTypeWarning, "ServiceConflict", message)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if result != controllerutil.OperationResultNone {
+		logf.FromContext(ctx).Info("Reconciled Service", "service", service.Name, "operation", result)
+	}
+	helloworld.Status.Endpoint = fmt.Sprintf("http://%s.%s.svc:%d", service.Name, service.Namespace, port)
+
+	ready, err := r.readyEndpoints(ctx, service)
+	if err != nil {
+		return err
+	}
+	if ready == 0 {
+		r.setCondition(helloworld, appsv1.TypeServing, metav1.ConditionFalse, "NoReadyEndpoints", "The greeter has no ready endpoints")
+	} else {
+		r.setCondition(helloworld, appsv1.TypeServing, metav1.ConditionTrue, "EndpointsReady", fmt.Sprintf("The greeter has %d ready endpoints", ready))
+	}
+	return nil
+}
+
+// readyEndpoints counts the ready endpoints of service
+func (r *HelloWorldReconciler) readyEndpoints(ctx context.Context, service *corev1.Service) (int, error) {
+	endpointSlices := &discoveryv1.EndpointSliceList{}
+	if err := r.List(ctx, endpointSlices, client.InNamespace(service.Namespace),
+		client.MatchingLabels{discoveryv1.LabelServiceName: service.Name}); err != nil {
+		return 0, err
+	}
+	ready := 0
+	for _, endpointSlice := range endpointSlices.Items {
+		for _, endpoint := range endpointSlice.Endpoints {
+			// A nil ready condition is to be read as ready
+			if endpoint.Conditions.Ready == nil || *endpoint.Conditions.Ready {
+				ready++
+			}
+		}
+	}
+	return ready, nil
+}
+
+// deleteOwned deletes obj, of the given kind, if it exists and helloworld
+// controls it
+func (r *HelloWorldReconciler) deleteOwned(ctx context.Context, helloworld *appsv1.HelloWorld, kind string, obj client.Object) error {
+	err := r.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !metav1.IsControlledBy(obj, helloworld) {
+		return nil
+	}
+	logf.FromContext(ctx).Info("Deleting object that is no longer needed", "kind", kind, "name", obj.GetName())
+	return client.IgnoreNotFound(r.Delete(ctx, obj, client.Preconditions{UID: ptr.To(obj.GetUID())}))
+}
+
+// setLabels adds labels to those of obj
+func setLabels(obj client.Object, labels map[string]string) {
+	merged := obj.GetLabels()
+	if merged == nil {
+		merged = map[string]string{}
+	}
+	for key, value := range labels {
+		merged[key] = value
+	}
+	obj.SetLabels(merged)
+}
+
+// helloWorldForEndpointSlice maps an EndpointSlice to the HelloWorld whose
+// Service it belongs to. The EndpointSlice controller copies the Service's
+// labels, so only slices of generated Services are mapped.
+func helloWorldForEndpointSlice(_ context.Context, endpointSlice client.Object) []reconcile.Request {
+	labels := endpointSlice.GetLabels()
+	if labels["app"] != "helloworld" || labels["helloworld"] == "" || labels[discoveryv1.LabelServiceName] != labels["helloworld"] {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: labels["helloworld"], Namespace: endpointSlice.GetNamespace()}}}
+}
@@ -0,0 +1,286 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	appsv1 "github.com/example/op-hello-world/api/v1"
+)
+
+// maxRenderedMessage bounds the output of a message template
+const maxRenderedMessage = 1024
+
+// Reasons of a false MessageResolved condition for spec.messageTemplate
+const (
+	reasonTemplateInvalid      = "TemplateInvalid"
+	reasonTemplateRenderFailed = "TemplateRenderFailed"
+)
+
+// templateFuncs are the functions a message template may call besides the safe
+// text/template builtins in allowedBuiltins
+var templateFuncs = template.FuncMap{
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"trim":       strings.TrimSpace,
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+	"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"default": func(fallback string, value any) string {
+		if s := fmt.Sprint(value); value != nil && s != "" {
+			return s
+		}
+		return fallback
+	},
+	"truncate": func(n int, s string) string {
+		if runes := []rune(s); n >= 0 && len(runes) > n {
+			return string(runes[:n])
+		}
+		return s
+	},
+}
+
+// allowedBuiltins are the text/template builtins a message template may call.
+// printf is left out since a width argument can allocate unbounded memory, and
+// call since it runs arbitrary functions.
+var allowedBuiltins = map[string]bool{
+	"and": true, "or": true, "not": true, "len": true, "index": true, "print": true,
+	"eq": true, "ne": true, "lt": true, "le": true, "gt": true, "ge": true,
+}
+
+// downwardFields are the pod fields a template can insert, passed to the
+// container in environment variables since they are only known once the pod
+// is scheduled
+var downwardFields = []struct {
+	env  string
+	path string
+}{
+	{"HELLOWORLD_POD_NAME", "metadata.name"},
+	{"HELLOWORLD_POD_IP", "status.podIP"},
+	{"HELLOWORLD_HOST_IP", "status.hostIP"},
+	{"HELLOWORLD_NODE_NAME", "spec.nodeName"},
+	{"HELLOWORLD_SERVICE_ACCOUNT", "spec.serviceAccountName"},
+}
+
+// templateData holds the values a message template can use
+type templateData struct {
+	// Metadata of the HelloWorld
+	Name        string
+	Namespace   string
+	UID         string
+	Generation  int64
+	Labels      map[string]string
+	Annotations map[string]string
+
+	// Downward API values of the generated pod, in downwardFields order
+	PodName            string
+	PodIP              string
+	HostIP             string
+	NodeName           string
+	ServiceAccountName string
+
+	// Now is when the pod was created
+	Now time.Time
+}
+
+// parseMessageTemplate parses text and rejects anything outside the sandbox:
+// functions that are not allowed, nested templates, and range over anything but
+// a field such as .Labels
+func parseMessageTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("message").Option("missingkey=zero").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if len(tmpl.Templates()) > 1 {
+		return nil, fmt.Errorf("message templates cannot define nested templates")
+	}
+	if err := checkTemplateNode(tmpl.Root); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+func checkTemplateNode(node parse.Node) error {
+	switch n := node.(type) {
+	case nil:
+		return nil
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := checkTemplateNode(child); err != nil {
+				return err
+			}
+		}
+	case *parse.ActionNode:
+		return checkTemplateNode(n.Pipe)
+	case *parse.PipeNode:
+		if n == nil {
+			return nil
+		}
+		for _, cmd := range n.Cmds {
+			if err := checkTemplateNode(cmd); err != nil {
+				return err
+			}
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			if err := checkTemplateNode(arg); err != nil {
+				return err
+			}
+		}
+	case *parse.IdentifierNode:
+		if _, ok := templateFuncs[n.Ident]; !ok && !allowedBuiltins[n.Ident] {
+			return fmt.Errorf("function %q is not allowed in message templates", n.Ident)
+		}
+	case *parse.IfNode:
+		return checkBranch(&n.BranchNode)
+	case *parse.WithNode:
+		return checkBranch(&n.BranchNode)
+	case *parse.RangeNode:
+		if len(n.Pipe.Cmds) != 1 || len(n.Pipe.Cmds[0].Args) != 1 {
+			return fmt.Errorf("range in message templates only accepts a field such as .Labels")
+		}
+		if _, ok := n.Pipe.Cmds[0].Args[0].(*parse.FieldNode); !ok {
+			return fmt.Errorf("range in message templates only accepts a field such as .Labels")
+		}
+		return checkBranch(&n.BranchNode)
+	case *parse.TemplateNode:
+		return fmt.Errorf("message templates cannot include other templates")
+	}
+	return nil
+}
+
+func checkBranch(n *parse.BranchNode) error {
+	for _, child := range []parse.Node{n.Pipe, n.List, n.ElseList} {
+		if err := checkTemplateNode(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// limitedBuffer fails writes that would grow it beyond max, which stops the
+// template executing
+type limitedBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.max {
+		return 0, fmt.Errorf("rendered message is longer than %d bytes", b.max)
+	}
+	return b.Buffer.Write(p)
+}
+
+// executeMessageTemplate renders tmpl with data
+func executeMessageTemplate(tmpl *template.Template, data *templateData) (string, error) {
+	out := &limitedBuffer{max: maxRenderedMessage}
+	if err := tmpl.Execute(out, data); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// newTemplateData returns the values of helloworld and, when it exists, its pod
+func newTemplateData(helloworld *appsv1.HelloWorld, pod *corev1.Pod, now time.Time) *templateData {
+	data := &templateData{
+		Name:        helloworld.Name,
+		Namespace:   helloworld.Namespace,
+		UID:         string(helloworld.UID),
+		Generation:  helloworld.Generation,
+		Labels:      helloworld.Labels,
+		Annotations: helloworld.Annotations,
+		Now:         now,
+	}
+	if pod != nil {
+		data.PodName = pod.Name
+		data.PodIP = pod.Status.PodIP
+		data.HostIP = pod.Status.HostIP
+		data.NodeName = pod.Spec.NodeName
+		data.ServiceAccountName = pod.Spec.ServiceAccountName
+	}
+	return data
+}
+
+// renderPodMessage renders the message template of helloworld for a pod created
+// at now. Downward API values are written as $(VAR) references the kubelet
+// expands from the downwardEnv variables, and any other $ is escaped, so label
+// values cannot reference variables of their own.
+func renderPodMessage(helloworld *appsv1.HelloWorld, now time.Time) (string, error) {
+	tmpl, err := parseMessageTemplate(helloworld.Spec.MessageTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	// Stand-ins that survive the template functions, swapped for references
+	// once the rest of the output is escaped
+	placeholder := func(i int) string { return "\x00" + strconv.Itoa(i) + "\x00" }
+	data := newTemplateData(helloworld, nil, now)
+	for i, field := range []*string{&data.PodName, &data.PodIP, &data.HostIP, &data.NodeName, &data.ServiceAccountName} {
+		*field = placeholder(i)
+	}
+
+	out, err := executeMessageTemplate(tmpl, data)
+	if err != nil {
+		return "", err
+	}
+	out = strings.ReplaceAll(out, "$", "$$")
+	for i, field := range downwardFields {
+		out = strings.ReplaceAll(out, placeholder(i), "$("+field.env+")")
+	}
+	return out, nil
+}
+
+// renderedMessage returns the message template of helloworld as rendered for
+// pod, or "" when it has no template
+func renderedMessage(helloworld *appsv1.HelloWorld, pod *corev1.Pod) string {
+	if helloworld.Spec.MessageTemplate == "" {
+		return ""
+	}
+	tmpl, err := parseMessageTemplate(helloworld.Spec.MessageTemplate)
+	if err != nil {
+		return ""
+	}
+	out, err := executeMessageTemplate(tmpl, newTemplateData(helloworld, pod, pod.CreationTimestamp.UTC()))
+	if err != nil {
+		return ""
+	}
+	return out
+}
+
+// downwardEnv returns the environment variables that pass downwardFields to the pod
+func downwardEnv() []corev1.EnvVar {
+	env := make([]corev1.EnvVar, 0, len(downwardFields))
+	for _, field := range downwardFields {
+		env = append(env, corev1.EnvVar{
+			Name:      field.env,
+			ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: field.path}},
+		})
+	}
+	return env
+}
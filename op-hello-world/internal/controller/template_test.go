@@ -0,0 +1,77 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	appsv1 "github.com/example/op-hello-world/api/v1"
+)
+
+var _ = Describe("Message templates", func() {
+	newHelloWorld := func(text string) *appsv1.HelloWorld {
+		return &appsv1.HelloWorld{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "templated", Namespace: "tenant-a", Generation: 3,
+				Labels: map[string]string{"team": "$(HOME)"},
+			},
+			Spec: appsv1.HelloWorldSpec{MessageTemplate: text},
+		}
+	}
+
+	DescribeTable("rejecting templates outside the sandbox",
+		func(text, wantErr string) {
+			_, err := parseMessageTemplate(text)
+			Expect(err).To(MatchError(ContainSubstring(wantErr)))
+		},
+		Entry("printf", `{{printf "%*d" 1000000000 1}}`, `function "printf" is not allowed`),
+		Entry("call", `{{call .Now}}`, `function "call" is not allowed`),
+		Entry("nested templates", `{{define "a"}}a{{end}}{{template "a"}}`, "nested templates"),
+		Entry("range over a number", `{{range 1000000000}}{{end}}`, "range in message templates only accepts a field"),
+		Entry("syntax errors", `{{.Name`, "unclosed action"),
+	)
+
+	It("inserts pod values as references and escapes everything else", func() {
+		rendered, err := renderPodMessage(newHelloWorld(`Hello from {{.Namespace}} on {{upper .NodeName}}, team {{.Labels.team}} #{{.Generation}}`), time.Time{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rendered).To(Equal("Hello from tenant-a on $(HELLOWORLD_NODE_NAME), team $$(HOME) #3"))
+	})
+
+	It("stops rendering at the output limit", func() {
+		helloworld := newHelloWorld(`{{range .Labels}}{{.}}{{end}}`)
+		helloworld.Labels = map[string]string{}
+		for _, key := range []string{"a", "b", "c", "d", "e"} {
+			helloworld.Labels[key] = string(make([]byte, 250))
+		}
+		_, err := renderPodMessage(helloworld, time.Time{})
+		Expect(err).To(MatchError(ContainSubstring("longer than 1024 bytes")))
+	})
+
+	It("renders the status message from the live pod", func() {
+		helloworld := newHelloWorld(`{{.Name}} on {{.NodeName}} since {{.Now.Format "15:04"}}`)
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(time.Date(2025, 1, 1, 9, 30, 0, 0, time.UTC))},
+			Spec:       corev1.PodSpec{NodeName: "node-1"},
+		}
+		Expect(renderedMessage(helloworld, pod)).To(Equal("templated on node-1 since 09:30"))
+	})
+})
@@ -0,0 +1,590 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package delivery POSTs HelloWorld messages to the HTTP endpoints listed in
+// spec.deliveries. Deliveries wait in a queue persisted with a Store, and failed
+// attempts are retried with exponential backoff.
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/example/op-hello-world/internal/metrics"
+)
+
+// Headers set on every delivery
+const (
+	// IdempotencyKeyHeader carries the key receivers deduplicate retries by
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// SignatureHeader carries "sha256=" and the hex HMAC-SHA256 of the
+	// timestamp, a dot and the body
+	SignatureHeader = "X-HelloWorld-Signature"
+	// TimestampHeader carries the Unix time the request was signed at, so
+	// receivers can reject replayed requests
+	TimestampHeader = "X-HelloWorld-Timestamp"
+)
+
+// ErrSuperseded is returned by a Builder for a delivery that is no longer
+// wanted, e.g. because the HelloWorld was changed or deleted. It is dropped.
+var ErrSuperseded = errors.New("delivery superseded")
+
+// errDeniedAddress is returned when dialing a loopback, link-local, multicast or
+// unspecified address, which would reach the manager's pod or node rather than
+// a webhook
+var errDeniedAddress = errors.New("address not allowed for deliveries")
+
+// errHostNotAllowed is returned for a URL whose host is not in AllowedHosts
+var errHostNotAllowed = errors.New("host not allowed for deliveries")
+
+// State is the state of a delivery
+type State string
+
+const (
+	// StatePending deliveries wait for their first or next attempt
+	StatePending State = "Pending"
+	// StateDelivered deliveries were accepted with a 2xx response
+	StateDelivered State = "Delivered"
+	// StateFailed deliveries were rejected, or ran out of attempts
+	StateFailed State = "Failed"
+)
+
+// Entry is a delivery of one generation of a HelloWorld to one of its targets,
+// as persisted in the queue. It holds no message or secret; those are read
+// when the request is built.
+type Entry struct {
+	// Namespace and Name identify the HelloWorld
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// UID and Generation identify the spec being delivered
+	UID        types.UID `json:"uid"`
+	Generation int64     `json:"generation"`
+	// Target is the name of the delivery in spec.deliveries
+	Target string `json:"target"`
+	// Attempts is the number of attempts made so far
+	Attempts int32 `json:"attempts,omitempty"`
+	// NextAttempt is when the delivery is attempted next
+	NextAttempt time.Time `json:"nextAttempt"`
+}
+
+// Key returns the key of the HelloWorld the entry delivers
+func (e Entry) Key() types.NamespacedName {
+	return types.NamespacedName{Namespace: e.Namespace, Name: e.Name}
+}
+
+// IdempotencyKey is the same for every attempt, and every target, of a
+// generation of a HelloWorld
+func (e Entry) IdempotencyKey() string {
+	return fmt.Sprintf("%s-%d", e.UID, e.Generation)
+}
+
+// slot identifies a target of a HelloWorld, which has at most one delivery
+// queued at a time
+func (e Entry) slot() string {
+	return e.Namespace + "/" + e.Name + "/" + e.Target
+}
+
+// Outcome is the latest known state of the delivery to a target
+type Outcome struct {
+	UID        types.UID
+	Generation int64
+	State      State
+	Attempts   int32
+	// LastAttempt is zero before the first attempt
+	LastAttempt time.Time
+	// NextAttempt is set while the delivery is pending
+	NextAttempt time.Time
+	// StatusCode is the HTTP status of the last response, if any
+	StatusCode int
+	// Error explains the last failed attempt
+	Error string
+}
+
+// Request is what an entry is delivered with
+type Request struct {
+	URL     string
+	Headers http.Header
+	Body    []byte
+	// SigningKey signs the timestamp and body in SignatureHeader when set
+	SigningKey []byte
+}
+
+// Builder builds the request for an entry when it is attempted, so changes to
+// referenced Secrets are picked up between retries
+type Builder func(ctx context.Context, entry Entry) (*Request, error)
+
+// Options configure a Dispatcher
+type Options struct {
+	// Store persists the queue. Deliveries only live in memory when it is nil.
+	Store Store
+	// Build builds the request of each attempt
+	Build Builder
+	// Client sends the requests. Defaults to a client with Timeout that
+	// refuses to dial loopback, link-local, multicast and unspecified addresses.
+	Client *http.Client
+	// AllowedHosts restricts the hosts deliveries are sent to, including on
+	// redirects. An entry is a host name, an IP address, or "*." and a domain
+	// matching its subdomains. Any host is allowed when it is empty.
+	AllowedHosts []string
+	// Timeout bounds each attempt when Client is not set
+	Timeout time.Duration
+	// InitialBackoff is the wait before the first retry. It doubles with every
+	// further attempt, up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// MaxAttempts is how many attempts are made before a delivery fails
+	MaxAttempts int32
+}
+
+// Dispatcher sends queued deliveries in the background. It is a manager
+// Runnable that only runs on the leader.
+type Dispatcher struct {
+	opts Options
+	log  logr.Logger
+
+	mu       sync.Mutex
+	loaded   bool
+	queue    map[string]*Entry
+	outcomes map[string]Outcome
+
+	wake      chan struct{}
+	dirty     chan struct{}
+	attempted chan types.NamespacedName
+}
+
+// NewDispatcher returns a Dispatcher for opts
+func NewDispatcher(opts Options, log logr.Logger) *Dispatcher {
+	if opts.Client == nil {
+		dialer := &net.Dialer{Timeout: opts.Timeout, Control: denyInternalAddress}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.Proxy = nil
+		transport.DialContext = dialer.DialContext
+		opts.Client = &http.Client{Timeout: opts.Timeout, Transport: transport}
+	}
+	if opts.Client.CheckRedirect == nil {
+		opts.Client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return checkHost(opts.AllowedHosts, req.URL)
+		}
+	}
+	return &Dispatcher{
+		opts:      opts,
+		log:       log,
+		queue:     map[string]*Entry{},
+		outcomes:  map[string]Outcome{},
+		wake:      make(chan struct{}, 1),
+		dirty:     make(chan struct{}, 1),
+		attempted: make(chan types.NamespacedName, 100),
+	}
+}
+
+// Attempted receives the HelloWorld of each attempt once it is made, so its
+// status can be updated
+func (d *Dispatcher) Attempted() <-chan types.NamespacedName {
+	return d.attempted
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable
+func (d *Dispatcher) NeedLeaderElection() bool {
+	return true
+}
+
+// Enqueue queues entry unless the same generation of the HelloWorld is already
+// queued for or was delivered to the target, and returns the outcome of that
+// delivery. An older generation still queued for the target is replaced.
+func (d *Dispatcher) Enqueue(ctx context.Context, entry Entry) Outcome {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	slot := entry.slot()
+	if outcome, ok := d.outcomes[slot]; ok && outcome.UID == entry.UID && outcome.Generation == entry.Generation {
+		return outcome
+	}
+	entry.Attempts = 0
+	entry.NextAttempt = time.Now()
+	d.queue[slot] = &entry
+	outcome := Outcome{UID: entry.UID, Generation: entry.Generation, State: StatePending, NextAttempt: entry.NextAttempt}
+	d.outcomes[slot] = outcome
+	d.persistLocked()
+	d.signal()
+	return outcome
+}
+
+// Forget drops the deliveries of a deleted HelloWorld
+func (d *Dispatcher) Forget(ctx context.Context, key types.NamespacedName) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	prefix := key.Namespace + "/" + key.Name + "/"
+	changed := false
+	for slot := range d.outcomes {
+		if strings.HasPrefix(slot, prefix) {
+			delete(d.outcomes, slot)
+			if _, ok := d.queue[slot]; ok {
+				delete(d.queue, slot)
+				changed = true
+			}
+		}
+	}
+	if changed {
+		d.persistLocked()
+	}
+}
+
+// Start loads the persisted queue and sends deliveries as they come due until
+// ctx is done
+func (d *Dispatcher) Start(ctx context.Context) error {
+	if err := d.load(ctx); err != nil {
+		return err
+	}
+	persisted := make(chan struct{})
+	go func() {
+		defer close(persisted)
+		d.persist(ctx)
+	}()
+	defer func() { <-persisted }()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		next := d.sendDue(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		wait := time.Hour
+		if !next.IsZero() {
+			wait = time.Until(next)
+		}
+		timer.Reset(wait)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-d.wake:
+		case <-timer.C:
+		}
+	}
+}
+
+// load merges the persisted queue with the deliveries enqueued before Start
+func (d *Dispatcher) load(ctx context.Context) error {
+	var entries []Entry
+	if d.opts.Store != nil {
+		var err error
+		if entries, err = d.opts.Store.Load(ctx); err != nil {
+			return fmt.Errorf("loading delivery queue: %w", err)
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i := range entries {
+		entry := entries[i]
+		slot := entry.slot()
+		// A delivery of another generation enqueued since is newer, while the
+		// same one keeps the attempts made before the restart
+		if queued, ok := d.queue[slot]; ok && (queued.UID != entry.UID || queued.Generation != entry.Generation) {
+			continue
+		}
+		d.queue[slot] = &entry
+		d.outcomes[slot] = Outcome{UID: entry.UID, Generation: entry.Generation, State: StatePending,
+			Attempts: entry.Attempts, NextAttempt: entry.NextAttempt}
+	}
+	d.loaded = true
+	d.log.Info("Loaded delivery queue", "pending", len(d.queue))
+	d.persistLocked()
+	return nil
+}
+
+// sendDue attempts every due delivery and returns when the next one is due,
+// or zero when the queue is empty
+func (d *Dispatcher) sendDue(ctx context.Context) time.Time {
+	d.mu.Lock()
+	var due []Entry
+	for _, entry := range d.queue {
+		if !entry.NextAttempt.After(time.Now()) {
+			due = append(due, *entry)
+		}
+	}
+	d.mu.Unlock()
+	sort.Slice(due, func(i, j int) bool { return due[i].NextAttempt.Before(due[j].NextAttempt) })
+
+	for _, entry := range due {
+		if ctx.Err() != nil {
+			return time.Time{}
+		}
+		d.attempt(ctx, entry)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var next time.Time
+	for _, entry := range d.queue {
+		if next.IsZero() || entry.NextAttempt.Before(next) {
+			next = entry.NextAttempt
+		}
+	}
+	return next
+}
+
+// attempt sends entry once and records the outcome
+func (d *Dispatcher) attempt(ctx context.Context, entry Entry) {
+	log := d.log.WithValues("helloworld", entry.Key(), "target", entry.Target, "generation", entry.Generation)
+
+	request, err := d.opts.Build(ctx, entry)
+	if errors.Is(err, ErrSuperseded) {
+		log.V(1).Info("Dropping superseded delivery")
+		d.finish(ctx, entry, func(outcome *Outcome) bool { return false })
+		metrics.Deliveries.WithLabelValues(entry.Namespace, "superseded").Inc()
+		return
+	}
+	// Requests that cannot be built yet, e.g. for a missing Secret, are retried
+	statusCode, retry := 0, true
+	if err == nil {
+		statusCode, retry, err = d.send(ctx, entry, request)
+	}
+
+	entry.Attempts++
+	now := time.Now()
+	result := "delivered"
+	state := StateDelivered
+	switch {
+	case err == nil:
+		log.Info("Delivered message", "status", statusCode, "attempts", entry.Attempts)
+	case retry && entry.Attempts < d.opts.MaxAttempts:
+		result, state = "retried", StatePending
+		entry.NextAttempt = now.Add(d.backoff(entry.Attempts))
+		log.Info("Delivery failed, retrying", "error", err.Error(), "attempts", entry.Attempts, "nextAttempt", entry.NextAttempt)
+	default:
+		result, state = "failed", StateFailed
+		log.Info("Delivery failed", "error", err.Error(), "attempts", entry.Attempts)
+	}
+	metrics.Deliveries.WithLabelValues(entry.Namespace, result).Inc()
+
+	d.finish(ctx, entry, func(outcome *Outcome) bool {
+		*outcome = Outcome{UID: entry.UID, Generation: entry.Generation, State: state, Attempts: entry.Attempts,
+			LastAttempt: now, StatusCode: statusCode}
+		if err != nil {
+			outcome.Error = err.Error()
+		}
+		if state == StatePending {
+			outcome.NextAttempt = entry.NextAttempt
+		}
+		return true
+	})
+}
+
+// finish records the outcome of an attempt with update, which returns false to
+// forget the delivery, keeps entry queued while it is pending and reports the
+// attempt on Attempted. Entries that were replaced or forgotten during the
+// attempt are left alone.
+func (d *Dispatcher) finish(ctx context.Context, entry Entry, update func(*Outcome) bool) {
+	d.mu.Lock()
+	slot := entry.slot()
+	queued, ok := d.queue[slot]
+	if !ok || queued.UID != entry.UID || queued.Generation != entry.Generation {
+		d.mu.Unlock()
+		return
+	}
+	outcome := d.outcomes[slot]
+	if !update(&outcome) {
+		delete(d.outcomes, slot)
+		delete(d.queue, slot)
+		d.persistLocked()
+		d.mu.Unlock()
+		return
+	}
+	d.outcomes[slot] = outcome
+	if outcome.State == StatePending {
+		d.queue[slot] = &entry
+	} else {
+		delete(d.queue, slot)
+	}
+	d.persistLocked()
+	d.mu.Unlock()
+
+	select {
+	case d.attempted <- entry.Key():
+	case <-ctx.Done():
+	}
+}
+
+// send POSTs request and reports whether a failure may succeed when retried
+func (d *Dispatcher) send(ctx context.Context, entry Entry, request *Request) (int, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, request.URL, bytes.NewReader(request.Body))
+	if err != nil {
+		return 0, false, err
+	}
+	if err := checkHost(d.opts.AllowedHosts, req.URL); err != nil {
+		return 0, false, err
+	}
+	for name, values := range request.Headers {
+		req.Header[http.CanonicalHeaderKey(name)] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(IdempotencyKeyHeader, entry.IdempotencyKey())
+	if len(request.SigningKey) > 0 {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(TimestampHeader, timestamp)
+		req.Header.Set(SignatureHeader, Sign(request.SigningKey, timestamp, request.Body))
+	}
+
+	resp, err := d.opts.Client.Do(req)
+	if err != nil {
+		// Addresses and hosts that are not allowed do not become allowed on retry
+		return 0, !errors.Is(err, errDeniedAddress) && !errors.Is(err, errHostNotAllowed), err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return resp.StatusCode, false, nil
+	case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return resp.StatusCode, true, fmt.Errorf("endpoint responded %s", resp.Status)
+	default:
+		return resp.StatusCode, false, fmt.Errorf("endpoint rejected the delivery with %s", resp.Status)
+	}
+}
+
+// backoff returns the wait after the given number of attempts
+func (d *Dispatcher) backoff(attempts int32) time.Duration {
+	wait := d.opts.InitialBackoff
+	for i := int32(1); i < attempts && wait < d.opts.MaxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, d.opts.MaxBackoff)
+}
+
+// persistLocked has the queue saved by persist. Saving is left to persist so
+// that Enqueue, which reconciles call, never waits for the Store.
+func (d *Dispatcher) persistLocked() {
+	if d.opts.Store == nil || !d.loaded {
+		return
+	}
+	select {
+	case d.dirty <- struct{}{}:
+	default:
+	}
+}
+
+// persist saves the queue whenever it changed until ctx is done, logging
+// failures: the queue is saved again with the next change, and deliveries lost
+// meanwhile are enqueued again by the controller, whose status still shows
+// them pending. Saves never overlap, and each one writes the queue as it is
+// when the save starts.
+func (d *Dispatcher) persist(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.dirty:
+		}
+		d.mu.Lock()
+		entries := make([]Entry, 0, len(d.queue))
+		for _, entry := range d.queue {
+			entries = append(entries, *entry)
+		}
+		d.mu.Unlock()
+		sort.Slice(entries, func(i, j int) bool { return entries[i].slot() < entries[j].slot() })
+		if err := d.opts.Store.Save(ctx, entries); err != nil {
+			d.log.Error(err, "Failed to persist delivery queue")
+		}
+	}
+}
+
+// signal wakes Start to look at the queue
+func (d *Dispatcher) signal() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Sign returns the SignatureHeader value for body sent at timestamp, signed
+// with key
+func Sign(key []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(timestamp + "."))
+	_, _ = mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// checkHost returns errHostNotAllowed unless the host of u matches allowed, or
+// allowed is empty
+func checkHost(allowed []string, u *url.URL) error {
+	if len(allowed) == 0 {
+		return nil
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	for _, pattern := range allowed {
+		pattern = strings.ToLower(pattern)
+		if domain, ok := strings.CutPrefix(pattern, "*."); ok {
+			if strings.HasSuffix(host, "."+domain) {
+				return nil
+			}
+		} else if host == pattern {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", errHostNotAllowed, host)
+}
+
+// denyInternalAddress is a net.Dialer Control function refusing the addresses
+// of errDeniedAddress. It runs after name resolution, so a host name resolving
+// to such an address is refused as well.
+func denyInternalAddress(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	ip = ip.Unmap()
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("%w: %s", errDeniedAddress, ip)
+	}
+	return nil
+}
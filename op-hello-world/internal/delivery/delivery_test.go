@@ -0,0 +1,358 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package delivery
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// receiver records the deliveries an httptest server receives and answers
+// them with the queued status codes, then 200
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   []string
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, string(body))
+	status := http.StatusOK
+	if len(rc.statuses) > 0 {
+		status, rc.statuses = rc.statuses[0], rc.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func (rc *receiver) received() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return len(rc.requests)
+}
+
+var entry = Entry{Namespace: "default", Name: "greeting", UID: types.UID("1234"), Generation: 3, Target: "audit"}
+
+// start runs a dispatcher delivering to server until the test ends
+func start(t *testing.T, server *httptest.Server, store Store, build Builder) *Dispatcher {
+	t.Helper()
+	return startWith(t, server, Options{Store: store, Build: build, Client: server.Client()})
+}
+
+// startWith runs a dispatcher with opts, completed with short backoffs and a
+// builder delivering to server, until the test ends
+func startWith(t *testing.T, server *httptest.Server, opts Options) *Dispatcher {
+	t.Helper()
+	build := opts.Build
+	if build == nil {
+		build = func(context.Context, Entry) (*Request, error) {
+			return &Request{
+				URL:        server.URL,
+				Headers:    http.Header{"X-Team": {"greetings"}},
+				Body:       []byte(`{"message":"Hello"}`),
+				SigningKey: []byte("secret"),
+			}, nil
+		}
+	}
+	opts.Build = build
+	opts.Timeout = time.Second
+	opts.InitialBackoff = 10 * time.Millisecond
+	opts.MaxBackoff = 40 * time.Millisecond
+	opts.MaxAttempts = 3
+	d := NewDispatcher(opts, logr.Discard())
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := d.Start(ctx); err != nil {
+			t.Error(err)
+		}
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return d
+}
+
+// waitFor waits for the outcome of entry to reach state
+func waitFor(t *testing.T, d *Dispatcher, state State) Outcome {
+	t.Helper()
+	deadline := time.After(5 * time.Second)
+	for {
+		if outcome := d.Enqueue(context.Background(), entry); outcome.State == state {
+			return outcome
+		}
+		select {
+		case <-d.Attempted():
+		case <-deadline:
+			t.Fatalf("timed out waiting for %s", state)
+		}
+	}
+}
+
+func TestDeliversSignedRequest(t *testing.T) {
+	rc := &receiver{}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+	d := start(t, server, nil, nil)
+
+	outcome := waitFor(t, d, StateDelivered)
+	if outcome.Attempts != 1 || outcome.StatusCode != http.StatusOK {
+		t.Errorf("expected one successful attempt, got %+v", outcome)
+	}
+	req := rc.requests[0]
+	if got := req.Header.Get(IdempotencyKeyHeader); got != "1234-3" {
+		t.Errorf("expected idempotency key 1234-3, got %q", got)
+	}
+	timestamp := req.Header.Get(TimestampHeader)
+	if sent, err := strconv.ParseInt(timestamp, 10, 64); err != nil || time.Since(time.Unix(sent, 0)) > time.Minute {
+		t.Errorf("expected the current Unix time in %s, got %q", TimestampHeader, timestamp)
+	}
+	if got, want := req.Header.Get(SignatureHeader), Sign([]byte("secret"), timestamp, []byte(rc.bodies[0])); got != want {
+		t.Errorf("expected signature %q, got %q", want, got)
+	}
+	// The timestamp is signed, so it cannot be replaced to replay the request
+	if got := req.Header.Get(SignatureHeader); got == Sign([]byte("secret"), "0", []byte(rc.bodies[0])) {
+		t.Error("expected the signature to depend on the timestamp")
+	}
+	if req.Header.Get("X-Team") != "greetings" || req.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("expected the configured headers, got %v", req.Header)
+	}
+
+	// The same generation is not delivered again
+	d.Enqueue(context.Background(), entry)
+	time.Sleep(50 * time.Millisecond)
+	if n := rc.received(); n != 1 {
+		t.Errorf("expected a single delivery, got %d", n)
+	}
+}
+
+func TestRetriesWithBackoff(t *testing.T) {
+	rc := &receiver{statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+	d := start(t, server, nil, nil)
+
+	outcome := waitFor(t, d, StateDelivered)
+	if outcome.Attempts != 3 {
+		t.Errorf("expected delivery on the third attempt, got %+v", outcome)
+	}
+	for i, req := range rc.requests {
+		if got := req.Header.Get(IdempotencyKeyHeader); got != "1234-3" {
+			t.Errorf("attempt %d: expected the same idempotency key, got %q", i, got)
+		}
+	}
+}
+
+func TestGivesUp(t *testing.T) {
+	tests := map[string]struct {
+		statuses     []int
+		wantAttempts int32
+	}{
+		"rejected":        {[]int{http.StatusBadRequest}, 1},
+		"out of attempts": {[]int{500, 502, 503, 504}, 3},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			rc := &receiver{statuses: tt.statuses}
+			server := httptest.NewServer(rc)
+			defer server.Close()
+			d := start(t, server, nil, nil)
+
+			outcome := waitFor(t, d, StateFailed)
+			if outcome.Attempts != tt.wantAttempts || outcome.Error == "" {
+				t.Errorf("expected %d attempts and an error, got %+v", tt.wantAttempts, outcome)
+			}
+		})
+	}
+}
+
+func TestDropsSupersededDeliveries(t *testing.T) {
+	rc := &receiver{}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+	d := start(t, server, nil, func(context.Context, Entry) (*Request, error) { return nil, ErrSuperseded })
+
+	d.Enqueue(context.Background(), entry)
+	select {
+	case <-d.Attempted():
+		t.Fatal("expected no attempt to be reported")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if rc.received() != 0 {
+		t.Error("expected nothing to be delivered")
+	}
+}
+
+func TestResumesPersistedQueue(t *testing.T) {
+	rc := &receiver{}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+	pending := entry
+	pending.Attempts = 2
+	pending.NextAttempt = time.Now()
+	store := &MemoryStore{entries: []Entry{pending}}
+	d := start(t, server, store, nil)
+
+	outcome := waitFor(t, d, StateDelivered)
+	if outcome.Attempts != 3 {
+		t.Errorf("expected the persisted attempts to count, got %+v", outcome)
+	}
+	// The queue is saved in the background
+	deadline := time.Now().Add(5 * time.Second)
+	for entries, _ := store.Load(context.Background()); len(entries) != 0; entries, _ = store.Load(context.Background()) {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the delivered entry to be removed from the store, got %v", entries)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// blockingStore is a Store whose saves block until release is closed
+type blockingStore struct {
+	MemoryStore
+	release chan struct{}
+}
+
+func (s *blockingStore) Save(ctx context.Context, entries []Entry) error {
+	select {
+	case <-s.release:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return s.MemoryStore.Save(ctx, entries)
+}
+
+func TestEnqueueDoesNotWaitForStore(t *testing.T) {
+	rc := &receiver{}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+	store := &blockingStore{release: make(chan struct{})}
+	// The attempt lasts until the test ends, so the entry stays queued
+	d := start(t, server, store, func(ctx context.Context, _ Entry) (*Request, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	enqueued := make(chan Outcome)
+	go func() { enqueued <- d.Enqueue(context.Background(), entry) }()
+	select {
+	case outcome := <-enqueued:
+		if outcome.State != StatePending {
+			t.Errorf("expected a pending delivery, got %+v", outcome)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected Enqueue to return while the store is blocked")
+	}
+
+	close(store.release)
+	deadline := time.Now().Add(5 * time.Second)
+	for entries, _ := store.Load(context.Background()); len(entries) != 1; entries, _ = store.Load(context.Background()) {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the queued entry to be saved once the store is released, got %v", entries)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRejectsHostsNotAllowed(t *testing.T) {
+	rc := &receiver{}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+	d := startWith(t, server, Options{Client: server.Client(), AllowedHosts: []string{"*.example.com", "hooks.example.org"}})
+
+	outcome := waitFor(t, d, StateFailed)
+	if outcome.Attempts != 1 || !strings.Contains(outcome.Error, "host not allowed") {
+		t.Errorf("expected a single attempt refused for its host, got %+v", outcome)
+	}
+	if rc.received() != 0 {
+		t.Error("expected nothing to be delivered")
+	}
+}
+
+func TestCheckHost(t *testing.T) {
+	allowed := []string{"*.example.com", "Hooks.example.org", "10.0.0.7"}
+	for rawURL, want := range map[string]bool{
+		"https://hooks.example.com/hello":    true,
+		"https://a.b.example.com:8443/hello": true,
+		"https://example.com/hello":          false,
+		"https://evilexample.com/hello":      false,
+		"https://hooks.example.org./hello":   true,
+		"https://other.example.org/hello":    false,
+		"http://10.0.0.7:8080/hello":         true,
+		"http://169.254.169.254/latest":      false,
+	} {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := checkHost(allowed, u) == nil; got != want {
+			t.Errorf("%s: expected allowed=%v, got %v", rawURL, want, got)
+		}
+	}
+	if err := checkHost(nil, &url.URL{Host: "169.254.169.254"}); err != nil {
+		t.Errorf("expected any host to be allowed without a list, got %v", err)
+	}
+}
+
+func TestDeniesInternalAddresses(t *testing.T) {
+	rc := &receiver{}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+	// The default client refuses the loopback address of the test server
+	d := startWith(t, server, Options{})
+
+	outcome := waitFor(t, d, StateFailed)
+	if outcome.Attempts != 1 || !strings.Contains(outcome.Error, "address not allowed") {
+		t.Errorf("expected a single attempt refused for its address, got %+v", outcome)
+	}
+	if rc.received() != 0 {
+		t.Error("expected nothing to be delivered")
+	}
+
+	for address, want := range map[string]bool{
+		"127.0.0.1:80":          false,
+		"[::1]:443":             false,
+		"169.254.169.254:80":    false,
+		"[fe80::1]:80":          false,
+		"0.0.0.0:80":            false,
+		"[::ffff:127.0.0.1]:80": false,
+		"224.0.0.1:80":          false,
+		"10.96.0.10:443":        true,
+		"93.184.216.34:443":     true,
+	} {
+		if got := denyInternalAddress("tcp", address, nil) == nil; got != want {
+			t.Errorf("%s: expected allowed=%v, got %v", address, want, got)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Store persists the delivery queue across manager restarts
+type Store interface {
+	// Load returns the persisted entries, or none if nothing was saved yet
+	Load(ctx context.Context) ([]Entry, error)
+	// Save replaces the persisted entries
+	Save(ctx context.Context, entries []Entry) error
+}
+
+// queueKey is the ConfigMap key the queue is stored under
+const queueKey = "queue.json"
+
+// queueLabels label the ConfigMaps a ConfigMapStore creates
+var queueLabels = map[string]string{"app.kubernetes.io/name": "op-hello-world", "app.kubernetes.io/component": "delivery-queue"}
+
+// ConfigMapStore stores the queue as JSON in a ConfigMap, typically in the
+// manager's namespace, which may be outside the namespaces the cache watches
+type ConfigMapStore struct {
+	// Client writes the ConfigMap
+	Client client.Client
+	// Reader reads the ConfigMap, bypassing the cache
+	Reader client.Reader
+	// Key names the ConfigMap
+	Key types.NamespacedName
+}
+
+// Load implements Store
+func (s *ConfigMapStore) Load(ctx context.Context) ([]Entry, error) {
+	configMap := &corev1.ConfigMap{}
+	err := s.Reader.Get(ctx, s.Key, configMap)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	if data := configMap.Data[queueKey]; data != "" {
+		if err := json.Unmarshal([]byte(data), &entries); err != nil {
+			return nil, fmt.Errorf("parsing ConfigMap %s: %w", s.Key, err)
+		}
+	}
+	return entries, nil
+}
+
+// Save implements Store
+func (s *ConfigMapStore) Save(ctx context.Context, entries []Entry) error {
+	if entries == nil {
+		entries = []Entry{}
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	configMap := &corev1.ConfigMap{}
+	err = s.Reader.Get(ctx, s.Key, configMap)
+	if errors.IsNotFound(err) {
+		configMap.Name, configMap.Namespace = s.Key.Name, s.Key.Namespace
+		configMap.Labels = queueLabels
+		configMap.Data = map[string]string{queueKey: string(data)}
+		return s.Client.Create(ctx, configMap)
+	}
+	if err != nil {
+		return err
+	}
+	if configMap.Data[queueKey] == string(data) {
+		return nil
+	}
+	if configMap.Data == nil {
+		configMap.Data = map[string]string{}
+	}
+	configMap.Data[queueKey] = string(data)
+	return s.Client.Update(ctx, configMap)
+}
+
+// DeleteQueues deletes the queue ConfigMaps in namespace whose names start with
+// prefix, e.g. those named after replicas that no longer run
+func DeleteQueues(ctx context.Context, c client.Client, reader client.Reader, namespace, prefix string) error {
+	configMaps := &corev1.ConfigMapList{}
+	if err := reader.List(ctx, configMaps, client.InNamespace(namespace), client.MatchingLabels(queueLabels)); err != nil {
+		return err
+	}
+	for i := range configMaps.Items {
+		configMap := &configMaps.Items[i]
+		if !strings.HasPrefix(configMap.Name, prefix) {
+			continue
+		}
+		if err := c.Delete(ctx, configMap); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// MemoryStore keeps the queue in memory, e.g. for tests
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// Load implements Store
+func (s *MemoryStore) Load(context.Context) ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Entry(nil), s.entries...), nil
+}
+
+// Save implements Store
+func (s *MemoryStore) Save(_ context.Context, entries []Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append([]Entry(nil), entries...)
+	return nil
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package delivery
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDeleteQueues(t *testing.T) {
+	ctx := context.Background()
+	queue := func(name string, labels map[string]string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "system", Labels: labels}}
+	}
+	c := fake.NewClientBuilder().WithObjects(
+		queue("op-hello-world-deliveries", queueLabels),
+		queue("op-hello-world-deliveries-manager-7d9f-abcde", queueLabels),
+		queue("op-hello-world-deliveries-manager-7d9f-fghij", queueLabels),
+		queue("op-hello-world-deliveries-settings", nil),
+	).Build()
+
+	if err := DeleteQueues(ctx, c, c, "system", "op-hello-world-deliveries-"); err != nil {
+		t.Fatal(err)
+	}
+	configMaps := &corev1.ConfigMapList{}
+	if err := c.List(ctx, configMaps, client.InNamespace("system")); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, configMap := range configMaps.Items {
+		names = append(names, configMap.Name)
+	}
+	if len(names) != 2 || names[0] != "op-hello-world-deliveries" || names[1] != "op-hello-world-deliveries-settings" {
+		t.Errorf("expected the unsharded queue and the unlabeled ConfigMap to remain, got %v", names)
+	}
+
+	store := &ConfigMapStore{Client: c, Reader: c, Key: types.NamespacedName{Namespace: "system", Name: "op-hello-world-deliveries"}}
+	if entries, err := store.Load(ctx); err != nil || len(entries) != 0 {
+		t.Errorf("expected the remaining queue to load empty, got %v, %v", entries, err)
+	}
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package greeter serves a HelloWorld message over HTTP. It is the workload of
+// HelloWorlds in serve mode, built as cmd/greeter.
+package greeter
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"golang.org/x/text/language"
+)
+
+// Environment variables the controller passes the message in
+const (
+	// EnvMessage holds a single message
+	EnvMessage = "MESSAGE"
+	// EnvLocale holds the locale of EnvMessage, if it is localized
+	EnvLocale = "HELLOWORLD_LOCALE"
+	// EnvMessages holds a JSON object of localized messages keyed by locale
+	EnvMessages = "MESSAGES"
+	// EnvDefaultLocale holds the locale of EnvMessages served when none matches
+	EnvDefaultLocale = "HELLOWORLD_DEFAULT_LOCALE"
+)
+
+// Port is the port the greeter listens on in the generated pod
+const Port = 8080
+
+// Paths served besides the message at /
+const (
+	LivenessPath  = "/healthz"
+	ReadinessPath = "/readyz"
+)
+
+// Greeter serves a message, or one of several localized messages chosen by the
+// request's Accept-Language header
+type Greeter struct {
+	messages map[language.Tag]string
+	tags     []language.Tag
+	matcher  language.Matcher
+}
+
+// New returns a greeter for the message in the environment, read with getenv.
+// EnvMessages takes precedence over EnvMessage.
+func New(getenv func(string) string) (*Greeter, error) {
+	if raw := getenv(EnvMessages); raw != "" {
+		var localized map[string]string
+		if err := json.Unmarshal([]byte(raw), &localized); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", EnvMessages, err)
+		}
+		if len(localized) == 0 {
+			return nil, fmt.Errorf("%s has no messages", EnvMessages)
+		}
+		return NewLocalized(localized, getenv(EnvDefaultLocale))
+	}
+
+	if locale := getenv(EnvLocale); locale != "" {
+		return NewLocalized(map[string]string{locale: getenv(EnvMessage)}, locale)
+	}
+	return &Greeter{messages: map[language.Tag]string{language.Und: getenv(EnvMessage)}, tags: []language.Tag{language.Und}}, nil
+}
+
+// NewLocalized returns a greeter for messages keyed by BCP 47 locale, serving
+// the one for defaultLocale to requests no other locale matches
+func NewLocalized(localized map[string]string, defaultLocale string) (*Greeter, error) {
+	g := &Greeter{messages: make(map[language.Tag]string, len(localized))}
+	for locale, message := range localized {
+		tag, err := language.Parse(locale)
+		if err != nil {
+			return nil, fmt.Errorf("parsing locale %q: %w", locale, err)
+		}
+		g.messages[tag] = message
+		g.tags = append(g.tags, tag)
+	}
+	// The first tag is the matcher's fallback
+	fallback, _ := language.Parse(defaultLocale)
+	sort.Slice(g.tags, func(i, j int) bool {
+		if (g.tags[i] == fallback) != (g.tags[j] == fallback) {
+			return g.tags[i] == fallback
+		}
+		return g.tags[i].String() < g.tags[j].String()
+	})
+	g.matcher = language.NewMatcher(g.tags)
+	return g, nil
+}
+
+// Message returns the message for an Accept-Language header value and its
+// locale, which is language.Und for an unlocalized message
+func (g *Greeter) Message(acceptLanguage string) (string, language.Tag) {
+	if g.matcher == nil {
+		return g.messages[language.Und], language.Und
+	}
+	accepted, _, _ := language.ParseAcceptLanguage(acceptLanguage)
+	_, index, _ := g.matcher.Match(accepted...)
+	tag := g.tags[index]
+	return g.messages[tag], tag
+}
+
+// Handler returns the greeter's HTTP handler
+func (g *Greeter) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		message, tag := g.Message(r.Header.Get("Accept-Language"))
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if tag != language.Und {
+			w.Header().Set("Content-Language", tag.String())
+			w.Header().Set("Vary", "Accept-Language")
+		}
+		_, _ = fmt.Fprintln(w, message)
+	})
+	ok := func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprintln(w, "ok")
+	}
+	mux.HandleFunc("GET "+LivenessPath, ok)
+	mux.HandleFunc("GET "+ReadinessPath, ok)
+	return mux
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package greeter
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func env(values map[string]string) func(string) string {
+	return func(key string) string { return values[key] }
+}
+
+func get(t *testing.T, server *httptest.Server, path, acceptLanguage string) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acceptLanguage != "" {
+		req.Header.Set("Accept-Language", acceptLanguage)
+	}
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(body)
+}
+
+func TestServesMessage(t *testing.T) {
+	g, err := New(env(map[string]string{EnvMessage: "Hello, world!"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(g.Handler())
+	defer server.Close()
+
+	resp, body := get(t, server, "/", "de")
+	if resp.StatusCode != http.StatusOK || body != "Hello, world!\n" {
+		t.Errorf("expected the message, got %d %q", resp.StatusCode, body)
+	}
+	if resp.Header.Get("Content-Language") != "" {
+		t.Errorf("expected no Content-Language for an unlocalized message, got %q", resp.Header.Get("Content-Language"))
+	}
+
+	for _, path := range []string{LivenessPath, ReadinessPath} {
+		if resp, _ := get(t, server, path, ""); resp.StatusCode != http.StatusOK {
+			t.Errorf("expected %s to succeed, got %d", path, resp.StatusCode)
+		}
+	}
+	if resp, _ := get(t, server, "/other", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected other paths to be not found, got %d", resp.StatusCode)
+	}
+}
+
+func TestNegotiatesLocale(t *testing.T) {
+	g, err := New(env(map[string]string{
+		EnvMessages:      `{"en":"Hello!","de":"Hallo!","fr":"Bonjour !"}`,
+		EnvDefaultLocale: "en",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(g.Handler())
+	defer server.Close()
+
+	tests := map[string]struct {
+		acceptLanguage string
+		want           string
+		wantLanguage   string
+	}{
+		"exact":     {"fr", "Bonjour !\n", "fr"},
+		"regional":  {"de-CH, en;q=0.5", "Hallo!\n", "de"},
+		"weighted":  {"ja, en;q=0.8, de;q=0.9", "Hallo!\n", "de"},
+		"unmatched": {"ja", "Hello!\n", "en"},
+		"missing":   {"", "Hello!\n", "en"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			resp, body := get(t, server, "/", tt.acceptLanguage)
+			if body != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, body)
+			}
+			if got := resp.Header.Get("Content-Language"); got != tt.wantLanguage {
+				t.Errorf("expected Content-Language %q, got %q", tt.wantLanguage, got)
+			}
+		})
+	}
+}
+
+func TestRejectsBadMessages(t *testing.T) {
+	for name, values := range map[string]map[string]string{
+		"invalid json":   {EnvMessages: "{"},
+		"no messages":    {EnvMessages: "{}"},
+		"invalid locale": {EnvMessage: "Hello", EnvLocale: "english"},
+	} {
+		if _, err := New(env(values)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
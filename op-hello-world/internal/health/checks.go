@@ -0,0 +1,133 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package health provides the readiness checks registered by the manager.
+package health
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+
+	"github.com/example/op-hello-world/internal/metrics"
+)
+
+// Check is a named readiness check
+type Check struct {
+	// Name is the sub-path the check is served under, e.g. /readyz/informer-sync
+	Name string
+	// Checker returns an error while the check is failing
+	Checker healthz.Checker
+	// Degraded checks are logged and exported as metrics when failing but never
+	// mark the manager unready
+	Degraded bool
+}
+
+// checkRegistrar is the subset of manager.Manager used to register checks
+type checkRegistrar interface {
+	AddReadyzCheck(name string, check healthz.Checker) error
+}
+
+// AddReadyzChecks registers each check under /readyz. Every result is exported
+// as helloworld_readiness_check{check,degraded}, and failures are logged when a
+// check changes state, so the reason a manager is not ready is visible.
+func AddReadyzChecks(mgr checkRegistrar, log logr.Logger, checks ...Check) error {
+	for _, c := range checks {
+		if err := mgr.AddReadyzCheck(c.Name, track(c, log)); err != nil {
+			return fmt.Errorf("adding readiness check %q: %w", c.Name, err)
+		}
+	}
+	return nil
+}
+
+// track wraps a check so its result is recorded, and degraded failures are swallowed
+func track(c Check, log logr.Logger) healthz.Checker {
+	var mu sync.Mutex
+	var lastErr error
+	first := true
+	gauge := metrics.ReadinessCheck.WithLabelValues(c.Name, fmt.Sprint(c.Degraded))
+
+	return func(req *http.Request) error {
+		err := c.Checker(req)
+
+		mu.Lock()
+		changed := first || (err == nil) != (lastErr == nil)
+		first, lastErr = false, err
+		mu.Unlock()
+
+		if err != nil {
+			gauge.Set(0)
+			if changed {
+				log.Info("Readiness check failing", "check", c.Name, "degraded", c.Degraded, "reason", err.Error())
+			}
+		} else {
+			gauge.Set(1)
+			if changed {
+				log.Info("Readiness check passing", "check", c.Name)
+			}
+		}
+
+		if c.Degraded {
+			return nil
+		}
+		return err
+	}
+}
+
+// CacheSynced fails until every informer in the cache has synced
+func CacheSynced(c cache.Cache, timeout time.Duration) healthz.Checker {
+	return func(req *http.Request) error {
+		ctx, cancel := context.WithTimeout(req.Context(), timeout)
+		defer cancel()
+		if !c.WaitForCacheSync(ctx) {
+			return fmt.Errorf("informer caches have not synced")
+		}
+		return nil
+	}
+}
+
+// Reachable fails while a TCP connection to address cannot be opened
+func Reachable(address string, timeout time.Duration) healthz.Checker {
+	return func(req *http.Request) error {
+		d := &net.Dialer{Timeout: timeout}
+		conn, err := d.DialContext(req.Context(), "tcp", address)
+		if err != nil {
+			return fmt.Errorf("%s is unreachable: %w", address, err)
+		}
+		return conn.Close()
+	}
+}
+
+// LeaderElected fails while this replica is a standby waiting for the leader lease.
+// The elected channel is closed once the lease is acquired, or immediately when
+// leader election is disabled.
+func LeaderElected(elected <-chan struct{}) healthz.Checker {
+	return func(_ *http.Request) error {
+		select {
+		case <-elected:
+			return nil
+		default:
+			return fmt.Errorf("standby: leader lease is held by another replica")
+		}
+	}
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+
+	"github.com/example/op-hello-world/internal/telemetrytest"
+)
+
+// fakeRegistrar collects checks the way the manager would
+type fakeRegistrar map[string]healthz.Checker
+
+func (f fakeRegistrar) AddReadyzCheck(name string, check healthz.Checker) error {
+	f[name] = check
+	return nil
+}
+
+func TestAddReadyzChecks(t *testing.T) {
+	recorder := telemetrytest.Install()
+	defer recorder.Uninstall()
+
+	failing := func(_ *http.Request) error { return errors.New("down") }
+	registrar := fakeRegistrar{}
+	err := AddReadyzChecks(registrar, logr.Discard(),
+		Check{Name: "hard", Checker: failing},
+		Check{Name: "soft", Checker: failing, Degraded: true},
+		Check{Name: "ok", Checker: healthz.Ping},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	if registrar["hard"](req) == nil {
+		t.Error("expected hard check to fail readiness")
+	}
+	if err := registrar["soft"](req); err != nil {
+		t.Errorf("expected degraded check not to fail readiness, got %v", err)
+	}
+
+	if err := registrar["ok"](req); err != nil {
+		t.Errorf("expected passing check to pass, got %v", err)
+	}
+	if got := recorder.Metric("helloworld_readiness_check", map[string]string{"check": "ok"}); got != 1 {
+		t.Errorf("expected ok check to be reported as passing, got %v", got)
+	}
+
+	for _, name := range []string{"hard", "soft"} {
+		labels := map[string]string{"check": name}
+		if got := recorder.Metric("helloworld_readiness_check", labels); got != 0 {
+			t.Errorf("expected %s check to be reported as failing, got %v", name, got)
+		}
+	}
+}
+
+func TestReachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+
+	if err := Reachable(address, time.Second)(req); err != nil {
+		t.Errorf("expected listener to be reachable, got %v", err)
+	}
+	_ = listener.Close()
+	if err := Reachable(address, time.Second)(req); err == nil {
+		t.Error("expected closed listener to be unreachable")
+	}
+}
+
+func TestLeaderElected(t *testing.T) {
+	elected := make(chan struct{})
+	check := LeaderElected(elected)
+	req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+
+	if check(req) == nil {
+		t.Error("expected standby replica to report not elected")
+	}
+	close(elected)
+	if err := check(req); err != nil {
+		t.Errorf("expected elected replica to pass, got %v", err)
+	}
+}
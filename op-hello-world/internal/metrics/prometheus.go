@@ -76,16 +76,105 @@ var (
 		},
 		[]string{"namespace"},
 	)
+
+	// ReadinessCheck is a gauge reporting whether each readiness check is passing
+	ReadinessCheck = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "helloworld_readiness_check",
+			Help: "Whether a manager readiness check is passing (1) or failing (0)",
+		},
+		[]string{"check", "degraded"},
+	)
+
+	// ConfigReloads is a counter for manager config reloads by result
+	ConfigReloads = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "config_reload_total",
+			Help: "Total number of manager config reloads, by result",
+		},
+		[]string{"result"},
+	)
+
+	// ShardMembers is a gauge of the live replicas in this replica's shard group
+	ShardMembers = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "helloworld_shard_members",
+			Help: "Number of live replicas sharing HelloWorld resources with this replica",
+		},
+		[]string{"group"},
+	)
+
+	// ShardMember is a gauge set to 1 for each live replica in the shard group, and
+	// labels whether the replica is this one
+	ShardMember = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "helloworld_shard_member",
+			Help: "Live replicas in the shard group as seen by this replica",
+		},
+		[]string{"group", "replica", "self"},
+	)
+
+	// ShardRebalances is a counter for shard membership changes
+	ShardRebalances = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "helloworld_shard_rebalances_total",
+			Help: "Total number of times shard membership changed and resources were rebalanced",
+		},
+		[]string{"group"},
+	)
+
+	// DriftCorrections is a counter for child pod fields restored after being changed
+	// outside the controller
+	DriftCorrections = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "helloworld_drift_corrections_total",
+			Help: "Total number of drifted child pod fields corrected by field",
+		},
+		[]string{"field"},
+	)
+
+	// Deliveries is a counter for attempts to deliver messages to spec.deliveries,
+	// by result: delivered, retried, failed or superseded
+	Deliveries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "helloworld_deliveries_total",
+			Help: "Total number of message delivery attempts by result",
+		},
+		[]string{"namespace", "result"},
+	)
+
+	// CloudEvents is a counter for CloudEvents published to the sink, by type and
+	// result: sent, failed or dropped
+	CloudEvents = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "helloworld_cloudevents_total",
+			Help: "Total number of CloudEvents published by type and result",
+		},
+		[]string{"type", "result"},
+	)
 )
 
-func init() {
-	// Register custom metrics with the global prometheus registry
-	metrics.Registry.MustRegister(
+// Collectors returns all custom metrics so they can be registered with a registry
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{
 		ReconcileTotal,
 		ReconcileErrors,
 		ReconcileDuration,
 		HelloWorldResources,
 		PodCreations,
 		PodCreationErrors,
-	)
+		ReadinessCheck,
+		ConfigReloads,
+		ShardMembers,
+		ShardMember,
+		ShardRebalances,
+		DriftCorrections,
+		Deliveries,
+		CloudEvents,
+	}
+}
+
+func init() {
+	// Register custom metrics with the global prometheus registry
+	metrics.Registry.MustRegister(Collectors()...)
 }
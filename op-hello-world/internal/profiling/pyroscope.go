@@ -0,0 +1,170 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package profiling
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/grafana/pyroscope-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// Label keys attached to profiling samples
+const (
+	// LabelSpanID links a profile sample to the reconcile span that produced it
+	LabelSpanID = "span_id"
+	// LabelSpanName is the name of the span that produced a profile sample
+	LabelSpanName = "span_name"
+	// LabelController is the controller that produced a profile sample
+	LabelController = "controller"
+	// LabelNamespace is the namespace of the resource being reconciled
+	LabelNamespace = "namespace"
+)
+
+// profileIDAttribute is the span attribute Grafana uses to find the profile for a span
+const profileIDAttribute = "pyroscope.profile.id"
+
+// enabled is set while the profiler is running so that labelling is skipped otherwise
+var enabled atomic.Bool
+
+// Config holds the continuous profiling settings
+type Config struct {
+	// ServerAddress is the Pyroscope server URL, e.g. http://pyroscope:4040
+	ServerAddress string
+	// ApplicationName is the service name profiles are stored under
+	ApplicationName string
+	// UploadRate is how often profiles are pushed to the server
+	UploadRate time.Duration
+	// MutexProfileFraction is the rate passed to runtime.SetMutexProfileFraction
+	MutexProfileFraction int
+	// Tags are static labels added to every profile
+	Tags map[string]string
+}
+
+// Profiler pushes CPU, heap, goroutine and mutex profiles to Pyroscope while the manager runs
+type Profiler struct {
+	config Config
+	log    logr.Logger
+}
+
+var _ manager.LeaderElectionRunnable = &Profiler{}
+
+// NewProfiler returns a Profiler to be added to the manager
+func NewProfiler(config Config, log logr.Logger) (*Profiler, error) {
+	if config.ServerAddress == "" {
+		return nil, fmt.Errorf("profiling server address must be set")
+	}
+	if config.UploadRate <= 0 {
+		return nil, fmt.Errorf("profiling upload rate must be positive, got %s", config.UploadRate)
+	}
+	if config.Tags == nil {
+		config.Tags = map[string]string{}
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		config.Tags["pod"] = hostname
+	}
+	return &Profiler{config: config, log: log}, nil
+}
+
+// Start pushes profiles until the context is cancelled
+func (p *Profiler) Start(ctx context.Context) error {
+	runtime.SetMutexProfileFraction(p.config.MutexProfileFraction)
+
+	profiler, err := pyroscope.Start(pyroscope.Config{
+		ApplicationName: p.config.ApplicationName,
+		ServerAddress:   p.config.ServerAddress,
+		Tags:            p.config.Tags,
+		UploadRate:      p.config.UploadRate,
+		Logger:          logAdapter{p.log},
+		ProfileTypes: []pyroscope.ProfileType{
+			pyroscope.ProfileCPU,
+			pyroscope.ProfileAllocObjects,
+			pyroscope.ProfileAllocSpace,
+			pyroscope.ProfileInuseObjects,
+			pyroscope.ProfileInuseSpace,
+			pyroscope.ProfileGoroutines,
+			pyroscope.ProfileMutexCount,
+			pyroscope.ProfileMutexDuration,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("starting pyroscope profiler: %w", err)
+	}
+	enabled.Store(true)
+	p.log.Info("Continuous profiling started", "server", p.config.ServerAddress, "application", p.config.ApplicationName)
+
+	<-ctx.Done()
+
+	enabled.Store(false)
+	if err := profiler.Stop(); err != nil {
+		return fmt.Errorf("stopping pyroscope profiler: %w", err)
+	}
+	return nil
+}
+
+// NeedLeaderElection returns false so every replica is profiled
+func (p *Profiler) NeedLeaderElection() bool {
+	return false
+}
+
+// WithSpanLabels attaches pprof labels for the span and the given key/value
+// pairs to the calling goroutine, and records the span ID on the span so
+// Grafana can jump from the trace to its profile. The returned function
+// restores the goroutine's previous labels. It is a no-op when profiling is off.
+func WithSpanLabels(ctx context.Context, span trace.Span, name string, keyValues ...string) (context.Context, func()) {
+	if !enabled.Load() {
+		return ctx, func() {}
+	}
+
+	labels := append([]string{LabelSpanName, name}, keyValues...)
+	if sc := span.SpanContext(); sc.IsValid() {
+		spanID := sc.SpanID().String()
+		labels = append(labels, LabelSpanID, spanID)
+		span.SetAttributes(attribute.String(profileIDAttribute, spanID))
+	}
+
+	previous := ctx
+	ctx = pprof.WithLabels(ctx, pprof.Labels(labels...))
+	pprof.SetGoroutineLabels(ctx)
+	return ctx, func() { pprof.SetGoroutineLabels(previous) }
+}
+
+// logAdapter forwards pyroscope log output to a logr.Logger
+type logAdapter struct {
+	log logr.Logger
+}
+
+func (l logAdapter) Infof(format string, args ...interface{}) {
+	l.log.V(1).Info(fmt.Sprintf(format, args...))
+}
+
+func (l logAdapter) Debugf(format string, args ...interface{}) {
+	l.log.V(2).Info(fmt.Sprintf(format, args...))
+}
+
+func (l logAdapter) Errorf(format string, args ...interface{}) {
+	l.log.Error(nil, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package profiling
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"runtime/pprof"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel"
+
+	"github.com/example/op-hello-world/internal/telemetrytest"
+)
+
+func TestWithSpanLabels(t *testing.T) {
+	recorder := telemetrytest.Install()
+	defer recorder.Uninstall()
+
+	enabled.Store(true)
+	defer enabled.Store(false)
+
+	ctx, span := otel.Tracer("test").Start(context.Background(), "Reconcile")
+	labelled, restore := WithSpanLabels(ctx, span, "Reconcile", LabelController, "helloworld", LabelNamespace, "default")
+	restore()
+	span.End()
+
+	spanID := span.SpanContext().SpanID().String()
+	want := map[string]string{
+		LabelSpanName:   "Reconcile",
+		LabelSpanID:     spanID,
+		LabelController: "helloworld",
+		LabelNamespace:  "default",
+	}
+	for key, value := range want {
+		if got, _ := pprof.Label(labelled, key); got != value {
+			t.Errorf("expected label %s=%q, got %q", key, value, got)
+		}
+	}
+
+	spans := recorder.SpansNamed("Reconcile")
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 Reconcile span, got %d", len(spans))
+	}
+	if id, _ := telemetrytest.Attribute(spans[0], profileIDAttribute); id.AsString() != spanID {
+		t.Errorf("expected %s %q, got %q", profileIDAttribute, spanID, id.AsString())
+	}
+}
+
+func TestWithSpanLabelsWhenDisabled(t *testing.T) {
+	recorder := telemetrytest.Install()
+	defer recorder.Uninstall()
+
+	ctx, span := otel.Tracer("test").Start(context.Background(), "Reconcile")
+	labelled, restore := WithSpanLabels(ctx, span, "Reconcile", LabelController, "helloworld")
+	restore()
+	span.End()
+
+	if labelled != ctx {
+		t.Error("expected the context to be returned unchanged")
+	}
+	if _, ok := pprof.Label(labelled, LabelSpanName); ok {
+		t.Errorf("expected no %s label", LabelSpanName)
+	}
+	if _, ok := telemetrytest.Attribute(recorder.SpansNamed("Reconcile")[0], profileIDAttribute); ok {
+		t.Errorf("expected no %s attribute", profileIDAttribute)
+	}
+}
+
+func TestNewProfiler(t *testing.T) {
+	tests := map[string]struct {
+		config  Config
+		wantErr bool
+	}{
+		"valid":             {config: Config{ServerAddress: "http://localhost:4040", UploadRate: time.Second}},
+		"no server address": {config: Config{UploadRate: time.Second}, wantErr: true},
+		"no upload rate":    {config: Config{ServerAddress: "http://localhost:4040"}, wantErr: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			profiler, err := NewProfiler(tt.config, logr.Discard())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if err == nil && profiler.config.Tags["pod"] == "" {
+				t.Error("expected a pod tag")
+			}
+		})
+	}
+}
+
+func TestProfilerStartStop(t *testing.T) {
+	var uploads atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uploads.Add(1)
+	}))
+	defer server.Close()
+
+	profiler, err := NewProfiler(Config{
+		ServerAddress:   server.URL,
+		ApplicationName: "op-hello-world-test",
+		UploadRate:      50 * time.Millisecond,
+	}, logr.Discard())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- profiler.Start(ctx) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !enabled.Load() || uploads.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("profiler did not start pushing profiles")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected Start to return nil, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("profiler did not stop")
+	}
+	if enabled.Load() {
+		t.Error("expected labelling to be disabled after the profiler stopped")
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sharding
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+// pointsPerMember is how many points each member places on the ring. More
+// points spread the hash ranges more evenly between members.
+const pointsPerMember = 64
+
+// Ring is a consistent hash ring. Each member owns the hash ranges that end at
+// its points, so when a member joins or leaves only the keys in its ranges move.
+type Ring struct {
+	points []uint32
+	owners []string
+}
+
+// NewRing returns a ring over the given members
+func NewRing(members []string) Ring {
+	type point struct {
+		hash  uint32
+		owner string
+	}
+	points := make([]point, 0, len(members)*pointsPerMember)
+	for _, member := range members {
+		for i := 0; i < pointsPerMember; i++ {
+			points = append(points, point{hash: hash(member + "#" + strconv.Itoa(i)), owner: member})
+		}
+	}
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].hash != points[j].hash {
+			return points[i].hash < points[j].hash
+		}
+		return points[i].owner < points[j].owner
+	})
+
+	r := Ring{points: make([]uint32, len(points)), owners: make([]string, len(points))}
+	for i, p := range points {
+		r.points[i], r.owners[i] = p.hash, p.owner
+	}
+	return r
+}
+
+// Owner returns the member that owns key, or "" when the ring is empty
+func (r Ring) Owner(key string) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+	h := hash(key)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.owners[i]
+}
+
+// hash is FNV-1a followed by the murmur3 finalizer, which spreads keys that
+// differ only in their last bytes, such as a member's point names
+func hash(s string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(s))
+	x := h.Sum32()
+	x ^= x >> 16
+	x *= 0x85ebca6b
+	x ^= x >> 13
+	x *= 0xc2b2ae35
+	x ^= x >> 16
+	return x
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sharding
+
+import (
+	"fmt"
+	"testing"
+)
+
+func keys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("tenant-%d", i)
+	}
+	return keys
+}
+
+func TestRingSpreadsKeys(t *testing.T) {
+	ring := NewRing([]string{"replica-a", "replica-b", "replica-c"})
+	counts := map[string]int{}
+	for _, key := range keys(3000) {
+		counts[ring.Owner(key)]++
+	}
+	for _, member := range []string{"replica-a", "replica-b", "replica-c"} {
+		// An even split is 1000 keys each
+		if counts[member] < 600 || counts[member] > 1400 {
+			t.Errorf("uneven split: %v", counts)
+		}
+	}
+	if NewRing(nil).Owner("tenant-0") != "" {
+		t.Error("expected an empty ring to have no owners")
+	}
+}
+
+func TestRingMovesOnlyDepartedKeys(t *testing.T) {
+	before := NewRing([]string{"replica-a", "replica-b", "replica-c"})
+	after := NewRing([]string{"replica-a", "replica-b"})
+	for _, key := range keys(1000) {
+		owner := before.Owner(key)
+		if owner != "replica-c" && after.Owner(key) != owner {
+			t.Errorf("key %s moved from %s to %s although its owner stayed", key, owner, after.Owner(key))
+		}
+	}
+}
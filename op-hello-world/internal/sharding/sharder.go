@@ -0,0 +1,352 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sharding splits HelloWorld resources between active manager replicas.
+// Every replica renews its own Lease; the live Leases of a group are the shard
+// members, and each member owns the keys that hash into its ranges of a
+// consistent hash ring.
+package sharding
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/example/op-hello-world/internal/config"
+	"github.com/example/op-hello-world/internal/metrics"
+)
+
+// LabelGroup labels the Leases of the replicas in a shard group
+const LabelGroup = "sharding.apps.example.com/group"
+
+// Options configures a Sharder
+type Options struct {
+	// Namespace is where the shard Leases are kept, normally the manager's namespace
+	Namespace string
+	// Group identifies the replicas that share resources; it is used as a label value
+	Group string
+	// ReplicaID identifies this replica, normally its pod name
+	ReplicaID string
+	// KeyFunc returns the key an object is sharded by
+	KeyFunc func(client.Object) string
+	// LeaseDuration is how long a replica keeps its shard after it stops renewing
+	LeaseDuration time.Duration
+	// RenewInterval is how often the Lease is renewed and membership is checked
+	RenewInterval time.Duration
+}
+
+// Sharder maintains this replica's shard membership while the manager runs
+type Sharder struct {
+	client  client.Client
+	reader  client.Reader
+	options Options
+	log     logr.Logger
+
+	mu      sync.RWMutex
+	joined  bool
+	members []string
+	ring    Ring
+	// claimed is the ring this replica owned keys by before the pending
+	// handoff, which ends at handoff; handoff is zero when none is pending
+	claimed Ring
+	handoff time.Time
+
+	changed chan struct{}
+}
+
+var _ manager.LeaderElectionRunnable = &Sharder{}
+
+// NewSharder returns a Sharder to be added to the manager. Leases are written
+// with c and read with reader, which should not be cached.
+func NewSharder(c client.Client, reader client.Reader, options Options, log logr.Logger) (*Sharder, error) {
+	if options.Namespace == "" || options.ReplicaID == "" || options.KeyFunc == nil {
+		return nil, fmt.Errorf("sharding namespace, replica ID and key function must be set")
+	}
+	if msgs := validation.IsValidLabelValue(options.Group); options.Group == "" || len(msgs) > 0 {
+		return nil, fmt.Errorf("invalid shard group %q: %s", options.Group, strings.Join(msgs, "; "))
+	}
+	if options.RenewInterval <= 0 || options.LeaseDuration <= options.RenewInterval {
+		return nil, fmt.Errorf("shard lease duration %s must be longer than the renew interval %s",
+			options.LeaseDuration, options.RenewInterval)
+	}
+	return &Sharder{
+		client:  c,
+		reader:  reader,
+		options: options,
+		log:     log.WithValues("group", options.Group, "replica", options.ReplicaID),
+		changed: make(chan struct{}, 1),
+	}, nil
+}
+
+// KeyFunc returns the function that shards objects by namespace or by UID
+func KeyFunc(key string) (func(client.Object) string, error) {
+	switch key {
+	case config.ShardByNamespace:
+		return func(obj client.Object) string { return obj.GetNamespace() }, nil
+	case config.ShardByUID:
+		return func(obj client.Object) string { return string(obj.GetUID()) }, nil
+	default:
+		return nil, fmt.Errorf("unsupported shard key %q", key)
+	}
+}
+
+// Start joins the shard group and keeps membership current until the context
+// is cancelled, then leaves so the other replicas take over immediately
+func (s *Sharder) Start(ctx context.Context) error {
+	s.log.Info("Joining shard group")
+	ticker := time.NewTicker(s.options.RenewInterval)
+	defer ticker.Stop()
+
+	s.sync(ctx)
+	handoff := s.handoffTimer()
+	for {
+		select {
+		case <-ctx.Done():
+			s.leave()
+			return nil
+		case <-ticker.C:
+			s.sync(ctx)
+			handoff = s.handoffTimer()
+		case <-handoff:
+			s.completeHandoff()
+			handoff = s.handoffTimer()
+		}
+	}
+}
+
+// NeedLeaderElection returns false since every replica owns a shard
+func (s *Sharder) NeedLeaderElection() bool {
+	return false
+}
+
+// OwnsObject reports whether this replica reconciles obj. Nothing is owned until
+// the replica has joined the group.
+func (s *Sharder) OwnsObject(obj client.Object) bool {
+	return s.Owns(s.options.KeyFunc(obj))
+}
+
+// Owns reports whether this replica owns key. Keys a membership change moves
+// to this replica are only owned once the handoff has ended, while keys it
+// moves away are released at once.
+func (s *Sharder) Owns(key string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.joined || s.ring.Owner(key) != s.options.ReplicaID {
+		return false
+	}
+	return s.handoff.IsZero() || s.claimed.Owner(key) == s.options.ReplicaID
+}
+
+// Members returns the live replicas in the group, sorted
+func (s *Sharder) Members() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.members)
+}
+
+// Changed receives a value after membership changes and after the handoff that
+// follows, so that resources this replica now owns can be requeued. Changes are
+// coalesced until received.
+func (s *Sharder) Changed() <-chan struct{} {
+	return s.changed
+}
+
+// Joined is a readiness check that fails until this replica owns a shard
+func (s *Sharder) Joined(_ *http.Request) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.joined {
+		return fmt.Errorf("replica %s has not joined shard group %s", s.options.ReplicaID, s.options.Group)
+	}
+	return nil
+}
+
+// sync renews this replica's Lease and recomputes membership from the live Leases
+func (s *Sharder) sync(ctx context.Context) {
+	if err := s.renew(ctx); err != nil {
+		// Keep going: if the Lease expires this replica drops out of the members below
+		s.log.Error(err, "Failed to renew shard lease")
+	}
+
+	members, err := s.liveMembers(ctx)
+	if err != nil {
+		s.log.Error(err, "Failed to list shard leases, keeping current membership")
+		return
+	}
+	s.setMembers(members)
+}
+
+func (s *Sharder) leaseName() string {
+	return s.options.Group + "-" + s.options.ReplicaID
+}
+
+func (s *Sharder) renew(ctx context.Context) error {
+	now := metav1.NewMicroTime(time.Now())
+	seconds := int32(s.options.LeaseDuration.Seconds())
+	holder := s.options.ReplicaID
+
+	lease := &coordinationv1.Lease{}
+	err := s.reader.Get(ctx, client.ObjectKey{Namespace: s.options.Namespace, Name: s.leaseName()}, lease)
+	if errors.IsNotFound(err) {
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      s.leaseName(),
+				Namespace: s.options.Namespace,
+				Labels:    map[string]string{LabelGroup: s.options.Group},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &holder,
+				LeaseDurationSeconds: &seconds,
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}
+		return s.client.Create(ctx, lease)
+	}
+	if err != nil {
+		return err
+	}
+
+	lease.Spec.HolderIdentity = &holder
+	lease.Spec.LeaseDurationSeconds = &seconds
+	lease.Spec.RenewTime = &now
+	return s.client.Update(ctx, lease)
+}
+
+func (s *Sharder) liveMembers(ctx context.Context) ([]string, error) {
+	leases := &coordinationv1.LeaseList{}
+	if err := s.reader.List(ctx, leases, client.InNamespace(s.options.Namespace),
+		client.MatchingLabels{LabelGroup: s.options.Group}); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var members []string
+	for _, lease := range leases.Items {
+		spec := lease.Spec
+		if spec.HolderIdentity == nil || spec.RenewTime == nil || spec.LeaseDurationSeconds == nil {
+			continue
+		}
+		expiry := spec.RenewTime.Add(time.Duration(*spec.LeaseDurationSeconds) * time.Second)
+		if now.Before(expiry) {
+			members = append(members, *spec.HolderIdentity)
+		}
+	}
+	slices.Sort(members)
+	return slices.Compact(members), nil
+}
+
+func (s *Sharder) setMembers(members []string) {
+	self := slices.Contains(members, s.options.ReplicaID)
+
+	s.mu.Lock()
+	if s.joined == self && slices.Equal(s.members, members) {
+		s.mu.Unlock()
+		return
+	}
+	previous := s.members
+	// Keys gained by an interrupted handoff were never owned, so the ring
+	// from before it stays claimed
+	if s.handoff.IsZero() {
+		s.claimed = Ring{}
+		if s.joined {
+			s.claimed = s.ring
+		}
+	}
+	s.members, s.joined, s.ring = members, self, NewRing(members)
+	// The previous owners of the keys this replica gains start their next sync
+	// at most one renew interval after the Leases listed here were written, and
+	// have seen the change once it finishes, which takes less than another
+	s.handoff = time.Time{}
+	if self {
+		s.handoff = time.Now().Add(2 * s.options.RenewInterval)
+	}
+	s.mu.Unlock()
+
+	s.log.Info("Shard membership changed", "members", members, "previous", previous, "joined", self)
+	metrics.ShardRebalances.WithLabelValues(s.options.Group).Inc()
+	metrics.ShardMembers.WithLabelValues(s.options.Group).Set(float64(len(members)))
+	for _, member := range previous {
+		metrics.ShardMember.DeleteLabelValues(s.options.Group, member, fmt.Sprint(member == s.options.ReplicaID))
+	}
+	for _, member := range members {
+		metrics.ShardMember.WithLabelValues(s.options.Group, member, fmt.Sprint(member == s.options.ReplicaID)).Set(1)
+	}
+
+	select {
+	case s.changed <- struct{}{}:
+	default:
+	}
+}
+
+// handoffTimer returns a channel that receives when the pending handoff ends,
+// or nil when none is pending
+func (s *Sharder) handoffTimer() <-chan time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.handoff.IsZero() {
+		return nil
+	}
+	return time.After(time.Until(s.handoff))
+}
+
+// completeHandoff claims the keys the last membership change moved to this
+// replica, and signals Changed so they are requeued
+func (s *Sharder) completeHandoff() {
+	s.mu.Lock()
+	if s.handoff.IsZero() || time.Now().Before(s.handoff) {
+		s.mu.Unlock()
+		return
+	}
+	s.handoff = time.Time{}
+	s.mu.Unlock()
+
+	s.log.Info("Shard handoff completed")
+	select {
+	case s.changed <- struct{}{}:
+	default:
+	}
+}
+
+// leave deletes this replica's Lease so the remaining replicas rebalance without
+// waiting for it to expire
+func (s *Sharder) leave() {
+	ctx, cancel := context.WithTimeout(context.Background(), s.options.RenewInterval)
+	defer cancel()
+
+	lease := &coordinationv1.Lease{ObjectMeta: metav1.ObjectMeta{Namespace: s.options.Namespace, Name: s.leaseName()}}
+	if err := s.client.Delete(ctx, lease); err != nil && !errors.IsNotFound(err) {
+		s.log.Error(err, "Failed to release shard lease; it will expire instead")
+		return
+	}
+
+	s.mu.Lock()
+	s.joined = false
+	s.mu.Unlock()
+	s.log.Info("Left shard group")
+}
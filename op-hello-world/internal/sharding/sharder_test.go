@@ -0,0 +1,228 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sharding
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/example/op-hello-world/internal/config"
+	"github.com/example/op-hello-world/internal/telemetrytest"
+)
+
+type replica struct {
+	*Sharder
+	stop func()
+}
+
+// startReplica runs a Sharder until the returned replica is stopped
+func startReplica(group, id string) replica {
+	keyFunc, err := KeyFunc(config.ShardByNamespace)
+	Expect(err).NotTo(HaveOccurred())
+	sharder, err := NewSharder(k8sClient, k8sClient, Options{
+		Namespace:     "default",
+		Group:         group,
+		ReplicaID:     id,
+		KeyFunc:       keyFunc,
+		LeaseDuration: 2 * time.Second,
+		RenewInterval: 100 * time.Millisecond,
+	}, GinkgoLogr)
+	Expect(err).NotTo(HaveOccurred())
+
+	replicaCtx, cancelReplica := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer GinkgoRecover()
+		defer close(done)
+		Expect(sharder.Start(replicaCtx)).To(Succeed())
+	}()
+	return replica{Sharder: sharder, stop: func() {
+		cancelReplica()
+		<-done
+	}}
+}
+
+// owners maps every test key to the replicas that claim it
+func owners(replicas ...replica) map[string][]string {
+	owners := map[string][]string{}
+	for _, key := range keys(500) {
+		for _, r := range replicas {
+			if r.Owns(key) {
+				owners[key] = append(owners[key], r.options.ReplicaID)
+			}
+		}
+	}
+	return owners
+}
+
+// expectSingleOwners waits until every replica sees the same members and every
+// key is owned by exactly one replica, once the handoffs have ended
+func expectSingleOwners(replicas ...replica) map[string]string {
+	ids := make([]string, len(replicas))
+	for i, r := range replicas {
+		ids[i] = r.options.ReplicaID
+	}
+	for _, r := range replicas {
+		Eventually(r.Members).WithTimeout(5 * time.Second).Should(ConsistOf(ids))
+	}
+
+	var owned map[string]string
+	Eventually(func(g Gomega) {
+		owned = map[string]string{}
+		for key, claims := range owners(replicas...) {
+			g.Expect(claims).To(HaveLen(1), "key %s", key)
+			owned[key] = claims[0]
+		}
+		g.Expect(owned).To(HaveLen(500))
+	}).WithTimeout(5 * time.Second).Should(Succeed())
+	return owned
+}
+
+var _ = Describe("Sharder", func() {
+	It("rebalances when replicas leave and join", func() {
+		metrics := telemetrytest.Install()
+		DeferCleanup(metrics.Uninstall)
+
+		group := "rebalance"
+		a, b, c := startReplica(group, "replica-a"), startReplica(group, "replica-b"), startReplica(group, "replica-c")
+		DeferCleanup(a.stop)
+		DeferCleanup(b.stop)
+
+		By("splitting keys between three replicas")
+		before := expectSingleOwners(a, b, c)
+		Expect(metrics.Metric("helloworld_shard_members", map[string]string{"group": group})).To(Equal(3.0))
+
+		By("handing a departed replica's keys to the others")
+		c.stop()
+		Expect(c.Owns(keys(1)[0])).To(BeFalse())
+		after := expectSingleOwners(a, b)
+		for key, owner := range before {
+			if owner != "replica-c" {
+				Expect(after[key]).To(Equal(owner), "key %s moved although its owner stayed", key)
+			}
+		}
+		Expect(metrics.Metric("helloworld_shard_members", map[string]string{"group": group})).To(Equal(2.0))
+		Eventually(a.Changed()).Should(Receive())
+
+		By("only moving keys to a replica that joins")
+		d := startReplica(group, "replica-d")
+		DeferCleanup(d.stop)
+		joined := expectSingleOwners(a, b, d)
+		for key, owner := range joined {
+			if owner != "replica-d" {
+				Expect(owner).To(Equal(after[key]), "key %s moved between remaining replicas", key)
+			}
+		}
+		Expect(metrics.Metric("helloworld_shard_member",
+			map[string]string{"group": group, "replica": "replica-d", "self": "true"})).To(Equal(1.0))
+	})
+
+	It("never lets two replicas own a key while rebalancing", func() {
+		group := "handoff"
+		a := startReplica(group, "replica-a")
+		DeferCleanup(a.stop)
+		expectSingleOwners(a)
+
+		// Sample ownership until the end of the test, recording every key
+		// claimed by more than one replica
+		var mu sync.Mutex
+		replicas := []replica{a}
+		doubled := map[string][]string{}
+		done, sampled := make(chan struct{}), make(chan struct{})
+		go func() {
+			defer close(sampled)
+			for {
+				mu.Lock()
+				for key, claims := range owners(replicas...) {
+					if len(claims) > 1 {
+						doubled[key] = claims
+					}
+				}
+				mu.Unlock()
+				select {
+				case <-done:
+					return
+				case <-time.After(5 * time.Millisecond):
+				}
+			}
+		}()
+		join := func(id string) replica {
+			r := startReplica(group, id)
+			mu.Lock()
+			defer mu.Unlock()
+			replicas = append(replicas, r)
+			return r
+		}
+
+		By("adding replicas one after the other")
+		b := join("replica-b")
+		DeferCleanup(b.stop)
+		expectSingleOwners(a, b)
+		c := join("replica-c")
+		expectSingleOwners(a, b, c)
+
+		By("removing a replica")
+		c.stop()
+		expectSingleOwners(a, b)
+
+		close(done)
+		<-sampled
+		Expect(doubled).To(BeEmpty())
+	})
+
+	It("drops replicas whose Lease expired", func() {
+		group := "expiry"
+		stale := &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      group + "-crashed",
+				Namespace: "default",
+				Labels:    map[string]string{LabelGroup: group},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       ptr.To("crashed"),
+				LeaseDurationSeconds: ptr.To[int32](2),
+				RenewTime:            ptr.To(metav1.NewMicroTime(time.Now().Add(-time.Minute))),
+			},
+		}
+		Expect(k8sClient.Create(ctx, stale)).To(Succeed())
+		DeferCleanup(func() { Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, stale))).To(Succeed()) })
+
+		a := startReplica(group, "replica-a")
+		DeferCleanup(a.stop)
+		expectSingleOwners(a)
+	})
+
+	It("owns nothing until it has joined", func() {
+		keyFunc, err := KeyFunc(config.ShardByUID)
+		Expect(err).NotTo(HaveOccurred())
+		sharder, err := NewSharder(k8sClient, k8sClient, Options{
+			Namespace: "default", Group: "idle", ReplicaID: "replica-a", KeyFunc: keyFunc,
+			LeaseDuration: 2 * time.Second, RenewInterval: time.Second,
+		}, GinkgoLogr)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sharder.Owns("anything")).To(BeFalse())
+		Expect(sharder.Joined(nil)).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,122 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package shutdown coordinates flushing telemetry once the manager has stopped.
+package shutdown
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// Func flushes and releases a component. It should return promptly once ctx is done.
+type Func func(ctx context.Context) error
+
+// Result is the outcome of one registered shutdown function
+type Result struct {
+	// Name identifies the component, e.g. "traces"
+	Name string
+	// Err is set if the component failed to flush or timed out
+	Err error
+	// Duration is how long the component took to shut down
+	Duration time.Duration
+}
+
+// Report lists the outcome of every registered shutdown function, in the order they ran
+type Report []Result
+
+// Failed returns the results that reported an error
+func (r Report) Failed() Report {
+	var failed Report
+	for _, result := range r {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// Coordinator runs registered shutdown functions once, in reverse registration
+// order, sharing a single bounded timeout
+type Coordinator struct {
+	timeout time.Duration
+	log     logr.Logger
+
+	mu     sync.Mutex
+	names  []string
+	funcs  []Func
+	once   sync.Once
+	report Report
+}
+
+// NewCoordinator returns a Coordinator whose shutdown functions must all finish within timeout
+func NewCoordinator(timeout time.Duration, log logr.Logger) *Coordinator {
+	return &Coordinator{timeout: timeout, log: log}
+}
+
+// Register adds a shutdown function. Functions registered later run first, like defers.
+func (c *Coordinator) Register(name string, fn Func) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.names = append(c.names, name)
+	c.funcs = append(c.funcs, fn)
+}
+
+// Shutdown runs every registered function and logs what failed or was dropped.
+// It is safe to call more than once; later calls return the first report.
+func (c *Coordinator) Shutdown() Report {
+	c.once.Do(func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+		defer cancel()
+
+		c.log.Info("Flushing telemetry before exit", "timeout", c.timeout.String())
+		for i := len(c.funcs) - 1; i >= 0; i-- {
+			start := time.Now()
+			err := runWithContext(ctx, c.funcs[i])
+			result := Result{Name: c.names[i], Err: err, Duration: time.Since(start)}
+			c.report = append(c.report, result)
+
+			if err != nil {
+				c.log.Error(err, "Shutdown incomplete, data may have been dropped",
+					"component", result.Name, "duration", result.Duration.String())
+			} else {
+				c.log.Info("Shutdown complete", "component", result.Name, "duration", result.Duration.String())
+			}
+		}
+	})
+	return c.report
+}
+
+// runWithContext runs fn and gives up once ctx is done, even if fn ignores ctx
+func runWithContext(ctx context.Context, fn Func) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/example/op-hello-world/internal/tracing"
+)
+
+// fakeExporter records exported spans, optionally failing or blocking until the context ends
+type fakeExporter struct {
+	mu       sync.Mutex
+	spans    int
+	fail     bool
+	block    bool
+	shutdown bool
+}
+
+func (e *fakeExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if e.block {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if e.fail {
+		return errors.New("collector unavailable")
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans += len(spans)
+	return nil
+}
+
+func (e *fakeExporter) Shutdown(context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.shutdown = true
+	return nil
+}
+
+// emitSpans ends n spans on the global tracer provider
+func emitSpans(n int) {
+	tracer := tracing.GetTracer("shutdown-test")
+	for range n {
+		_, span := tracer.Start(context.Background(), "work")
+		span.End()
+	}
+}
+
+func TestShutdownFlushesTraces(t *testing.T) {
+	exporter := &fakeExporter{}
+	coordinator := NewCoordinator(time.Second, logr.Discard())
+	coordinator.Register("traces", tracing.NewProvider(exporter, "shutdown-test"))
+
+	emitSpans(3)
+	report := coordinator.Shutdown()
+
+	if failed := report.Failed(); len(failed) != 0 {
+		t.Fatalf("expected clean shutdown, got %+v", failed)
+	}
+	if exporter.spans != 3 || !exporter.shutdown {
+		t.Errorf("expected 3 spans flushed and exporter shut down, got %d spans, shutdown=%v", exporter.spans, exporter.shutdown)
+	}
+}
+
+func TestShutdownReportsDroppedSpans(t *testing.T) {
+	exporter := &fakeExporter{fail: true}
+	coordinator := NewCoordinator(time.Second, logr.Discard())
+	coordinator.Register("traces", tracing.NewProvider(exporter, "shutdown-test"))
+
+	emitSpans(2)
+	failed := coordinator.Shutdown().Failed()
+
+	if len(failed) != 1 || failed[0].Name != "traces" {
+		t.Fatalf("expected traces to report a failure, got %+v", failed)
+	}
+	if !strings.Contains(failed[0].Err.Error(), "2 of 2 spans were dropped") {
+		t.Errorf("expected dropped span count in error, got %v", failed[0].Err)
+	}
+}
+
+func TestShutdownIsBounded(t *testing.T) {
+	exporter := &fakeExporter{block: true}
+	coordinator := NewCoordinator(100*time.Millisecond, logr.Discard())
+	coordinator.Register("traces", tracing.NewProvider(exporter, "shutdown-test"))
+
+	emitSpans(1)
+	start := time.Now()
+	failed := coordinator.Shutdown().Failed()
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected shutdown to give up after the timeout, took %s", elapsed)
+	}
+	if len(failed) != 1 || !errors.Is(failed[0].Err, context.DeadlineExceeded) {
+		t.Errorf("expected traces to time out, got %+v", failed)
+	}
+}
+
+func TestShutdownRunsInReverseOrderOnce(t *testing.T) {
+	var order []string
+	coordinator := NewCoordinator(time.Second, logr.Discard())
+	for _, name := range []string{"first", "second"} {
+		coordinator.Register(name, func(context.Context) error {
+			order = append(order, name)
+			return nil
+		})
+	}
+
+	coordinator.Shutdown()
+	report := coordinator.Shutdown()
+
+	if strings.Join(order, ",") != "second,first" {
+		t.Errorf("expected reverse registration order once, got %v", order)
+	}
+	if len(report) != 2 {
+		t.Errorf("expected the first report to be returned again, got %+v", report)
+	}
+}
+
+// syncRecorder is a log destination that records syncs, optionally failing them
+type syncRecorder struct {
+	synced bool
+	err    error
+}
+
+func (s *syncRecorder) Write(p []byte) (int, error) { return len(p), nil }
+
+func (s *syncRecorder) Sync() error {
+	s.synced = true
+	return s.err
+}
+
+func newSyncedLogger(sink *syncRecorder) *zap.Logger {
+	return zap.New(zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), sink, zapcore.InfoLevel))
+}
+
+func TestShutdownSyncsLogsLast(t *testing.T) {
+	sink := &syncRecorder{}
+	exporter := &fakeExporter{}
+	coordinator := NewCoordinator(time.Second, logr.Discard())
+	coordinator.Register("logs", SyncLogger(newSyncedLogger(sink)))
+	coordinator.Register("traces", tracing.NewProvider(exporter, "shutdown-test"))
+
+	report := coordinator.Shutdown()
+
+	if !sink.synced {
+		t.Error("expected the logger to be synced")
+	}
+	if len(report) != 2 || report[0].Name != "traces" || report[1].Name != "logs" {
+		t.Errorf("expected traces then logs in the report, got %+v", report)
+	}
+	if failed := report.Failed(); len(failed) != 0 {
+		t.Errorf("expected clean shutdown, got %+v", failed)
+	}
+}
+
+func TestSyncLoggerErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		err     error
+		wantErr bool
+	}{
+		{name: "terminal", err: &os.PathError{Op: "sync", Path: "/dev/stderr", Err: syscall.EINVAL}},
+		{name: "pipe", err: &os.PathError{Op: "sync", Path: "/dev/stderr", Err: syscall.ENOTTY}},
+		{name: "write failure", err: &os.PathError{Op: "sync", Path: "/var/log/manager.log", Err: syscall.EIO}, wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := SyncLogger(newSyncedLogger(&syncRecorder{err: tc.err}))(context.Background())
+			if (err != nil) != tc.wantErr {
+				t.Errorf("expected error %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"syscall"
+
+	"go.uber.org/zap"
+)
+
+// SyncLogger returns a Func that flushes the entries buffered by logger.
+// Syncing a terminal or pipe fails with EINVAL or ENOTTY on some platforms
+// although nothing was lost, so those errors are ignored.
+func SyncLogger(logger *zap.Logger) Func {
+	return func(context.Context) error {
+		err := logger.Sync()
+		if errors.Is(err, syscall.EINVAL) || errors.Is(err, syscall.ENOTTY) {
+			return nil
+		}
+		return err
+	}
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package telemetrytest provides an in-memory span recorder and a fresh
+// Prometheus registry so tests can assert the telemetry the operator emits.
+package telemetrytest
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/example/op-hello-world/internal/metrics"
+)
+
+// Recorder captures spans and metrics emitted while it is installed
+type Recorder struct {
+	// Exporter holds every span ended since the recorder was installed or reset
+	Exporter *tracetest.InMemoryExporter
+	// Registry holds the operator's custom metrics
+	Registry *prometheus.Registry
+
+	provider *sdktrace.TracerProvider
+	previous trace.TracerProvider
+}
+
+// Install replaces the global tracer provider with one that records spans
+// synchronously in memory, and registers the operator's metrics with a fresh
+// registry. Metric values are reset, so every value read from the recorder is
+// the delta since Install or Reset. Call Uninstall to restore the previous
+// tracer provider.
+func Install() *Recorder {
+	exporter := tracetest.NewInMemoryExporter()
+	r := &Recorder{
+		Exporter: exporter,
+		Registry: prometheus.NewRegistry(),
+		provider: sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)),
+		previous: otel.GetTracerProvider(),
+	}
+	r.Registry.MustRegister(metrics.Collectors()...)
+	r.Reset()
+
+	otel.SetTracerProvider(r.provider)
+	return r
+}
+
+// Uninstall restores the previous tracer provider
+func (r *Recorder) Uninstall() {
+	otel.SetTracerProvider(r.previous)
+	_ = r.provider.Shutdown(context.Background())
+}
+
+// Reset discards recorded spans and zeroes the operator's metrics
+func (r *Recorder) Reset() {
+	r.Exporter.Reset()
+	for _, c := range metrics.Collectors() {
+		if vec, ok := c.(interface{ Reset() }); ok {
+			vec.Reset()
+		}
+	}
+}
+
+// Spans returns every recorded span in the order they ended
+func (r *Recorder) Spans() tracetest.SpanStubs {
+	return r.Exporter.GetSpans()
+}
+
+// SpansNamed returns the recorded spans with the given name
+func (r *Recorder) SpansNamed(name string) tracetest.SpanStubs {
+	var spans tracetest.SpanStubs
+	for _, s := range r.Exporter.GetSpans() {
+		if s.Name == name {
+			spans = append(spans, s)
+		}
+	}
+	return spans
+}
+
+// Metric returns the value of the named metric series matching labels. Counters
+// and gauges report their value and histograms their sample count. Series that
+// were never written report zero.
+func (r *Recorder) Metric(name string, labels map[string]string) float64 {
+	families, err := r.Registry.Gather()
+	if err != nil {
+		return 0
+	}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			if !labelsMatch(m, labels) {
+				continue
+			}
+			switch {
+			case m.Counter != nil:
+				return m.Counter.GetValue()
+			case m.Gauge != nil:
+				return m.Gauge.GetValue()
+			case m.Histogram != nil:
+				return float64(m.Histogram.GetSampleCount())
+			}
+		}
+	}
+	return 0
+}
+
+// labelsMatch reports whether the metric carries every wanted label value
+func labelsMatch(m *dto.Metric, want map[string]string) bool {
+	matched := 0
+	for _, pair := range m.GetLabel() {
+		if value, ok := want[pair.GetName()]; ok {
+			if value != pair.GetValue() {
+				return false
+			}
+			matched++
+		}
+	}
+	return matched == len(want)
+}
+
+// Attribute returns the value of the attribute with the given key on a span
+func Attribute(span tracetest.SpanStub, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+// HasErrorEvent reports whether an error was recorded on the span
+func HasErrorEvent(span tracetest.SpanStub) bool {
+	for _, event := range span.Events {
+		if event.Name == "exception" {
+			return true
+		}
+	}
+	return false
+}
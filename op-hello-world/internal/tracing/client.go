@@ -0,0 +1,245 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// Span attribute keys for Kubernetes API calls
+const (
+	AttrK8sVerb        = attribute.Key("k8s.api.verb")
+	AttrK8sGroup       = attribute.Key("k8s.api.group")
+	AttrK8sKind        = attribute.Key("k8s.resource.kind")
+	AttrK8sName        = attribute.Key("k8s.resource.name")
+	AttrK8sNamespace   = attribute.Key("k8s.namespace.name")
+	AttrK8sSubresource = attribute.Key("k8s.api.subresource")
+	AttrStatusCode     = attribute.Key("http.response.status_code")
+	AttrStatusReason   = attribute.Key("k8s.api.status_reason")
+)
+
+// NewClient creates a Kubernetes client whose API calls are traced. It matches
+// client.NewClientFunc so it can be passed to the manager via ctrl.Options.NewClient.
+func NewClient(config *rest.Config, options client.Options) (client.Client, error) {
+	c, err := client.New(config, options)
+	if err != nil {
+		return nil, err
+	}
+	return WrapClient(c), nil
+}
+
+// WrapClient decorates a client so that every Get, List, Create, Update, Patch
+// and Delete call is recorded as a child span of the caller's span
+func WrapClient(c client.Client) client.Client {
+	return &tracedClient{
+		Client: c,
+		tracer: GetTracer("k8s-client"),
+	}
+}
+
+// tracedClient wraps a client.Client and starts a span around each API call
+type tracedClient struct {
+	client.Client
+	tracer trace.Tracer
+}
+
+// Get implements client.Reader
+func (c *tracedClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	ctx, span := c.start(ctx, "get", "", obj, key.Namespace, key.Name)
+	err := c.Client.Get(ctx, key, obj, opts...)
+	endSpan(span, err, http.StatusOK)
+	return err
+}
+
+// List implements client.Reader
+func (c *tracedClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	listOpts := &client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+	ctx, span := c.start(ctx, "list", "", list, listOpts.Namespace, "")
+	err := c.Client.List(ctx, list, opts...)
+	endSpan(span, err, http.StatusOK)
+	return err
+}
+
+// Create implements client.Writer
+func (c *tracedClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	ctx, span := c.start(ctx, "create", "", obj, obj.GetNamespace(), obj.GetName())
+	err := c.Client.Create(ctx, obj, opts...)
+	endSpan(span, err, http.StatusCreated)
+	return err
+}
+
+// Update implements client.Writer
+func (c *tracedClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	ctx, span := c.start(ctx, "update", "", obj, obj.GetNamespace(), obj.GetName())
+	err := c.Client.Update(ctx, obj, opts...)
+	endSpan(span, err, http.StatusOK)
+	return err
+}
+
+// Patch implements client.Writer
+func (c *tracedClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	ctx, span := c.start(ctx, "patch", "", obj, obj.GetNamespace(), obj.GetName())
+	err := c.Client.Patch(ctx, obj, patch, opts...)
+	endSpan(span, err, http.StatusOK)
+	return err
+}
+
+// Delete implements client.Writer
+func (c *tracedClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	ctx, span := c.start(ctx, "delete", "", obj, obj.GetNamespace(), obj.GetName())
+	err := c.Client.Delete(ctx, obj, opts...)
+	endSpan(span, err, http.StatusOK)
+	return err
+}
+
+// DeleteAllOf implements client.Writer
+func (c *tracedClient) DeleteAllOf(ctx context.Context, obj client.Object, opts ...client.DeleteAllOfOption) error {
+	deleteOpts := &client.DeleteAllOfOptions{}
+	deleteOpts.ApplyOptions(opts)
+	ctx, span := c.start(ctx, "deletecollection", "", obj, deleteOpts.Namespace, "")
+	err := c.Client.DeleteAllOf(ctx, obj, opts...)
+	endSpan(span, err, http.StatusOK)
+	return err
+}
+
+// Status implements client.StatusClient
+func (c *tracedClient) Status() client.SubResourceWriter {
+	return &tracedSubResourceClient{
+		writer:      c.Client.Status(),
+		parent:      c,
+		subResource: "status",
+	}
+}
+
+// SubResource implements client.SubResourceClientConstructor
+func (c *tracedClient) SubResource(subResource string) client.SubResourceClient {
+	inner := c.Client.SubResource(subResource)
+	return &tracedSubResourceClient{
+		reader:      inner,
+		writer:      inner,
+		parent:      c,
+		subResource: subResource,
+	}
+}
+
+// start opens a client span named after the verb and resource kind, e.g. "get Pod"
+func (c *tracedClient) start(ctx context.Context, verb, subResource string, obj runtime.Object, namespace, name string) (context.Context, trace.Span) {
+	kind := "Unknown"
+	group := ""
+	if gvk, err := apiutil.GVKForObject(obj, c.Scheme()); err == nil {
+		kind = strings.TrimSuffix(gvk.Kind, "List")
+		group = gvk.Group
+	}
+
+	spanName := verb + " " + kind
+	if subResource != "" {
+		spanName += "/" + subResource
+	}
+
+	attrs := []attribute.KeyValue{
+		AttrK8sVerb.String(verb),
+		AttrK8sGroup.String(group),
+		AttrK8sKind.String(kind),
+	}
+	if namespace != "" {
+		attrs = append(attrs, AttrK8sNamespace.String(namespace))
+	}
+	if name != "" {
+		attrs = append(attrs, AttrK8sName.String(name))
+	}
+	if subResource != "" {
+		attrs = append(attrs, AttrK8sSubresource.String(subResource))
+	}
+
+	return c.tracer.Start(ctx, spanName,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// endSpan records the API result code on the span and ends it
+func endSpan(span trace.Span, err error, successCode int) {
+	defer span.End()
+
+	if err == nil {
+		span.SetAttributes(AttrStatusCode.Int(successCode))
+		return
+	}
+
+	var status apierrors.APIStatus
+	if errors.As(err, &status) {
+		span.SetAttributes(
+			AttrStatusCode.Int(int(status.Status().Code)),
+			AttrStatusReason.String(string(status.Status().Reason)),
+		)
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// tracedSubResourceClient traces calls made through Status() and SubResource()
+type tracedSubResourceClient struct {
+	reader      client.SubResourceReader
+	writer      client.SubResourceWriter
+	parent      *tracedClient
+	subResource string
+}
+
+// Get implements client.SubResourceReader
+func (c *tracedSubResourceClient) Get(ctx context.Context, obj client.Object, subResource client.Object, opts ...client.SubResourceGetOption) error {
+	ctx, span := c.parent.start(ctx, "get", c.subResource, obj, obj.GetNamespace(), obj.GetName())
+	err := c.reader.Get(ctx, obj, subResource, opts...)
+	endSpan(span, err, http.StatusOK)
+	return err
+}
+
+// Create implements client.SubResourceWriter
+func (c *tracedSubResourceClient) Create(ctx context.Context, obj client.Object, subResource client.Object, opts ...client.SubResourceCreateOption) error {
+	ctx, span := c.parent.start(ctx, "create", c.subResource, obj, obj.GetNamespace(), obj.GetName())
+	err := c.writer.Create(ctx, obj, subResource, opts...)
+	endSpan(span, err, http.StatusCreated)
+	return err
+}
+
+// Update implements client.SubResourceWriter
+func (c *tracedSubResourceClient) Update(ctx context.Context, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+	ctx, span := c.parent.start(ctx, "update", c.subResource, obj, obj.GetNamespace(), obj.GetName())
+	err := c.writer.Update(ctx, obj, opts...)
+	endSpan(span, err, http.StatusOK)
+	return err
+}
+
+// Patch implements client.SubResourceWriter
+func (c *tracedSubResourceClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+	ctx, span := c.parent.start(ctx, "patch", c.subResource, obj, obj.GetNamespace(), obj.GetName())
+	err := c.writer.Patch(ctx, obj, patch, opts...)
+	endSpan(span, err, http.StatusOK)
+	return err
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/example/op-hello-world/internal/telemetrytest"
+)
+
+func TestTracedClient(t *testing.T) {
+	recorder := telemetrytest.Install()
+	defer recorder.Uninstall()
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "greeting", Namespace: "default"}}
+	c := WrapClient(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithStatusSubresource(&corev1.Pod{}).Build())
+	ctx := context.Background()
+
+	if err := c.Create(ctx, pod); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := c.Get(ctx, types.NamespacedName{Name: "missing", Namespace: "default"}, &corev1.Pod{}); err == nil {
+		t.Fatal("expected not found error")
+	}
+	if err := c.List(ctx, &corev1.PodList{}, client.InNamespace("default")); err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if err := c.Status().Update(ctx, pod); err != nil {
+		t.Fatalf("status update: %v", err)
+	}
+	if err := c.Delete(ctx, pod); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	spans := recorder.Spans()
+	wantNames := []string{"create Pod", "get Pod", "list Pod", "update Pod/status", "delete Pod"}
+	if len(spans) != len(wantNames) {
+		t.Fatalf("expected %d spans, got %d", len(wantNames), len(spans))
+	}
+	for i, want := range wantNames {
+		if spans[i].Name != want {
+			t.Errorf("span %d: expected name %q, got %q", i, want, spans[i].Name)
+		}
+	}
+
+	namespace, _ := telemetrytest.Attribute(spans[0], AttrK8sNamespace)
+	name, _ := telemetrytest.Attribute(spans[0], AttrK8sName)
+	code, _ := telemetrytest.Attribute(spans[0], AttrStatusCode)
+	if namespace.AsString() != "default" || name.AsString() != "greeting" || code.AsInt64() != 201 {
+		t.Errorf("unexpected create attributes %v", spans[0].Attributes)
+	}
+
+	code, _ = telemetrytest.Attribute(spans[1], AttrStatusCode)
+	if spans[1].Status.Code != codes.Error || code.AsInt64() != 404 || !telemetrytest.HasErrorEvent(spans[1]) {
+		t.Errorf("expected not found error on get span, got status %v attributes %v", spans[1].Status, spans[1].Attributes)
+	}
+}
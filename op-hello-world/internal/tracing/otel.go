@@ -18,27 +18,22 @@ package tracing
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"sync/atomic"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
-	"go.opentelemetry.io/otel/propagation"
 	sdkresource "go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/trace"
 )
 
-// InitTracer initializes OpenTelemetry tracing
-func InitTracer(ctx context.Context, serviceName string) (func(context.Context) error, error) {
-	// Get OTLP endpoint from environment or use default
-	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
-	if endpoint == "" {
-		endpoint = "localhost:4317"
-	}
-
+// InitTracer initializes OpenTelemetry tracing, exporting to the given OTLP gRPC endpoint
+func InitTracer(ctx context.Context, serviceName, endpoint string) (func(context.Context) error, error) {
 	// Create OTLP trace exporter
 	exporter, err := otlptrace.New(
 		ctx,
@@ -51,6 +46,13 @@ func InitTracer(ctx context.Context, serviceName string) (func(context.Context)
 		return nil, fmt.Errorf("creating OTLP trace exporter: %w", err)
 	}
 
+	return NewProvider(exporter, serviceName), nil
+}
+
+// NewProvider installs a global tracer provider that batches spans to the given
+// exporter. The returned function flushes and shuts down the provider, and
+// returns an error reporting how many spans were dropped instead of exported.
+func NewProvider(exporter sdktrace.SpanExporter, serviceName string) func(context.Context) error {
 	// Create resource with service information
 	// Using empty schema URL and plain attribute keys to avoid schema conflicts
 	resource := sdkresource.NewWithAttributes(
@@ -60,26 +62,109 @@ func InitTracer(ctx context.Context, serviceName string) (func(context.Context)
 		attribute.String("environment", getEnvironment()),
 	)
 
+	// Count spans on both sides of the batcher so dropped spans can be reported
+	counter := &spanCounter{}
+	counted := &countingExporter{SpanExporter: exporter}
+
 	// Create trace provider
 	tp := sdktrace.NewTracerProvider(
-		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSpanProcessor(counter),
+		sdktrace.WithBatcher(counted),
 		sdktrace.WithResource(resource),
-		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+		sdktrace.WithSampler(sampler),
 	)
 
 	// Set global tracer provider
 	otel.SetTracerProvider(tp)
 
 	// Set global propagator
-	otel.SetTextMapPropagator(
-		propagation.NewCompositeTextMapPropagator(
-			propagation.TraceContext{},
-			propagation.Baggage{},
-		),
-	)
+	otel.SetTextMapPropagator(propagator)
 
 	// Return shutdown function
-	return tp.Shutdown, nil
+	return func(ctx context.Context) error {
+		err := tp.Shutdown(ctx)
+		ended, exported := counter.ended.Load(), counted.exported.Load()
+		if dropped := ended - exported; dropped > 0 {
+			return errors.Join(err, fmt.Errorf("%d of %d spans were dropped", dropped, ended))
+		}
+		return err
+	}
+}
+
+// sampler samples new traces at a ratio that can be changed while the provider runs
+var sampler = newRatioSampler(1)
+
+// SetSamplingRatio changes the fraction of new traces that are sampled. Spans
+// with a parent keep their parent's sampling decision.
+func SetSamplingRatio(ratio float64) {
+	sampler.set(ratio)
+}
+
+// ratioSampler is a parent-based trace ID ratio sampler whose ratio can be swapped
+type ratioSampler struct {
+	current atomic.Pointer[sdktrace.Sampler]
+}
+
+func newRatioSampler(ratio float64) *ratioSampler {
+	s := &ratioSampler{}
+	s.set(ratio)
+	return s
+}
+
+func (s *ratioSampler) set(ratio float64) {
+	next := sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))
+	s.current.Store(&next)
+}
+
+// ShouldSample implements sdktrace.Sampler
+func (s *ratioSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	return (*s.current.Load()).ShouldSample(p)
+}
+
+// Description implements sdktrace.Sampler
+func (s *ratioSampler) Description() string {
+	return (*s.current.Load()).Description()
+}
+
+// spanCounter is a span processor that counts sampled spans as they end
+type spanCounter struct {
+	ended atomic.Int64
+}
+
+func (c *spanCounter) OnStart(context.Context, sdktrace.ReadWriteSpan) {}
+
+func (c *spanCounter) OnEnd(s sdktrace.ReadOnlySpan) {
+	if s.SpanContext().IsSampled() {
+		c.ended.Add(1)
+	}
+}
+
+func (c *spanCounter) Shutdown(context.Context) error { return nil }
+
+func (c *spanCounter) ForceFlush(context.Context) error { return nil }
+
+// countingExporter counts the spans its exporter accepted
+type countingExporter struct {
+	sdktrace.SpanExporter
+	exported atomic.Int64
+}
+
+// ExportSpans implements sdktrace.SpanExporter
+func (e *countingExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	err := e.SpanExporter.ExportSpans(ctx, spans)
+	if err == nil {
+		e.exported.Add(int64(len(spans)))
+	}
+	return err
+}
+
+// Endpoint returns the OTLP endpoint from the environment or the default
+func Endpoint() string {
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	if endpoint == "" {
+		endpoint = "localhost:4317"
+	}
+	return endpoint
 }
 
 // GetTracer returns a tracer for the given component
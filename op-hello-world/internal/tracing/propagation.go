@@ -0,0 +1,126 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel/propagation"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Annotation keys used to carry W3C trace context on Kubernetes objects
+const (
+	// TraceparentAnnotation carries the W3C traceparent header value
+	TraceparentAnnotation = "tracing.apps.example.com/traceparent"
+	// TracestateAnnotation carries the W3C tracestate header value
+	TracestateAnnotation = "tracing.apps.example.com/tracestate"
+	// BaggageAnnotation carries the W3C baggage header value
+	BaggageAnnotation = "tracing.apps.example.com/baggage"
+)
+
+// propagator is used for annotation and environment carriers. It is independent
+// of the global propagator so that extraction works even if InitTracer failed.
+var propagator = propagation.NewCompositeTextMapPropagator(
+	propagation.TraceContext{},
+	propagation.Baggage{},
+)
+
+// annotationKeys maps W3C header names to annotation keys
+var annotationKeys = map[string]string{
+	"traceparent": TraceparentAnnotation,
+	"tracestate":  TracestateAnnotation,
+	"baggage":     BaggageAnnotation,
+}
+
+// AnnotationCarrier adapts an object's annotations to a propagation.TextMapCarrier
+type AnnotationCarrier map[string]string
+
+var _ propagation.TextMapCarrier = AnnotationCarrier{}
+
+// Get returns the annotation value for the given header name
+func (c AnnotationCarrier) Get(key string) string {
+	annotation, ok := annotationKeys[strings.ToLower(key)]
+	if !ok {
+		return ""
+	}
+	return c[annotation]
+}
+
+// Set stores the header value under the matching annotation key
+func (c AnnotationCarrier) Set(key, value string) {
+	annotation, ok := annotationKeys[strings.ToLower(key)]
+	if !ok {
+		return
+	}
+	c[annotation] = value
+}
+
+// Keys lists the header names present in the annotations
+func (c AnnotationCarrier) Keys() []string {
+	keys := make([]string, 0, len(annotationKeys))
+	for header, annotation := range annotationKeys {
+		if _, ok := c[annotation]; ok {
+			keys = append(keys, header)
+		}
+	}
+	return keys
+}
+
+// ExtractFromAnnotations returns a context carrying the remote span context and
+// baggage found in the given annotations. The context is returned unchanged if
+// the annotations carry no trace context.
+func ExtractFromAnnotations(ctx context.Context, annotations map[string]string) context.Context {
+	if len(annotations) == 0 {
+		return ctx
+	}
+	return propagator.Extract(ctx, AnnotationCarrier(annotations))
+}
+
+// InjectIntoAnnotations stamps the span context and baggage from ctx onto the
+// given annotations, allocating the map if needed
+func InjectIntoAnnotations(ctx context.Context, annotations map[string]string) map[string]string {
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	propagator.Inject(ctx, AnnotationCarrier(annotations))
+	return annotations
+}
+
+// EnvVars returns the span context and baggage from ctx as TRACEPARENT,
+// TRACESTATE and BAGGAGE environment variables for a workload container
+func EnvVars(ctx context.Context) []corev1.EnvVar {
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+
+	var env []corev1.EnvVar
+	for _, header := range []string{"traceparent", "tracestate", "baggage"} {
+		if value := carrier.Get(header); value != "" {
+			env = append(env, corev1.EnvVar{Name: strings.ToUpper(header), Value: value})
+		}
+	}
+	return env
+}
+
+// TraceContext returns the W3C traceparent and tracestate of the span in ctx,
+// or empty strings when it has none
+func TraceContext(ctx context.Context) (traceparent, tracestate string) {
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+	return carrier.Get("traceparent"), carrier.Get("tracestate")
+}
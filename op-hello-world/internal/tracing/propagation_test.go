@@ -0,0 +1,73 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const testTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestExtractFromAnnotations(t *testing.T) {
+	ctx := ExtractFromAnnotations(context.Background(), map[string]string{
+		TraceparentAnnotation: testTraceparent,
+		BaggageAnnotation:     "pipeline=deploy-42",
+		"unrelated":           "value",
+	})
+
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() || !sc.IsRemote() {
+		t.Fatalf("expected a valid remote span context, got %+v", sc)
+	}
+	if got := sc.TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("unexpected trace ID %q", got)
+	}
+	if got := baggage.FromContext(ctx).Member("pipeline").Value(); got != "deploy-42" {
+		t.Errorf("unexpected baggage value %q", got)
+	}
+}
+
+func TestExtractFromAnnotationsWithoutTraceContext(t *testing.T) {
+	ctx := ExtractFromAnnotations(context.Background(), map[string]string{"unrelated": "value"})
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		t.Error("expected no span context")
+	}
+}
+
+func TestInjectRoundTrip(t *testing.T) {
+	remote := ExtractFromAnnotations(context.Background(), map[string]string{
+		TraceparentAnnotation: testTraceparent,
+	})
+
+	annotations := InjectIntoAnnotations(remote, nil)
+	if got := annotations[TraceparentAnnotation]; got != testTraceparent {
+		t.Errorf("unexpected traceparent annotation %q", got)
+	}
+
+	env := EnvVars(remote)
+	if len(env) != 1 || env[0].Name != "TRACEPARENT" || env[0].Value != testTraceparent {
+		t.Errorf("unexpected env vars %+v", env)
+	}
+
+	if traceparent, tracestate := TraceContext(remote); traceparent != testTraceparent || tracestate != "" {
+		t.Errorf("unexpected trace context %q, %q", traceparent, tracestate)
+	}
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package v1 holds the admission webhooks of the apps.example.com/v1 API
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	appsv1 "github.com/example/op-hello-world/api/v1"
+)
+
+var helloworldlog = logf.Log.WithName("helloworld-resource")
+
+// SetupHelloWorldWebhookWithManager registers the webhook for HelloWorld in the manager.
+func SetupHelloWorldWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&appsv1.HelloWorld{}).
+		WithDefaulter(&HelloWorldCustomDefaulter{}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-apps-example-com-v1-helloworld,mutating=true,failurePolicy=fail,sideEffects=None,groups=apps.example.com,resources=helloworlds,verbs=create;update,versions=v1,name=mhelloworld-v1.kb.io,admissionReviewVersions=v1
+
+// HelloWorldCustomDefaulter records who approves a HelloWorld's message. When a
+// request sets the apps.example.com/approve-generation annotation to a new
+// value, the requesting user is written to apps.example.com/approved-by; any
+// other change to apps.example.com/approved-by is reverted, so it cannot be
+// forged. A new approval must name the generation the HelloWorld had before
+// the request, so a generation cannot be approved before it exists, nor by the
+// update that creates it.
+type HelloWorldCustomDefaulter struct{}
+
+var _ admission.CustomDefaulter = &HelloWorldCustomDefaulter{}
+
+// Default implements admission.CustomDefaulter
+func (d *HelloWorldCustomDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	helloworld, ok := obj.(*appsv1.HelloWorld)
+	if !ok {
+		return fmt.Errorf("expected a HelloWorld object but got %T", obj)
+	}
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	old := &appsv1.HelloWorld{}
+	if req.Operation == admissionv1.Update {
+		if err := json.Unmarshal(req.OldObject.Raw, old); err != nil {
+			return fmt.Errorf("failed to decode the old HelloWorld: %w", err)
+		}
+	}
+
+	approval, approved := helloworld.Annotations[appsv1.ApproveGenerationAnnotation]
+	approver, recorded := old.Annotations[appsv1.ApprovedByAnnotation]
+	switch {
+	case !approved:
+		delete(helloworld.Annotations, appsv1.ApprovedByAnnotation)
+	case approval != old.Annotations[appsv1.ApproveGenerationAnnotation]:
+		if req.Operation != admissionv1.Update {
+			return fmt.Errorf("%s cannot be set when a HelloWorld is created", appsv1.ApproveGenerationAnnotation)
+		}
+		if generation, _, _ := strings.Cut(approval, "/"); generation != strconv.FormatInt(old.Generation, 10) {
+			return fmt.Errorf("%s must name the current generation %d of the HelloWorld, not %q",
+				appsv1.ApproveGenerationAnnotation, old.Generation, approval)
+		}
+		helloworldlog.Info("Recording approver", "name", helloworld.Name, "namespace", helloworld.Namespace,
+			"generation", approval, "approver", req.UserInfo.Username)
+		helloworld.Annotations[appsv1.ApprovedByAnnotation] = req.UserInfo.Username
+	case recorded:
+		helloworld.Annotations[appsv1.ApprovedByAnnotation] = approver
+	default:
+		delete(helloworld.Annotations, appsv1.ApprovedByAnnotation)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	appsv1 "github.com/example/op-hello-world/api/v1"
+)
+
+func helloWorld(annotations map[string]string) *appsv1.HelloWorld {
+	return &appsv1.HelloWorld{
+		ObjectMeta: metav1.ObjectMeta{Name: "greeting", Namespace: "default", Generation: 2, Annotations: annotations},
+		Spec:       appsv1.HelloWorldSpec{Message: "Hello", ApprovalPolicy: appsv1.ApprovalRequired},
+	}
+}
+
+// admit runs the defaulter on an update from old to updated, or on a create
+// when old is nil, as alice
+func admit(old, updated *appsv1.HelloWorld) error {
+	req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Operation: admissionv1.Create,
+		UserInfo:  authenticationv1.UserInfo{Username: "alice"},
+	}}
+	if old != nil {
+		raw, err := json.Marshal(old)
+		if err != nil {
+			return err
+		}
+		req.Operation = admissionv1.Update
+		req.OldObject = runtime.RawExtension{Raw: raw}
+	}
+	return (&HelloWorldCustomDefaulter{}).Default(admission.NewContextWithRequest(context.Background(), req), updated)
+}
+
+func TestDefaultRecordsApprover(t *testing.T) {
+	approve := appsv1.ApproveGenerationAnnotation
+	approvedBy := appsv1.ApprovedByAnnotation
+	tests := []struct {
+		name string
+		old  map[string]string
+		new  map[string]string
+		want string
+	}{
+		{"new approval", map[string]string{approve: "1", approvedBy: "bob"},
+			map[string]string{approve: "2", approvedBy: "bob"}, "alice"},
+		{"approval of a changed message", map[string]string{approve: "2", approvedBy: "bob"},
+			map[string]string{approve: "2/3f1c9a0b7d2e4c68", approvedBy: "bob"}, "alice"},
+		{"forged approver", nil,
+			map[string]string{approve: "2", approvedBy: "bob"}, "alice"},
+		{"unrelated update", map[string]string{approve: "2", approvedBy: "bob"},
+			map[string]string{approve: "2"}, "bob"},
+		{"changed approver", map[string]string{approve: "2", approvedBy: "bob"},
+			map[string]string{approve: "2", approvedBy: "carol"}, "bob"},
+		{"approver without approval", nil,
+			map[string]string{approvedBy: "carol"}, ""},
+		{"approval removed", map[string]string{approve: "2", approvedBy: "bob"},
+			map[string]string{approvedBy: "bob"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helloworld := helloWorld(tt.new)
+			if err := admit(helloWorld(tt.old), helloworld); err != nil {
+				t.Fatal(err)
+			}
+			if got := helloworld.Annotations[approvedBy]; got != tt.want {
+				t.Errorf("expected approver %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDefaultRejectsApprovalOfOtherGenerations(t *testing.T) {
+	approve := appsv1.ApproveGenerationAnnotation
+	edited := helloWorld(map[string]string{approve: "3"})
+	edited.Spec.Message = "Hello, unapproved!"
+	tests := []struct {
+		name string
+		old  *appsv1.HelloWorld
+		new  *appsv1.HelloWorld
+	}{
+		{"approval on create", nil, helloWorld(map[string]string{approve: "1"})},
+		{"approval of the next generation", helloWorld(nil), helloWorld(map[string]string{approve: "3"})},
+		{"approval of the edit made by the same update", helloWorld(nil), edited},
+		{"approval of an earlier generation", helloWorld(nil), helloWorld(map[string]string{approve: "1"})},
+		{"approval of a message of another generation", helloWorld(nil), helloWorld(map[string]string{approve: "3/3f1c9a0b7d2e4c68"})},
+		{"approval that names no generation", helloWorld(nil), helloWorld(map[string]string{approve: "latest"})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := admit(tt.old, tt.new); err == nil {
+				t.Errorf("expected %s=%q to be rejected", approve, tt.new.Annotations[approve])
+			}
+		})
+	}
+}
+
+func TestDefaultRequiresAdmissionRequest(t *testing.T) {
+	if err := (&HelloWorldCustomDefaulter{}).Default(context.Background(), helloWorld(nil)); err == nil {
+		t.Error("expected an error without an admission request")
+	}
+}